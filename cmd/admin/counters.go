@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+
+	config "github.com/OxytocinGroup/theca-backend/internal/config"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+func repairCounters(database *gorm.DB, _ config.Config, _ []string) error {
+	repaired, err := repository.NewUserRepository(database).RepairBookmarkCounters()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("repaired bookmark counters of %d users\n", repaired)
+	return nil
+}
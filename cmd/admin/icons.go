@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	config "github.com/OxytocinGroup/theca-backend/internal/config"
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/internal/usecase"
+	"github.com/OxytocinGroup/theca-backend/pkg/blobstore"
+	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// fetchIcons retries the favicons of bookmarks saved before icons were
+// stored by Theca and of those that got a generated icon. Each URL is
+// fetched once however many users saved it.
+func fetchIcons(database *gorm.DB, cfg config.Config, _ []string) error {
+	blobs, err := blobstore.FromConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("open blob store: %w", err)
+	}
+	bookmarkRepo := repository.NewBookmarkRepository(database)
+	icons := usecase.NewIconUseCase(repository.NewIconRepository(database), blobs, logger.NewLogrusLogger(cfg.LogLevel))
+
+	bookmarks, err := bookmarkRepo.GetBookmarksWithoutFavicon()
+	if err != nil {
+		return fmt.Errorf("get bookmarks: %w", err)
+	}
+
+	fetched := make(map[string]domain.Icon)
+	stored := 0
+	for _, bookmark := range bookmarks {
+		icon, seen := fetched[bookmark.URL]
+		if !seen {
+			if icon, err = icons.FetchIcon(context.Background(), bookmark.Title, bookmark.URL, true); err != nil {
+				log.Printf("%s: %v", bookmark.URL, err)
+			}
+			fetched[bookmark.URL] = icon
+		}
+		if icon.Hash == "" || icon.Hash == bookmark.IconHash {
+			continue
+		}
+		if err := bookmarkRepo.UploadBookmarkFavicon(bookmark.ID, icon); err != nil {
+			return fmt.Errorf("update bookmark %d: %w", bookmark.ID, err)
+		}
+		stored++
+	}
+	fmt.Printf("updated icons of %d of %d bookmarks\n", stored, len(bookmarks))
+	return nil
+}
+
+// backfillColors gives icons stored before colors were extracted their
+// colors, then copies icon colors onto the bookmarks using them.
+func backfillColors(database *gorm.DB, cfg config.Config, _ []string) error {
+	blobs, err := blobstore.FromConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("open blob store: %w", err)
+	}
+	icons := usecase.NewIconUseCase(repository.NewIconRepository(database), blobs, logger.NewLogrusLogger(cfg.LogLevel))
+
+	updatedIcons, err := icons.BackfillColors()
+	if err != nil {
+		return err
+	}
+	updatedBookmarks, err := repository.NewBookmarkRepository(database).CopyIconColors()
+	if err != nil {
+		return fmt.Errorf("copy colors to bookmarks: %w", err)
+	}
+	fmt.Printf("extracted colors of %d icons, updated %d bookmarks\n", updatedIcons, updatedBookmarks)
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	config "github.com/OxytocinGroup/theca-backend/internal/config"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// retryJobs queues dead background jobs again, for instance after the
+// bug that made them fail was fixed.
+func retryJobs(database *gorm.DB, _ config.Config, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: retry-jobs [kind]")
+	}
+	kind := ""
+	if len(args) == 1 {
+		kind = args[0]
+	}
+
+	retried, err := repository.NewJobRepository(database).RetryDeadJobs(kind)
+	if err != nil {
+		return fmt.Errorf("retry jobs: %w", err)
+	}
+	fmt.Printf("queued %d dead jobs again\n", retried)
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	config "github.com/OxytocinGroup/theca-backend/internal/config"
+	db "github.com/OxytocinGroup/theca-backend/internal/db"
+	"gorm.io/gorm"
+)
+
+// command is a maintenance task run against the application database.
+type command struct {
+	usage       string
+	description string
+	run         func(database *gorm.DB, cfg config.Config, args []string) error
+}
+
+var commands = map[string]command{
+	"repair-counters": {
+		usage:       "repair-counters",
+		description: "recompute users' bookmark counters from their bookmarks",
+		run:         repairCounters,
+	},
+	"set-plan": {
+		usage:       "set-plan <username> <plan>",
+		description: "move a user to another plan",
+		run:         setPlan,
+	},
+	"list-plans": {
+		usage:       "list-plans",
+		description: "show the plans and their limits",
+		run:         listPlans,
+	},
+	"fetch-icons": {
+		usage:       "fetch-icons",
+		description: "fetch favicons of bookmarks without one, or with a generated one",
+		run:         fetchIcons,
+	},
+	"backfill-colors": {
+		usage:       "backfill-colors",
+		description: "extract missing icon colors and copy them onto bookmarks",
+		run:         backfillColors,
+	},
+	"retry-jobs": {
+		usage:       "retry-jobs [kind]",
+		description: "queue dead background jobs again, of one kind or all",
+		run:         retryJobs,
+	},
+	"normalize-urls": {
+		usage:       "normalize-urls",
+		description: "store the duplicate keys of bookmark URLs, e.g. after changing TRACKING_PARAMS",
+		run:         normalizeURLs,
+	},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		printUsage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal("cannot load config: ", err)
+	}
+	database := db.ConnectDatabase(cfg).GetDB()
+
+	if err := cmd.run(database, cfg, os.Args[2:]); err != nil {
+		log.Fatalf("%s: %v", os.Args[1], err)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: admin <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-32s %s\n", commands[name].usage, commands[name].description)
+	}
+}
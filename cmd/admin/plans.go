@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	config "github.com/OxytocinGroup/theca-backend/internal/config"
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+func setPlan(database *gorm.DB, _ config.Config, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: set-plan <username> <plan>")
+	}
+	username, planName := args[0], args[1]
+
+	user, err := repository.NewUserRepository(database).GetByUsername(username)
+	if err != nil {
+		return fmt.Errorf("get user %q: %w", username, err)
+	}
+	plan, err := repository.NewPlanRepository(database).GetPlanByName(planName)
+	if err != nil {
+		return fmt.Errorf("get plan %q: %w", planName, err)
+	}
+
+	// The free plan is the default, users on it keep no plan reference.
+	var planID *uint
+	if plan.Name != domain.PlanFree {
+		planID = &plan.ID
+	}
+	if err := repository.NewUserRepository(database).SetPlan(user.ID, planID); err != nil {
+		return err
+	}
+	fmt.Printf("user %s is now on the %s plan\n", user.Username, plan.Name)
+	return nil
+}
+
+func listPlans(database *gorm.DB, _ config.Config, _ []string) error {
+	plans, err := repository.NewPlanRepository(database).GetPlans()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%-16s %10s %10s %10s\n", "PLAN", "BOOKMARKS", "FOLDERS", "TAGS")
+	for _, plan := range plans {
+		fmt.Printf("%-16s %10s %10s %10s\n", plan.Name, formatLimit(plan.MaxBookmarks), formatLimit(plan.MaxFolders), formatLimit(plan.MaxTags))
+	}
+	return nil
+}
+
+func formatLimit(limit int) string {
+	if limit == domain.Unlimited {
+		return "unlimited"
+	}
+	return fmt.Sprint(limit)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	config "github.com/OxytocinGroup/theca-backend/internal/config"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg/urlnorm"
+	"gorm.io/gorm"
+)
+
+// normalizeURLs stores the duplicate key of every bookmark whose key is
+// missing or was computed with other tracking parameters. URLs themselves
+// are left as saved. When a user already has the key on an older
+// bookmark, the newer one is left without a key; such duplicates show up
+// in the duplicates listing and can be merged there.
+func normalizeURLs(database *gorm.DB, cfg config.Config, _ []string) error {
+	urls := urlnorm.New(cfg.TrackingParams)
+	bookmarkRepo := repository.NewBookmarkRepository(database)
+
+	bookmarks, err := bookmarkRepo.GetAllBookmarks()
+	if err != nil {
+		return fmt.Errorf("get bookmarks: %w", err)
+	}
+
+	updated, duplicates := 0, 0
+	for _, bookmark := range bookmarks {
+		key := urls.Key(bookmark.URL)
+		if key == bookmark.NormalizedURL {
+			continue
+		}
+		err := bookmarkRepo.SetNormalizedURL(bookmark.ID, key)
+		if errors.Is(err, repository.ErrDuplicateURL) {
+			duplicates++
+			if bookmark.NormalizedURL == "" {
+				continue
+			}
+			err = bookmarkRepo.SetNormalizedURL(bookmark.ID, "")
+		} else if err == nil {
+			updated++
+		}
+		if err != nil {
+			return fmt.Errorf("update bookmark %d: %w", bookmark.ID, err)
+		}
+	}
+	fmt.Printf("updated %d of %d bookmarks, %d are duplicates\n", updated, len(bookmarks), duplicates)
+	return nil
+}
@@ -22,7 +22,7 @@ const docTemplate = `{
                         "CookieAuth": []
                     }
                 ],
-                "description": "This endpoint allows an authenticated user to create a new bookmark.",
+                "description": "This endpoint allows an authenticated user to create a new bookmark. The URL is stored without tracking parameters and default ports, and saving a URL that leads to the same page as an existing bookmark fails with DUPLICATE_BOOKMARK. The title may be left empty: the bookmark then shows its URL until the title of the page is loaded.",
                 "consumes": [
                     "application/json"
                 ],
@@ -64,7 +64,7 @@ const docTemplate = `{
                         }
                     },
                     "409": {
-                        "description": "Limit of bookmarks",
+                        "description": "Limit of bookmarks or tags of the user's plan, or the URL is already saved",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
@@ -85,7 +85,7 @@ const docTemplate = `{
                         "CookieAuth": []
                     }
                 ],
-                "description": "Delete a specific bookmark associated with the user based on the bookmark ID",
+                "description": "Move a bookmark of the current user to the trash. It can be restored until the trash retention period passes.",
                 "consumes": [
                     "application/json"
                 ],
@@ -126,6 +126,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/pkg.Response"
                         }
                     },
+                    "404": {
+                        "description": "Bookmark not found or already in the trash",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -135,28 +141,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/bookmarks/get": {
+        "/api/bookmarks/duplicates": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Fetch all bookmarks associated with the current user",
+                "description": "Groups of the current user's bookmarks whose URLs lead to the same page, ignoring the scheme, a leading www., trailing slashes, tracking parameters and the order of query parameters. Bookmarks in each group are oldest first.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Bookmark"
                 ],
-                "summary": "Get bookmarks by user ID",
+                "summary": "List duplicate bookmarks",
                 "responses": {
                     "200": {
-                        "description": "List of bookmarks",
+                        "description": "Groups of duplicates",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/domain.Bookmark"
+                                "$ref": "#/definitions/domain.DuplicateGroup"
                             }
                         }
                     },
@@ -169,14 +175,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/bookmarks/update": {
+        "/api/bookmarks/duplicates/merge": {
             "post": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Update a specific bookmark associated with the user based on the bookmark ID",
+                "description": "Keep one bookmark of a group of duplicates and move the others to the trash. The kept bookmark gets their tags.",
                 "consumes": [
                     "application/json"
                 ],
@@ -186,33 +192,39 @@ const docTemplate = `{
                 "tags": [
                     "Bookmark"
                 ],
-                "summary": "Update a bookmark by ID",
+                "summary": "Merge duplicate bookmarks",
                 "parameters": [
                     {
-                        "description": "Request body with the bookmark ID to update",
+                        "description": "Bookmark to keep and its duplicates",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.Bookmark"
+                            "$ref": "#/definitions/requests.MergeDuplicatesRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully updated the bookmark",
+                        "description": "Bookmarks merged",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
                     },
                     "400": {
-                        "description": "Bad request, invalid input",
+                        "description": "Bad request, or the bookmarks are not duplicates",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
                     },
-                    "403": {
-                        "description": "Forbidden, the user does not have permission to update this bookmark",
+                    "404": {
+                        "description": "Bookmark not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Another bookmark holds the URL",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
@@ -226,62 +238,101 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/user/get-info": {
-            "post": {
-                "description": "Gives info about the user by finding him by session",
+        "/api/bookmarks/export": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Download all bookmarks of the current user with their folders, tags, icon URLs and show_text. The html format is a Netscape bookmark file that browsers and other bookmark managers can import.",
                 "produces": [
-                    "application/json"
+                    "text/html",
+                    "application/json",
+                    "text/csv",
+                    "text/markdown"
                 ],
                 "tags": [
-                    "User"
+                    "Bookmark"
+                ],
+                "summary": "Export bookmarks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "html (default), json, csv or md",
+                        "name": "format",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Give user info",
                 "responses": {
                     "200": {
-                        "description": "User inforamiton got successfully",
+                        "description": "Bookmark export",
                         "schema": {
-                            "$ref": "#/definitions/pkg.UserInfoResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Session isn't valid",
-                        "schema": {
-                            "$ref": "#/definitions/pkg.UserInfoResponse"
+                            "type": "file"
                         }
                     },
-                    "403": {
-                        "description": "Session cookie didn't found",
+                    "400": {
+                        "description": "Unknown format",
                         "schema": {
-                            "$ref": "#/definitions/pkg.UserInfoResponse"
+                            "$ref": "#/definitions/pkg.Response"
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/pkg.UserInfoResponse"
+                            "$ref": "#/definitions/pkg.Response"
                         }
                     }
                 }
             }
         },
-        "/api/user/logout": {
-            "delete": {
-                "description": "This endpoint allows a user to log out by deleting all active sessions associated with the user.",
-                "consumes": [
-                    "application/json"
+        "/api/bookmarks/get": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
                 ],
+                "description": "Fetch all bookmarks associated with the current user. With view=tree the bookmarks are returned nested in their folders. Repeat the tag parameter to filter by several tags.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Bookmark"
+                ],
+                "summary": "Get bookmarks by user ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Response layout: list (default) or tree",
+                        "name": "view",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only bookmarks with these tag names",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "all (default) requires every tag, any requires at least one",
+                        "name": "match",
+                        "in": "query"
+                    }
                 ],
-                "summary": "User logout",
                 "responses": {
                     "200": {
-                        "description": "Logout successful",
+                        "description": "List of bookmarks, or a domain.BookmarkTree object for view=tree",
                         "schema": {
-                            "$ref": "#/definitions/pkg.Response"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Bookmark"
+                            }
                         }
                     },
                     "500": {
@@ -293,67 +344,63 @@ const docTemplate = `{
                 }
             }
         },
-        "/user/login": {
-            "post": {
-                "description": "This endpoint allows a user to log in using their username and password. If already logged in, a conflict response is returned.",
-                "consumes": [
-                    "application/json"
+        "/api/bookmarks/health": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
                 ],
+                "description": "Bookmarks of the current user whose links the periodic link checker found broken or permanently redirected, with counts of every status. Repeat the status parameter to list other statuses: ok, redirected, broken, unknown (the site refuses automated checks) or unchecked.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Bookmark"
                 ],
-                "summary": "User login",
+                "summary": "List bookmarks by link health",
                 "parameters": [
                     {
-                        "description": "Username and password",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/requests.LoginRequest"
-                        }
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Link statuses to list, broken and redirected by default",
+                        "name": "status",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Login successful",
+                        "description": "Bookmarks and counts by status",
                         "schema": {
-                            "$ref": "#/definitions/pkg.LoginResponse"
+                            "$ref": "#/definitions/pkg.BookmarkHealthResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request - Invalid input",
-                        "schema": {
-                            "$ref": "#/definitions/pkg.Response"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized - Invalid username or password or not verified",
-                        "schema": {
-                            "$ref": "#/definitions/pkg.Response"
-                        }
-                    },
-                    "409": {
-                        "description": "Conflict - User already logged in",
+                        "description": "Unknown status",
                         "schema": {
-                            "$ref": "#/definitions/pkg.Response"
+                            "$ref": "#/definitions/pkg.BookmarkHealthResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/pkg.Response"
+                            "$ref": "#/definitions/pkg.BookmarkHealthResponse"
                         }
                     }
                 }
             }
         },
-        "/user/password-reset/request": {
+        "/api/bookmarks/health/apply-redirects": {
             "post": {
-                "description": "This endpoint allows a user to request a password reset by providing their email.",
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Move bookmarks whose links redirect permanently to the URL they redirect to. Bookmarks that are not redirected, or whose new URL is already saved, are skipped.",
                 "consumes": [
                     "application/json"
                 ],
@@ -361,35 +408,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Bookmark"
                 ],
-                "summary": "Request a password reset",
+                "summary": "Update redirected bookmarks",
                 "parameters": [
                     {
-                        "description": "User email",
+                        "description": "IDs of the bookmarks to update",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/requests.RequestPasswordReset"
+                            "$ref": "#/definitions/requests.ApplyRedirectsRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Password reset email sent",
+                        "description": "Numbers of updated and skipped bookmarks",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
                     },
                     "400": {
-                        "description": "Bad request - Invalid input",
-                        "schema": {
-                            "$ref": "#/definitions/pkg.Response"
-                        }
-                    },
-                    "404": {
-                        "description": "Email not found",
+                        "description": "Bad request, invalid input",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
@@ -403,45 +444,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/user/password-reset/reset": {
+        "/api/bookmarks/import": {
             "post": {
-                "description": "This endpoint allows a user to reset their password by providing a valid reset token and a new password.",
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Import bookmarks from an export file. The format is detected automatically: Netscape bookmark HTML (browsers, Theca), Pocket HTML or CSV, Raindrop.io CSV and Pinboard JSON are supported. Folders are recreated, already saved URLs are skipped and the limits of the user's plan are respected. The response reports the outcome of every entry.",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Bookmark"
                 ],
-                "summary": "Reset user password",
+                "summary": "Import bookmarks",
                 "parameters": [
                     {
-                        "description": "Reset token and new password",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/requests.ResetPassword"
-                        }
+                        "type": "file",
+                        "description": "Export file (up to 5 MB)",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Password reset successfully",
+                        "description": "Import report",
                         "schema": {
-                            "$ref": "#/definitions/pkg.Response"
+                            "$ref": "#/definitions/pkg.ImportResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request - Invalid input",
-                        "schema": {
-                            "$ref": "#/definitions/pkg.Response"
-                        }
-                    },
-                    "404": {
-                        "description": "Reset token not found or expired",
+                        "description": "Missing, unknown or invalid import file",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
@@ -449,15 +487,20 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/pkg.Response"
+                            "$ref": "#/definitions/pkg.ImportResponse"
                         }
                     }
                 }
             }
         },
-        "/user/register": {
+        "/api/bookmarks/preview": {
             "post": {
-                "description": "Register a new user",
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Load the page at a URL and return its title, description, site name, canonical URL and preview image, read from its OpenGraph and Twitter card tags or its \u003ctitle\u003e and description. Meant to fill in a bookmark before it is saved; bookmarks saved without a title get the one of their page automatically.",
                 "consumes": [
                     "application/json"
                 ],
@@ -465,51 +508,56 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Bookmark"
                 ],
-                "summary": "Register a new user",
+                "summary": "Preview a bookmark",
                 "parameters": [
                     {
-                        "description": "Username, email and password",
+                        "description": "URL of the page",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/requests.RegisterRequest"
+                            "$ref": "#/definitions/requests.PreviewBookmarkRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "Page metadata, with empty fields for what the page does not provide",
                         "schema": {
-                            "$ref": "#/definitions/pkg.Response"
+                            "$ref": "#/definitions/pkg.BookmarkPreviewResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid body, or the URL is not a public http or https address",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "401": {
+                        "description": "Unauthorized - User not authenticated",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "502": {
+                        "description": "The page could not be loaded",
                         "schema": {
-                            "$ref": "#/definitions/pkg.Response"
+                            "$ref": "#/definitions/pkg.BookmarkPreviewResponse"
                         }
                     }
                 }
             }
         },
-        "/user/verify-email": {
+        "/api/bookmarks/reorder": {
             "post": {
-                "description": "This endpoint allows a user to verify their email address by providing the email and verification code.",
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Changes the manual position of a bookmark. With move_to_folder the bookmark is also moved into the folder of the target bookmark, otherwise it stays in its folder.",
                 "consumes": [
                     "application/json"
                 ],
@@ -517,35 +565,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Bookmark"
                 ],
-                "summary": "Verify email address",
+                "summary": "Move a bookmark before or after another one",
                 "parameters": [
                     {
-                        "description": "verification code",
+                        "description": "Bookmark to move, target bookmark and placement",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/requests.EmailVerifyRequest"
+                            "$ref": "#/definitions/requests.ReorderBookmarkRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Email verified successfully",
+                        "description": "Bookmark moved",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
                     },
                     "400": {
-                        "description": "Bad request - Invalid input",
+                        "description": "Bad request, invalid input",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
                     },
                     "404": {
-                        "description": "Verification code not found",
+                        "description": "Bookmark or target not found",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
@@ -559,9 +607,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/user/verify-email/request": {
+        "/api/bookmarks/restore": {
             "post": {
-                "description": "Resends a verification token for the provided username.",
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Move a deleted bookmark back to its folder, or to the root level when the folder was deleted too. Counts towards the bookmark limit again.",
                 "consumes": [
                     "application/json"
                 ],
@@ -569,23 +622,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Bookmark"
                 ],
-                "summary": "Resend Verification Token",
+                "summary": "Restore a bookmark from the trash",
                 "parameters": [
                     {
-                        "description": "Request body containing the username",
+                        "description": "ID of the deleted bookmark",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/requests.RequestVerificationToken"
+                            "$ref": "#/definitions/requests.RestoreBookmarkRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Verification token resent successfully",
+                        "description": "Bookmark restored",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
@@ -596,14 +649,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/pkg.Response"
                         }
                     },
+                    "403": {
+                        "description": "Bookmark belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
                     "404": {
-                        "description": "User not found",
+                        "description": "Bookmark not found in trash",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
                     },
                     "409": {
-                        "description": "User has verified email",
+                        "description": "Limit of bookmarks of the user's plan, or the URL is saved again",
                         "schema": {
                             "$ref": "#/definitions/pkg.Response"
                         }
@@ -616,46 +675,2482 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "domain.Bookmark": {
-            "type": "object",
-            "properties": {
-                "icon_url": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "show_text": {
-                    "type": "boolean"
-                },
-                "title": {
-                    "type": "string"
-                },
-                "url": {
-                    "type": "string"
-                },
-                "user_id": {
-                    "type": "integer"
-                }
-            }
+        },
+        "/api/bookmarks/search": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Full-text and fuzzy search over the title and the URL host and path of the current user's bookmarks. Results are ranked, highlighted and paginated.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bookmark"
+                ],
+                "summary": "Search bookmarks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, starting from 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Results per page (default 20, max 100)",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matching bookmarks",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.BookmarkSearchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Empty search query",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.BookmarkSearchResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.BookmarkSearchResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/bookmarks/trash": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Bookmarks deleted by the current user, most recently deleted first. They are removed for good after the retention period.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bookmark"
+                ],
+                "summary": "List the trash",
+                "responses": {
+                    "200": {
+                        "description": "Deleted bookmarks",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Bookmark"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Permanently delete every bookmark in the current user's trash.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bookmark"
+                ],
+                "summary": "Empty the trash",
+                "responses": {
+                    "200": {
+                        "description": "Trash emptied",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/bookmarks/update": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Update a specific bookmark associated with the user based on the bookmark ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bookmark"
+                ],
+                "summary": "Update a bookmark by ID",
+                "parameters": [
+                    {
+                        "description": "Request body with the bookmark ID to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Bookmark"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully updated the bookmark",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request, invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden, the user does not have permission to update this bookmark",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Limit of tags of the user's plan, or another bookmark has this URL",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/folders/create": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Create a new bookmark folder. Set parent_id to nest it inside another folder.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Folder"
+                ],
+                "summary": "Create a folder",
+                "parameters": [
+                    {
+                        "description": "Folder details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Folder"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Folder created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Parent folder belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Parent folder not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Limit of folders of the user's plan",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/folders/delete": {
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Delete a folder. With cascade the nested folders and their bookmarks are deleted too, otherwise the contents are moved to the parent folder.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Folder"
+                ],
+                "summary": "Delete a folder by ID",
+                "parameters": [
+                    {
+                        "description": "Folder ID and delete mode",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.DeleteFolderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully deleted the folder",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request, invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden, the folder belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Folder not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/folders/get": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Fetch all folders of the current user as a flat list",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Folder"
+                ],
+                "summary": "Get folders",
+                "responses": {
+                    "200": {
+                        "description": "List of folders",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Folder"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/folders/update": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Rename, reorder or move a folder. A folder can't be moved into itself or one of its subfolders.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Folder"
+                ],
+                "summary": "Update a folder by ID",
+                "parameters": [
+                    {
+                        "description": "Folder with the ID to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Folder"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully updated the folder",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request, invalid input or parent folder",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden, the folder belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Folder not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tags/create": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Create a new tag for the current user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Create a tag",
+                "parameters": [
+                    {
+                        "description": "Tag name",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.TagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Tag created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Tag with this name already exists, or limit of tags of the user's plan",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tags/delete": {
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Delete a tag and detach it from all bookmarks",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Delete a tag",
+                "parameters": [
+                    {
+                        "description": "Tag ID",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.DeleteTagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tag deleted",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Tag belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Tag not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tags/get": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Fetch all tags of the current user with the number of bookmarks using each tag",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Get tags",
+                "responses": {
+                    "200": {
+                        "description": "List of tags",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Tag"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tags/merge": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Move all bookmarks of the source tags to the target tag and delete the source tags",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Merge tags",
+                "parameters": [
+                    {
+                        "description": "Source tag IDs and target tag ID",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.MergeTagsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tags merged",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Tag belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Tag not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tags/rename": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Rename a tag of the current user. Use merge when a tag with the new name already exists.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Rename a tag",
+                "parameters": [
+                    {
+                        "description": "Tag ID and new name",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.TagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tag renamed",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Tag belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Tag not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Tag with this name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa/disable": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Turns two-factor authentication off for the current user after checking their password. The authenticator app's secret and the recovery codes stop working.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Disable two-factor authentication",
+                "parameters": [
+                    {
+                        "description": "Current password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.DisableTwoFactorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Two-factor authentication disabled",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Wrong password",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication is not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa/totp": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Generates a TOTP secret for the current user, as an otpauth:// URI and a QR code PNG (base64). Two-factor authentication is enabled once a code from the app is confirmed; enrolling again replaces an unconfirmed secret.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Start enrolling an authenticator app",
+                "responses": {
+                    "200": {
+                        "description": "Secret to add to the app",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.TOTPEnrollmentResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication is already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.TOTPEnrollmentResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.TOTPEnrollmentResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Enables two-factor authentication with a first code from the enrolled app. The response holds recovery codes, each usable once in place of a code; they are not shown again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Confirm an authenticator app",
+                "parameters": [
+                    {
+                        "description": "Code from the app",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.ConfirmTOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Two-factor authentication enabled",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request, or no app being enrolled",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.RecoveryCodesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid code",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.RecoveryCodesResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication is already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.RecoveryCodesResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.RecoveryCodesResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/change-password": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Change the current user's password after checking the current one. Every session of the user is ended and the device making the request gets a new one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Change password",
+                "parameters": [
+                    {
+                        "description": "Current and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password changed",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Current password is wrong",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/get-info": {
+            "post": {
+                "description": "Gives info about the user by finding him by session, with the plan and how much of its limits is used (-1 means unlimited)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Give user info",
+                "responses": {
+                    "200": {
+                        "description": "User inforamiton got successfully",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.UserInfoResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Session isn't valid",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.UserInfoResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Session cookie didn't found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.UserInfoResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.UserInfoResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.UserInfoResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/logout": {
+            "delete": {
+                "description": "This endpoint allows a user to log out by ending the current session. With all=true every session of the user is ended, logging them out on all devices.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "User logout",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "End all sessions of the user, not only the current one",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Logout successful",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/passkeys": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "The passkeys of the current user, oldest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "List passkeys",
+                "responses": {
+                    "200": {
+                        "description": "Passkeys",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Passkey"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/passkeys/register/begin": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the options for navigator.credentials.create() to add a passkey with the name to the current user's account. Without a name it is called \"Passkey N\". The credential is sent to /api/user/passkeys/register/finish with the ceremony within 5 minutes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Start adding a passkey",
+                "parameters": [
+                    {
+                        "description": "Name of the passkey",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BeginPasskeyRegistrationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Options for the browser",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.PasskeyOptionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Limit of passkeys reached",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.PasskeyOptionsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.PasskeyOptionsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/passkeys/register/finish": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Stores the passkey created by the browser for the current user. It can then be used to log in, alone or as a second factor.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Add a passkey",
+                "parameters": [
+                    {
+                        "description": "Ceremony and credential",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.FinishPasskeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Passkey added",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Passkey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request, invalid passkey, or the ceremony expired",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "The passkey is already registered",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/passkeys/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Rename a passkey",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Passkey ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New name",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.RenamePasskeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Passkey renamed",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Passkey not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "The passkey can no longer be used to log in.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Remove a passkey",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Passkey ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Passkey removed",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Passkey not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "The sessions the current user is logged in with, one per device or browser, most recently used first. The session of the request is marked as current.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "List active sessions",
+                "responses": {
+                    "200": {
+                        "description": "Active sessions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Session"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Log the current user out on one device by ending the session with the given id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Revoke a session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session id, as listed",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Session revoked",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/icons/{hash}": {
+            "get": {
+                "description": "Serve a stored favicon as a square PNG of the requested size. SVG icons, including the letter avatars generated for sites without a favicon, are also available as SVG; those that could not be rasterized are always served as sanitized SVG. Icons are addressed by the SHA-256 of their content and never change, so they may be cached forever.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "Icon"
+                ],
+                "summary": "Get an icon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of the icon, as found in a bookmark's icon_hash",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 64,
+                        "description": "Edge in pixels of the PNG: 32, 64 or 128",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "png",
+                        "description": "png or svg",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Icon image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Icon not modified"
+                    },
+                    "400": {
+                        "description": "Unsupported size or format",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Icon not found, or it has no SVG version",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login": {
+            "post": {
+                "description": "This endpoint allows a user to log in using their username and password. If already logged in, a conflict response is returned. With remember_me the session lasts for weeks of inactivity instead of hours. Users with two-factor authentication or a passkey get 202 and a challenge to complete the login with at /user/login/2fa.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "User login",
+                "parameters": [
+                    {
+                        "description": "Username and password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Login successful",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.LoginResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Password accepted, a second factor is required",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - Invalid username or password or not verified",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - User already logged in",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login/2fa": {
+            "post": {
+                "description": "Completes a login that returned a challenge with, when \"totp\" is among the challenge's methods, a code of the user's authenticator app or one of their recovery codes or, when \"passkey\" is among the challenge's methods, the credential answering the options of /user/login/2fa/passkey. It opens a session. A challenge is valid for 5 minutes and 5 attempts.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Complete a two-factor login",
+                "parameters": [
+                    {
+                        "description": "Challenge and code or passkey credential",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.TwoFactorLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Login successful",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid code or passkey, or the challenge expired",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login/2fa/passkey": {
+            "post": {
+                "description": "Returns the options for navigator.credentials.get() asking for one of the passkeys of the user logging in. The credential is then sent to /user/login/2fa with the challenge.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Answer a two-factor login with a passkey",
+                "parameters": [
+                    {
+                        "description": "Challenge",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.TwoFactorPasskeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Options for the browser",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.PasskeyOptionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request, or the user has no passkeys",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.PasskeyOptionsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "The challenge expired",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.PasskeyOptionsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.PasskeyOptionsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login/passkey/begin": {
+            "post": {
+                "description": "Returns the options for navigator.credentials.get() to log in with any passkey of the site, without a username or password. The authenticator must verify the user. The credential is sent to /user/login/passkey/finish with the ceremony within 5 minutes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Start a passkey login",
+                "parameters": [
+                    {
+                        "description": "Remember me",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BeginPasskeyLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Options for the browser",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.PasskeyOptionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.PasskeyOptionsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login/passkey/finish": {
+            "post": {
+                "description": "Completes a passkey login with the credential returned by the browser, opening a session for the passkey's user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Log in with a passkey",
+                "parameters": [
+                    {
+                        "description": "Ceremony and credential",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.FinishPasskeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Login successful",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request, or the ceremony expired",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid passkey",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/password-reset/request": {
+            "post": {
+                "description": "This endpoint allows a user to request a password reset by providing their email.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Request a password reset",
+                "parameters": [
+                    {
+                        "description": "User email",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.RequestPasswordReset"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password reset email sent",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Email not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/password-reset/reset": {
+            "post": {
+                "description": "This endpoint allows a user to reset their password by providing a valid reset token and a new password.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Reset user password",
+                "parameters": [
+                    {
+                        "description": "Reset token and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.ResetPassword"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password reset successfully",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Reset token not found or expired",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/register": {
+            "post": {
+                "description": "Register a new user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "Username, email and password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/verify-email": {
+            "post": {
+                "description": "This endpoint allows a user to verify their email address by providing the email and verification code.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Verify email address",
+                "parameters": [
+                    {
+                        "description": "verification code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.EmailVerifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email verified successfully",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Verification code not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/verify-email/request": {
+            "post": {
+                "description": "Resends a verification token for the provided username.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Resend Verification Token",
+                "parameters": [
+                    {
+                        "description": "Request body containing the username",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.RequestVerificationToken"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Verification token resent successfully",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request, invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "User has verified email",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/pkg.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "domain.Bookmark": {
+            "type": "object",
+            "properties": {
+                "accent_color": {
+                    "type": "string"
+                },
+                "canonical_url": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is set while the bookmark is in the trash.",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description, SiteName, CanonicalURL and ImageURL are read from the\npage after the bookmark is saved, for link previews. Empty until\nthen and when the page does not provide them.",
+                    "type": "string"
+                },
+                "dominant_color": {
+                    "description": "DominantColor and AccentColor come from the icon, as \"#rrggbb\", for\ntiles in the site's colors. Empty until the icon is known.",
+                    "type": "string"
+                },
+                "folder_id": {
+                    "type": "integer"
+                },
+                "icon_hash": {
+                    "type": "string"
+                },
+                "icon_url": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "image_url": {
+                    "type": "string"
+                },
+                "import_source": {
+                    "description": "ImportSource and ImportTags record where an imported bookmark came\nfrom and the tags it had there, as written by the source.",
+                    "type": "string"
+                },
+                "import_tags": {
+                    "type": "string"
+                },
+                "link_checked_at": {
+                    "type": "string"
+                },
+                "link_final_url": {
+                    "type": "string"
+                },
+                "link_status": {
+                    "description": "LinkStatus is what the link checker last found at URL, one of the\nLink constants, and empty until the first check. LinkStatusCode is\nthe status of the last response, 0 when none came, and LinkFinalURL\nwhere redirects led, if anywhere.",
+                    "type": "string"
+                },
+                "link_status_code": {
+                    "type": "integer"
+                },
+                "position": {
+                    "type": "string"
+                },
+                "show_text": {
+                    "type": "boolean"
+                },
+                "site_name": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Tag"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.BookmarkSearchResult": {
+            "type": "object",
+            "properties": {
+                "accent_color": {
+                    "type": "string"
+                },
+                "canonical_url": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is set while the bookmark is in the trash.",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description, SiteName, CanonicalURL and ImageURL are read from the\npage after the bookmark is saved, for link previews. Empty until\nthen and when the page does not provide them.",
+                    "type": "string"
+                },
+                "dominant_color": {
+                    "description": "DominantColor and AccentColor come from the icon, as \"#rrggbb\", for\ntiles in the site's colors. Empty until the icon is known.",
+                    "type": "string"
+                },
+                "folder_id": {
+                    "type": "integer"
+                },
+                "icon_hash": {
+                    "type": "string"
+                },
+                "icon_url": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "image_url": {
+                    "type": "string"
+                },
+                "import_source": {
+                    "description": "ImportSource and ImportTags record where an imported bookmark came\nfrom and the tags it had there, as written by the source.",
+                    "type": "string"
+                },
+                "import_tags": {
+                    "type": "string"
+                },
+                "link_checked_at": {
+                    "type": "string"
+                },
+                "link_final_url": {
+                    "type": "string"
+                },
+                "link_status": {
+                    "description": "LinkStatus is what the link checker last found at URL, one of the\nLink constants, and empty until the first check. LinkStatusCode is\nthe status of the last response, 0 when none came, and LinkFinalURL\nwhere redirects led, if anywhere.",
+                    "type": "string"
+                },
+                "link_status_code": {
+                    "type": "integer"
+                },
+                "position": {
+                    "type": "string"
+                },
+                "rank": {
+                    "type": "number"
+                },
+                "show_text": {
+                    "type": "boolean"
+                },
+                "site_name": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Tag"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "title_highlight": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "url_highlight": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.DuplicateGroup": {
+            "type": "object",
+            "properties": {
+                "bookmarks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Bookmark"
+                    }
+                },
+                "normalized_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Folder": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "type": "integer"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.LinkHealthSummary": {
+            "type": "object",
+            "properties": {
+                "broken": {
+                    "type": "integer"
+                },
+                "ok": {
+                    "type": "integer"
+                },
+                "redirected": {
+                    "type": "integer"
+                },
+                "unchecked": {
+                    "type": "integer"
+                },
+                "unknown": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Passkey": {
+            "type": "object",
+            "properties": {
+                "backed_up": {
+                    "type": "boolean"
+                },
+                "clone_warning": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.QuotaUsage": {
+            "type": "object",
+            "properties": {
+                "bookmarks": {
+                    "$ref": "#/definitions/domain.ResourceUsage"
+                },
+                "folders": {
+                    "$ref": "#/definitions/domain.ResourceUsage"
+                },
+                "plan": {
+                    "type": "string"
+                },
+                "tags": {
+                    "$ref": "#/definitions/domain.ResourceUsage"
+                }
+            }
+        },
+        "domain.ResourceUsage": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "used": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Session": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "Current marks the session of the request listing sessions.",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt moves forward as the session is used, by the idle timeout\nof its policy, but never past MaxExpiresAt. Remember selects the long\npolicy.",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Handle identifies the session to its user. UserAgent and IP are\nthose of the device at login. LastSeenAt is only written every few\nminutes.",
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "remember": {
+                    "type": "boolean"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Tag": {
+            "type": "object",
+            "properties": {
+                "bookmark_count": {
+                    "description": "BookmarkCount is only filled when tags are listed with their usage.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "parsers.PageMetadata": {
+            "type": "object",
+            "properties": {
+                "canonical_url": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "image_url": {
+                    "type": "string"
+                },
+                "site_name": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "url": {
+                    "description": "URL is where the page was found, after redirects.",
+                    "type": "string"
+                }
+            }
+        },
+        "pkg.BookmarkHealthResponse": {
+            "type": "object",
+            "properties": {
+                "bookmarks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Bookmark"
+                    }
+                },
+                "code": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "summary": {
+                    "$ref": "#/definitions/domain.LinkHealthSummary"
+                }
+            }
+        },
+        "pkg.BookmarkPreviewResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "preview": {
+                    "$ref": "#/definitions/parsers.PageMetadata"
+                }
+            }
+        },
+        "pkg.BookmarkSearchResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.BookmarkSearchResult"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "pkg.ImportItem": {
+            "type": "object",
+            "properties": {
+                "folder": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "pkg.ImportResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pkg.ImportItem"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "skipped": {
+                    "type": "integer"
+                }
+            }
         },
         "pkg.LoginResponse": {
             "type": "object",
             "properties": {
+                "challenge": {
+                    "type": "string"
+                },
                 "code": {
                     "type": "integer"
                 },
                 "message": {
                     "type": "string"
                 },
+                "methods": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "username": {
                     "type": "string"
                 }
             }
         },
+        "pkg.PasskeyOptionsResponse": {
+            "type": "object",
+            "properties": {
+                "ceremony": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "options": {}
+            }
+        },
+        "pkg.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "pkg.Response": {
             "type": "object",
             "properties": {
@@ -670,6 +3165,32 @@ const docTemplate = `{
                 }
             }
         },
+        "pkg.TOTPEnrollmentResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "qr_code": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "uri": {
+                    "type": "string"
+                }
+            }
+        },
         "pkg.UserInfoResponse": {
             "type": "object",
             "properties": {
@@ -682,11 +3203,112 @@ const docTemplate = `{
                 "message": {
                     "type": "string"
                 },
+                "two_factor_enabled": {
+                    "type": "boolean"
+                },
+                "usage": {
+                    "$ref": "#/definitions/domain.QuotaUsage"
+                },
                 "username": {
                     "type": "string"
                 }
             }
         },
+        "requests.ApplyRedirectsRequest": {
+            "type": "object",
+            "required": [
+                "ids"
+            ],
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "requests.BeginPasskeyLoginRequest": {
+            "type": "object",
+            "properties": {
+                "remember_me": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "requests.BeginPasskeyRegistrationRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "requests.ChangePasswordRequest": {
+            "type": "object",
+            "required": [
+                "current_password",
+                "password"
+            ],
+            "properties": {
+                "current_password": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 6
+                }
+            }
+        },
+        "requests.ConfirmTOTPRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "requests.DeleteFolderRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "cascade": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "requests.DeleteTagRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "requests.DisableTwoFactorRequest": {
+            "type": "object",
+            "required": [
+                "password"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
         "requests.EmailVerifyRequest": {
             "type": "object",
             "required": [
@@ -699,6 +3321,21 @@ const docTemplate = `{
                 }
             }
         },
+        "requests.FinishPasskeyRequest": {
+            "type": "object",
+            "required": [
+                "ceremony",
+                "credential"
+            ],
+            "properties": {
+                "ceremony": {
+                    "type": "string"
+                },
+                "credential": {
+                    "type": "object"
+                }
+            }
+        },
         "requests.LoginRequest": {
             "type": "object",
             "required": [
@@ -707,9 +3344,63 @@ const docTemplate = `{
             ],
             "properties": {
                 "password": {
-                    "type": "string"
+                    "type": "string",
+                    "minLength": 6
+                },
+                "remember_me": {
+                    "type": "boolean"
                 },
                 "username": {
+                    "type": "string",
+                    "minLength": 3
+                }
+            }
+        },
+        "requests.MergeDuplicatesRequest": {
+            "type": "object",
+            "required": [
+                "keep_id",
+                "merge_ids"
+            ],
+            "properties": {
+                "keep_id": {
+                    "type": "integer"
+                },
+                "merge_ids": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "requests.MergeTagsRequest": {
+            "type": "object",
+            "required": [
+                "source_ids",
+                "target_id"
+            ],
+            "properties": {
+                "source_ids": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "target_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "requests.PreviewBookmarkRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "url": {
                     "type": "string"
                 }
             }
@@ -727,7 +3418,7 @@ const docTemplate = `{
                 },
                 "password": {
                     "type": "string",
-                    "minLength": 8
+                    "minLength": 6
                 },
                 "username": {
                     "type": "string",
@@ -735,6 +3426,45 @@ const docTemplate = `{
                 }
             }
         },
+        "requests.RenamePasskeyRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "requests.ReorderBookmarkRequest": {
+            "type": "object",
+            "required": [
+                "id",
+                "placement",
+                "target_id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "move_to_folder": {
+                    "description": "MoveToFolder also moves the bookmark into the folder of the target.",
+                    "type": "boolean"
+                },
+                "placement": {
+                    "type": "string",
+                    "enum": [
+                        "before",
+                        "after"
+                    ]
+                },
+                "target_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "requests.RequestPasswordReset": {
             "type": "object",
             "required": [
@@ -772,6 +3502,60 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "requests.RestoreBookmarkRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "requests.TagRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "requests.TwoFactorLoginRequest": {
+            "type": "object",
+            "required": [
+                "challenge"
+            ],
+            "properties": {
+                "challenge": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "credential": {
+                    "type": "object"
+                }
+            }
+        },
+        "requests.TwoFactorPasskeyRequest": {
+            "type": "object",
+            "required": [
+                "challenge"
+            ],
+            "properties": {
+                "challenge": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
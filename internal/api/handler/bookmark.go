@@ -1,13 +1,19 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/OxytocinGroup/theca-backend/internal/domain"
 	"github.com/OxytocinGroup/theca-backend/internal/usecase"
 	"github.com/OxytocinGroup/theca-backend/pkg"
 	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/exporters"
+	"github.com/OxytocinGroup/theca-backend/pkg/importers"
 	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"github.com/OxytocinGroup/theca-backend/pkg/requests"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,7 +31,7 @@ func NewBookmarkHandler(usecase usecase.BookmarkUseCase, log logger.Logger) *Boo
 
 // @CreateBookmark GoDoc
 // @Summary Create a bookmark
-// @Description This endpoint allows an authenticated user to create a new bookmark.
+// @Description This endpoint allows an authenticated user to create a new bookmark. The URL is stored without tracking parameters and default ports, and saving a URL that leads to the same page as an existing bookmark fails with DUPLICATE_BOOKMARK. The title may be left empty: the bookmark then shows its URL until the title of the page is loaded.
 // @Tags Bookmark
 // @Accept  json
 // @Produce  json
@@ -33,7 +39,7 @@ func NewBookmarkHandler(usecase usecase.BookmarkUseCase, log logger.Logger) *Boo
 // @Success 201 {object} pkg.Response "Bookmark created successfully"
 // @Failure 400 {object} pkg.Response "Bad request - Invalid input"
 // @Failure 401 {object} pkg.Response "Unauthorized - User not authenticated"
-// @Failure 409 {object} pkg.Response "Limit of bookmarks"
+// @Failure 409 {object} pkg.Response "Limit of bookmarks or tags of the user's plan, or the URL is already saved"
 // @Failure 500 {object} pkg.Response "Internal server error"
 // @Security CookieAuth
 // @Router /api/bookmarks/create [post]
@@ -52,24 +58,67 @@ func (bh *BookmarkHandler) CreateBookmark(c *gin.Context) {
 	c.JSON(resp.Code, resp)
 }
 
+// PreviewBookmark godoc
+// @Summary Preview a bookmark
+// @Description Load the page at a URL and return its title, description, site name, canonical URL and preview image, read from its OpenGraph and Twitter card tags or its <title> and description. Meant to fill in a bookmark before it is saved; bookmarks saved without a title get the one of their page automatically.
+// @Tags Bookmark
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.PreviewBookmarkRequest true "URL of the page"
+// @Success 200 {object} pkg.BookmarkPreviewResponse "Page metadata, with empty fields for what the page does not provide"
+// @Failure 400 {object} pkg.Response "Invalid body, or the URL is not a public http or https address"
+// @Failure 401 {object} pkg.Response "Unauthorized - User not authenticated"
+// @Failure 502 {object} pkg.BookmarkPreviewResponse "The page could not be loaded"
+// @Router /api/bookmarks/preview [post]
+func (bh *BookmarkHandler) PreviewBookmark(c *gin.Context) {
+	var req requests.PreviewBookmarkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bh.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := bh.BookmarkUseCase.PreviewBookmark(c.Request.Context(), req.URL)
+	c.JSON(resp.Code, resp)
+}
+
 // GetBookmarks godoc
 // @Summary Get bookmarks by user ID
-// @Description Fetch all bookmarks associated with the current user
+// @Description Fetch all bookmarks associated with the current user. With view=tree the bookmarks are returned nested in their folders. Repeat the tag parameter to filter by several tags.
 // @Tags Bookmark
 // @Produce json
 // @Security CookieAuth
-// @Success 200 {array} domain.Bookmark "List of bookmarks"
+// @Param view query string false "Response layout: list (default) or tree"
+// @Param tag query []string false "Only bookmarks with these tag names" collectionFormat(multi)
+// @Param match query string false "all (default) requires every tag, any requires at least one"
+// @Success 200 {array} domain.Bookmark "List of bookmarks, or a domain.BookmarkTree object for view=tree"
 // @Failure 500 {object} pkg.Response "Internal server error"
 // @Router /api/bookmarks/get [get]
 func (bh *BookmarkHandler) GetBookmarks(c *gin.Context) {
 	userID := c.GetUint("user_id")
-	bookmarks, resp := bh.BookmarkUseCase.GetBookmarksByUser(userID)
+	filter := domain.BookmarkFilter{
+		Tags:     c.QueryArray("tag"),
+		MatchAll: c.DefaultQuery("match", "all") != "any",
+	}
+
+	if c.Query("view") == "tree" {
+		tree, resp := bh.BookmarkUseCase.GetBookmarkTree(userID, filter)
+		if resp.Code != http.StatusOK {
+			c.JSON(resp.Code, resp)
+			return
+		}
+		c.JSON(resp.Code, tree)
+		return
+	}
+
+	bookmarks, resp := bh.BookmarkUseCase.GetBookmarksByUser(userID, filter)
 	c.JSON(resp.Code, bookmarks)
 }
 
 // DeleteBookmark godoc
 // @Summary Delete a bookmark by ID
-// @Description Delete a specific bookmark associated with the user based on the bookmark ID
+// @Description Move a bookmark of the current user to the trash. It can be restored until the trash retention period passes.
 // @Tags Bookmark
 // @Accept json
 // @Produce json
@@ -78,6 +127,7 @@ func (bh *BookmarkHandler) GetBookmarks(c *gin.Context) {
 // @Success 200 {object} pkg.Response "Successfully deleted the bookmark"
 // @Failure 400 {object} pkg.Response "Bad request, invalid input"
 // @Failure 403 {object} pkg.Response "Forbidden, the user does not have permission to delete this bookmark"
+// @Failure 404 {object} pkg.Response "Bookmark not found or already in the trash"
 // @Failure 500 {object} pkg.Response "Internal server error"
 // @Router /api/bookmarks/delete [delete]
 func (bh *BookmarkHandler) DeleteBookmark(c *gin.Context) {
@@ -105,6 +155,7 @@ func (bh *BookmarkHandler) DeleteBookmark(c *gin.Context) {
 // @Success 200 {object} pkg.Response "Successfully updated the bookmark"
 // @Failure 400 {object} pkg.Response "Bad request, invalid input"
 // @Failure 403 {object} pkg.Response "Forbidden, the user does not have permission to update this bookmark"
+// @Failure 409 {object} pkg.Response "Limit of tags of the user's plan, or another bookmark has this URL"
 // @Failure 500 {object} pkg.Response "Internal server error"
 // @Router /api/bookmarks/update [post]
 func (bh *BookmarkHandler) UpdateBookmark(c *gin.Context) {
@@ -121,3 +172,281 @@ func (bh *BookmarkHandler) UpdateBookmark(c *gin.Context) {
 	resp := bh.BookmarkUseCase.UpdateBookmark(userID, &bookmark)
 	c.JSON(resp.Code, resp)
 }
+
+// ReorderBookmark godoc
+// @Summary Move a bookmark before or after another one
+// @Description Changes the manual position of a bookmark. With move_to_folder the bookmark is also moved into the folder of the target bookmark, otherwise it stays in its folder.
+// @Tags Bookmark
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.ReorderBookmarkRequest true "Bookmark to move, target bookmark and placement"
+// @Success 200 {object} pkg.Response "Bookmark moved"
+// @Failure 400 {object} pkg.Response "Bad request, invalid input"
+// @Failure 404 {object} pkg.Response "Bookmark or target not found"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/bookmarks/reorder [post]
+func (bh *BookmarkHandler) ReorderBookmark(c *gin.Context) {
+	var req requests.ReorderBookmarkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bh.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := bh.BookmarkUseCase.ReorderBookmark(c.GetUint("user_id"), req.ID, req.TargetID, req.Placement, req.MoveToFolder)
+	c.JSON(resp.Code, resp)
+}
+
+// SearchBookmarks godoc
+// @Summary Search bookmarks
+// @Description Full-text and fuzzy search over the title and the URL host and path of the current user's bookmarks. Results are ranked, highlighted and paginated.
+// @Tags Bookmark
+// @Produce json
+// @Security CookieAuth
+// @Param q query string true "Search query"
+// @Param page query int false "Page number, starting from 1"
+// @Param page_size query int false "Results per page (default 20, max 100)"
+// @Success 200 {object} pkg.BookmarkSearchResponse "Matching bookmarks"
+// @Failure 400 {object} pkg.BookmarkSearchResponse "Empty search query"
+// @Failure 500 {object} pkg.BookmarkSearchResponse "Internal server error"
+// @Router /api/bookmarks/search [get]
+func (bh *BookmarkHandler) SearchBookmarks(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	resp := bh.BookmarkUseCase.SearchBookmarks(c.GetUint("user_id"), c.Query("q"), page, pageSize)
+	c.JSON(resp.Code, resp)
+}
+
+// maxImportFileSize caps the size of an uploaded bookmark file.
+const maxImportFileSize = 5 << 20
+
+// ImportBookmarks godoc
+// @Summary Import bookmarks
+// @Description Import bookmarks from an export file. The format is detected automatically: Netscape bookmark HTML (browsers, Theca), Pocket HTML or CSV, Raindrop.io CSV and Pinboard JSON are supported. Folders are recreated, already saved URLs are skipped and the limits of the user's plan are respected. The response reports the outcome of every entry.
+// @Tags Bookmark
+// @Accept multipart/form-data
+// @Produce json
+// @Security CookieAuth
+// @Param file formData file true "Export file (up to 5 MB)"
+// @Success 200 {object} pkg.ImportResponse "Import report"
+// @Failure 400 {object} pkg.Response "Missing, unknown or invalid import file"
+// @Failure 500 {object} pkg.ImportResponse "Internal server error"
+// @Router /api/bookmarks/import [post]
+func (bh *BookmarkHandler) ImportBookmarks(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		bh.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		bh.Logger.Error(c, "Import bookmarks: failed to open uploaded file", map[string]any{"error": err})
+		c.JSON(http.StatusInternalServerError, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	source, entries, err := importers.Parse(header.Filename, file)
+	if err != nil {
+		bh.Logger.Info(c, "invalid import file", map[string]any{"error": err, "source": source})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: err.Error(), Error: cerr.ErrInvalidImportFile})
+		return
+	}
+
+	resp := bh.BookmarkUseCase.ImportBookmarks(c.GetUint("user_id"), source, entries)
+	c.JSON(resp.Code, resp)
+}
+
+// ExportBookmarks godoc
+// @Summary Export bookmarks
+// @Description Download all bookmarks of the current user with their folders, tags, icon URLs and show_text. The html format is a Netscape bookmark file that browsers and other bookmark managers can import.
+// @Tags Bookmark
+// @Produce html
+// @Produce json
+// @Produce text/csv
+// @Produce text/markdown
+// @Security CookieAuth
+// @Param format query string false "html (default), json, csv or md"
+// @Success 200 {file} file "Bookmark export"
+// @Failure 400 {object} pkg.Response "Unknown format"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/bookmarks/export [get]
+func (bh *BookmarkHandler) ExportBookmarks(c *gin.Context) {
+	format, err := exporters.Lookup(c.DefaultQuery("format", "html"))
+	if err != nil {
+		bh.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Bad request " + err.Error(), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	tree, resp := bh.BookmarkUseCase.GetBookmarkTree(c.GetUint("user_id"), domain.BookmarkFilter{})
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	filename := fmt.Sprintf("theca-bookmarks-%s.%s", time.Now().UTC().Format("2006-01-02"), format.Extension)
+	c.Header("Content-Type", format.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+	if err := format.Write(c.Writer, tree, requestOrigin(c)); err != nil {
+		bh.Logger.Error(c, "Export bookmarks: failed to write export", map[string]any{"error": err})
+	}
+}
+
+// requestOrigin is the scheme and host the request was sent to.
+func requestOrigin(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// GetTrash godoc
+// @Summary List the trash
+// @Description Bookmarks deleted by the current user, most recently deleted first. They are removed for good after the retention period.
+// @Tags Bookmark
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {array} domain.Bookmark "Deleted bookmarks"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/bookmarks/trash [get]
+func (bh *BookmarkHandler) GetTrash(c *gin.Context) {
+	bookmarks, resp := bh.BookmarkUseCase.GetTrash(c.GetUint("user_id"))
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
+	c.JSON(resp.Code, bookmarks)
+}
+
+// RestoreBookmark godoc
+// @Summary Restore a bookmark from the trash
+// @Description Move a deleted bookmark back to its folder, or to the root level when the folder was deleted too. Counts towards the bookmark limit again.
+// @Tags Bookmark
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.RestoreBookmarkRequest true "ID of the deleted bookmark"
+// @Success 200 {object} pkg.Response "Bookmark restored"
+// @Failure 400 {object} pkg.Response "Bad request, invalid input"
+// @Failure 403 {object} pkg.Response "Bookmark belongs to another user"
+// @Failure 404 {object} pkg.Response "Bookmark not found in trash"
+// @Failure 409 {object} pkg.Response "Limit of bookmarks of the user's plan, or the URL is saved again"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/bookmarks/restore [post]
+func (bh *BookmarkHandler) RestoreBookmark(c *gin.Context) {
+	var req requests.RestoreBookmarkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bh.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := bh.BookmarkUseCase.RestoreBookmark(c.GetUint("user_id"), req.ID)
+	c.JSON(resp.Code, resp)
+}
+
+// EmptyTrash godoc
+// @Summary Empty the trash
+// @Description Permanently delete every bookmark in the current user's trash.
+// @Tags Bookmark
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} pkg.Response "Trash emptied"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/bookmarks/trash [delete]
+func (bh *BookmarkHandler) EmptyTrash(c *gin.Context) {
+	resp := bh.BookmarkUseCase.EmptyTrash(c.GetUint("user_id"))
+	c.JSON(resp.Code, resp)
+}
+
+// GetDuplicates godoc
+// @Summary List duplicate bookmarks
+// @Description Groups of the current user's bookmarks whose URLs lead to the same page, ignoring the scheme, a leading www., trailing slashes, tracking parameters and the order of query parameters. Bookmarks in each group are oldest first.
+// @Tags Bookmark
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {array} domain.DuplicateGroup "Groups of duplicates"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/bookmarks/duplicates [get]
+func (bh *BookmarkHandler) GetDuplicates(c *gin.Context) {
+	groups, resp := bh.BookmarkUseCase.GetDuplicates(c.GetUint("user_id"))
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
+	c.JSON(resp.Code, groups)
+}
+
+// MergeDuplicates godoc
+// @Summary Merge duplicate bookmarks
+// @Description Keep one bookmark of a group of duplicates and move the others to the trash. The kept bookmark gets their tags.
+// @Tags Bookmark
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.MergeDuplicatesRequest true "Bookmark to keep and its duplicates"
+// @Success 200 {object} pkg.Response "Bookmarks merged"
+// @Failure 400 {object} pkg.Response "Bad request, or the bookmarks are not duplicates"
+// @Failure 404 {object} pkg.Response "Bookmark not found"
+// @Failure 409 {object} pkg.Response "Another bookmark holds the URL"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/bookmarks/duplicates/merge [post]
+func (bh *BookmarkHandler) MergeDuplicates(c *gin.Context) {
+	var req requests.MergeDuplicatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bh.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := bh.BookmarkUseCase.MergeDuplicates(c.GetUint("user_id"), req.KeepID, req.MergeIDs)
+	c.JSON(resp.Code, resp)
+}
+
+// GetLinkHealth godoc
+// @Summary List bookmarks by link health
+// @Description Bookmarks of the current user whose links the periodic link checker found broken or permanently redirected, with counts of every status. Repeat the status parameter to list other statuses: ok, redirected, broken, unknown (the site refuses automated checks) or unchecked.
+// @Tags Bookmark
+// @Produce json
+// @Security CookieAuth
+// @Param status query []string false "Link statuses to list, broken and redirected by default" collectionFormat(multi)
+// @Success 200 {object} pkg.BookmarkHealthResponse "Bookmarks and counts by status"
+// @Failure 400 {object} pkg.BookmarkHealthResponse "Unknown status"
+// @Failure 500 {object} pkg.BookmarkHealthResponse "Internal server error"
+// @Router /api/bookmarks/health [get]
+func (bh *BookmarkHandler) GetLinkHealth(c *gin.Context) {
+	resp := bh.BookmarkUseCase.GetLinkHealth(c.GetUint("user_id"), c.QueryArray("status"))
+	c.JSON(resp.Code, resp)
+}
+
+// ApplyRedirects godoc
+// @Summary Update redirected bookmarks
+// @Description Move bookmarks whose links redirect permanently to the URL they redirect to. Bookmarks that are not redirected, or whose new URL is already saved, are skipped.
+// @Tags Bookmark
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.ApplyRedirectsRequest true "IDs of the bookmarks to update"
+// @Success 200 {object} pkg.Response "Numbers of updated and skipped bookmarks"
+// @Failure 400 {object} pkg.Response "Bad request, invalid input"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/bookmarks/health/apply-redirects [post]
+func (bh *BookmarkHandler) ApplyRedirects(c *gin.Context) {
+	var req requests.ApplyRedirectsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bh.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := bh.BookmarkUseCase.ApplyRedirects(c.GetUint("user_id"), req.IDs)
+	c.JSON(resp.Code, resp)
+}
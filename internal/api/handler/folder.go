@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/usecase"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"github.com/OxytocinGroup/theca-backend/pkg/requests"
+	"github.com/gin-gonic/gin"
+)
+
+type FolderHandler struct {
+	FolderUseCase usecase.FolderUseCase
+	Logger        logger.Logger
+}
+
+func NewFolderHandler(usecase usecase.FolderUseCase, log logger.Logger) *FolderHandler {
+	return &FolderHandler{
+		FolderUseCase: usecase,
+		Logger:        log,
+	}
+}
+
+// CreateFolder godoc
+// @Summary Create a folder
+// @Description Create a new bookmark folder. Set parent_id to nest it inside another folder.
+// @Tags Folder
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body domain.Folder true "Folder details"
+// @Success 201 {object} pkg.Response "Folder created successfully"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 403 {object} pkg.Response "Parent folder belongs to another user"
+// @Failure 404 {object} pkg.Response "Parent folder not found"
+// @Failure 409 {object} pkg.Response "Limit of folders of the user's plan"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/folders/create [post]
+func (fh *FolderHandler) CreateFolder(c *gin.Context) {
+	var folder domain.Folder
+	if err := c.ShouldBindJSON(&folder); err != nil {
+		fh.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	folder.ID = 0
+	folder.UserID = c.GetUint("user_id")
+	resp := fh.FolderUseCase.CreateFolder(folder)
+	c.JSON(resp.Code, resp)
+}
+
+// GetFolders godoc
+// @Summary Get folders
+// @Description Fetch all folders of the current user as a flat list
+// @Tags Folder
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {array} domain.Folder "List of folders"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/folders/get [get]
+func (fh *FolderHandler) GetFolders(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	folders, resp := fh.FolderUseCase.GetFoldersByUser(userID)
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
+	c.JSON(resp.Code, folders)
+}
+
+// UpdateFolder godoc
+// @Summary Update a folder by ID
+// @Description Rename, reorder or move a folder. A folder can't be moved into itself or one of its subfolders.
+// @Tags Folder
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body domain.Folder true "Folder with the ID to update"
+// @Success 200 {object} pkg.Response "Successfully updated the folder"
+// @Failure 400 {object} pkg.Response "Bad request, invalid input or parent folder"
+// @Failure 403 {object} pkg.Response "Forbidden, the folder belongs to another user"
+// @Failure 404 {object} pkg.Response "Folder not found"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/folders/update [post]
+func (fh *FolderHandler) UpdateFolder(c *gin.Context) {
+	var folder domain.Folder
+	if err := c.ShouldBindJSON(&folder); err != nil {
+		fh.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := fh.FolderUseCase.UpdateFolder(c.GetUint("user_id"), &folder)
+	c.JSON(resp.Code, resp)
+}
+
+// DeleteFolder godoc
+// @Summary Delete a folder by ID
+// @Description Delete a folder. With cascade the nested folders and their bookmarks are deleted too, otherwise the contents are moved to the parent folder.
+// @Tags Folder
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.DeleteFolderRequest true "Folder ID and delete mode"
+// @Success 200 {object} pkg.Response "Successfully deleted the folder"
+// @Failure 400 {object} pkg.Response "Bad request, invalid input"
+// @Failure 403 {object} pkg.Response "Forbidden, the folder belongs to another user"
+// @Failure 404 {object} pkg.Response "Folder not found"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/folders/delete [delete]
+func (fh *FolderHandler) DeleteFolder(c *gin.Context) {
+	var req requests.DeleteFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fh.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := fh.FolderUseCase.DeleteFolder(c.GetUint("user_id"), req.ID, req.Cascade)
+	c.JSON(resp.Code, resp)
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/OxytocinGroup/theca-backend/internal/usecase"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type IconHandler struct {
+	IconUseCase usecase.IconUseCase
+	Logger      logger.Logger
+}
+
+func NewIconHandler(usecase usecase.IconUseCase, log logger.Logger) *IconHandler {
+	return &IconHandler{
+		IconUseCase: usecase,
+		Logger:      log,
+	}
+}
+
+// GetIcon godoc
+// @Summary Get an icon
+// @Description Serve a stored favicon as a square PNG of the requested size. SVG icons, including the letter avatars generated for sites without a favicon, are also available as SVG; those that could not be rasterized are always served as sanitized SVG. Icons are addressed by the SHA-256 of their content and never change, so they may be cached forever.
+// @Tags Icon
+// @Produce image/png,image/svg+xml
+// @Param hash path string true "SHA-256 of the icon, as found in a bookmark's icon_hash"
+// @Param size query int false "Edge in pixels of the PNG: 32, 64 or 128" default(64)
+// @Param format query string false "png or svg" default(png)
+// @Success 200 {file} file "Icon image"
+// @Success 304 "Icon not modified"
+// @Failure 400 {object} pkg.Response "Unsupported size or format"
+// @Failure 404 {object} pkg.Response "Icon not found, or it has no SVG version"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /icons/{hash} [get]
+func (ih *IconHandler) GetIcon(c *gin.Context) {
+	hash := c.Param("hash")
+	size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(usecase.DefaultIconSize)))
+	if err != nil {
+		ih.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Bad request " + err.Error(), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	format := c.DefaultQuery("format", usecase.IconFormatPNG)
+
+	etag := fmt.Sprintf(`"%s-%d-%s"`, hash, size, format)
+	cacheHeaders := map[string]string{
+		"Cache-Control": "public, max-age=31536000, immutable",
+		"ETag":          etag,
+	}
+
+	if c.GetHeader("If-None-Match") == etag {
+		for key, value := range cacheHeaders {
+			c.Header(key, value)
+		}
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	icon, resp := ih.IconUseCase.GetIcon(hash, size, format)
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
+	defer icon.Body.Close()
+
+	// Icons come from arbitrary sites; an SVG must not run scripts in
+	// Theca's origin when opened directly.
+	cacheHeaders["Content-Security-Policy"] = "default-src 'none'; style-src 'unsafe-inline'; sandbox"
+	cacheHeaders["X-Content-Type-Options"] = "nosniff"
+	c.DataFromReader(http.StatusOK, icon.Size, icon.ContentType, icon.Body, cacheHeaders)
+}
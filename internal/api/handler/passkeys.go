@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/requests"
+	"github.com/gin-gonic/gin"
+)
+
+// BeginPasskeyLogin godoc
+// @Summary Start a passkey login
+// @Description Returns the options for navigator.credentials.get() to log in with any passkey of the site, without a username or password. The authenticator must verify the user. The credential is sent to /user/login/passkey/finish with the ceremony within 5 minutes.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body requests.BeginPasskeyLoginRequest false "Remember me"
+// @Success 200 {object} pkg.PasskeyOptionsResponse "Options for the browser"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 500 {object} pkg.PasskeyOptionsResponse "Internal server error"
+// @Router /user/login/passkey/begin [post]
+func (uh *UserHandler) BeginPasskeyLogin(c *gin.Context) {
+	var req requests.BeginPasskeyLoginRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			uh.Logger.Info(context.Background(), "Begin passkey login: bad request", map[string]any{"error": err})
+			c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Invalid request body", Error: cerr.ErrInvalidBody})
+			return
+		}
+	}
+
+	resp := uh.PasskeyUseCase.BeginLogin(req.RememberMe)
+	c.JSON(resp.Code, resp)
+}
+
+// FinishPasskeyLogin godoc
+// @Summary Log in with a passkey
+// @Description Completes a passkey login with the credential returned by the browser, opening a session for the passkey's user.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body requests.FinishPasskeyRequest true "Ceremony and credential"
+// @Success 200 {object} pkg.LoginResponse "Login successful"
+// @Failure 400 {object} pkg.Response "Bad request, or the ceremony expired"
+// @Failure 401 {object} pkg.Response "Invalid passkey"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /user/login/passkey/finish [post]
+func (uh *UserHandler) FinishPasskeyLogin(c *gin.Context) {
+	var req requests.FinishPasskeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		uh.Logger.Info(context.Background(), "Finish passkey login: bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Invalid request body", Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	user, remember, resp := uh.PasskeyUseCase.FinishLogin(req.Ceremony, req.Credential)
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	if err := uh.startSession(c, user.ID, remember); err != nil {
+		uh.Logger.Error(context.Background(), "Finish passkey login: failed to create session", map[string]any{"user_id": user.ID, "error": err})
+		c.JSON(http.StatusInternalServerError, pkg.Response{Code: http.StatusInternalServerError, Message: "Failed to create session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pkg.LoginResponse{
+		Code:     http.StatusOK,
+		Message:  "Login successful",
+		Username: user.Username,
+	})
+}
+
+// ListPasskeys godoc
+// @Summary List passkeys
+// @Description The passkeys of the current user, oldest first.
+// @Tags User
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {array} domain.Passkey "Passkeys"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/user/passkeys [get]
+func (uh *UserHandler) ListPasskeys(c *gin.Context) {
+	passkeys, resp := uh.PasskeyUseCase.ListPasskeys(c.GetUint("user_id"))
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
+	c.JSON(resp.Code, passkeys)
+}
+
+// BeginPasskeyRegistration godoc
+// @Summary Start adding a passkey
+// @Description Returns the options for navigator.credentials.create() to add a passkey with the name to the current user's account. Without a name it is called "Passkey N". The credential is sent to /api/user/passkeys/register/finish with the ceremony within 5 minutes.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.BeginPasskeyRegistrationRequest false "Name of the passkey"
+// @Success 200 {object} pkg.PasskeyOptionsResponse "Options for the browser"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 409 {object} pkg.PasskeyOptionsResponse "Limit of passkeys reached"
+// @Failure 500 {object} pkg.PasskeyOptionsResponse "Internal server error"
+// @Router /api/user/passkeys/register/begin [post]
+func (uh *UserHandler) BeginPasskeyRegistration(c *gin.Context) {
+	var req requests.BeginPasskeyRegistrationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			uh.Logger.Info(context.Background(), "Begin passkey registration: bad request", map[string]any{"error": err})
+			c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Invalid request body", Error: cerr.ErrInvalidBody})
+			return
+		}
+	}
+
+	resp := uh.PasskeyUseCase.BeginRegistration(c.GetUint("user_id"), req.Name)
+	c.JSON(resp.Code, resp)
+}
+
+// FinishPasskeyRegistration godoc
+// @Summary Add a passkey
+// @Description Stores the passkey created by the browser for the current user. It can then be used to log in, alone or as a second factor.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.FinishPasskeyRequest true "Ceremony and credential"
+// @Success 201 {object} domain.Passkey "Passkey added"
+// @Failure 400 {object} pkg.Response "Bad request, invalid passkey, or the ceremony expired"
+// @Failure 409 {object} pkg.Response "The passkey is already registered"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/user/passkeys/register/finish [post]
+func (uh *UserHandler) FinishPasskeyRegistration(c *gin.Context) {
+	var req requests.FinishPasskeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		uh.Logger.Info(context.Background(), "Finish passkey registration: bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Invalid request body", Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	passkey, resp := uh.PasskeyUseCase.FinishRegistration(c.GetUint("user_id"), req.Ceremony, req.Credential)
+	if resp.Code != http.StatusCreated {
+		c.JSON(resp.Code, resp)
+		return
+	}
+	c.JSON(resp.Code, passkey)
+}
+
+// RenamePasskey godoc
+// @Summary Rename a passkey
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param id path int true "Passkey ID"
+// @Param request body requests.RenamePasskeyRequest true "New name"
+// @Success 200 {object} pkg.Response "Passkey renamed"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 404 {object} pkg.Response "Passkey not found"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/user/passkeys/{id} [put]
+func (uh *UserHandler) RenamePasskey(c *gin.Context) {
+	passkeyID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	var req requests.RenamePasskeyRequest
+	if err == nil {
+		err = c.ShouldBindJSON(&req)
+	}
+	if err != nil {
+		uh.Logger.Info(context.Background(), "Rename passkey: bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Invalid request body", Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := uh.PasskeyUseCase.RenamePasskey(c.GetUint("user_id"), uint(passkeyID), req.Name)
+	c.JSON(resp.Code, resp)
+}
+
+// DeletePasskey godoc
+// @Summary Remove a passkey
+// @Description The passkey can no longer be used to log in.
+// @Tags User
+// @Produce json
+// @Security CookieAuth
+// @Param id path int true "Passkey ID"
+// @Success 200 {object} pkg.Response "Passkey removed"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 404 {object} pkg.Response "Passkey not found"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/user/passkeys/{id} [delete]
+func (uh *UserHandler) DeletePasskey(c *gin.Context) {
+	passkeyID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		uh.Logger.Info(context.Background(), "Delete passkey: bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Invalid passkey ID", Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := uh.PasskeyUseCase.DeletePasskey(c.GetUint("user_id"), uint(passkeyID))
+	c.JSON(resp.Code, resp)
+}
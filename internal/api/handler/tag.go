@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/OxytocinGroup/theca-backend/internal/usecase"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"github.com/OxytocinGroup/theca-backend/pkg/requests"
+	"github.com/gin-gonic/gin"
+)
+
+type TagHandler struct {
+	TagUseCase usecase.TagUseCase
+	Logger     logger.Logger
+}
+
+func NewTagHandler(usecase usecase.TagUseCase, log logger.Logger) *TagHandler {
+	return &TagHandler{
+		TagUseCase: usecase,
+		Logger:     log,
+	}
+}
+
+// CreateTag godoc
+// @Summary Create a tag
+// @Description Create a new tag for the current user
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.TagRequest true "Tag name"
+// @Success 201 {object} pkg.Response "Tag created successfully"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 409 {object} pkg.Response "Tag with this name already exists, or limit of tags of the user's plan"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/tags/create [post]
+func (th *TagHandler) CreateTag(c *gin.Context) {
+	var req requests.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		th.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := th.TagUseCase.CreateTag(c.GetUint("user_id"), req.Name)
+	c.JSON(resp.Code, resp)
+}
+
+// GetTags godoc
+// @Summary Get tags
+// @Description Fetch all tags of the current user with the number of bookmarks using each tag
+// @Tags Tag
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {array} domain.Tag "List of tags"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/tags/get [get]
+func (th *TagHandler) GetTags(c *gin.Context) {
+	tags, resp := th.TagUseCase.GetTagsByUser(c.GetUint("user_id"))
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
+	c.JSON(resp.Code, tags)
+}
+
+// RenameTag godoc
+// @Summary Rename a tag
+// @Description Rename a tag of the current user. Use merge when a tag with the new name already exists.
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.TagRequest true "Tag ID and new name"
+// @Success 200 {object} pkg.Response "Tag renamed"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 403 {object} pkg.Response "Tag belongs to another user"
+// @Failure 404 {object} pkg.Response "Tag not found"
+// @Failure 409 {object} pkg.Response "Tag with this name already exists"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/tags/rename [post]
+func (th *TagHandler) RenameTag(c *gin.Context) {
+	var req requests.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		th.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := th.TagUseCase.RenameTag(c.GetUint("user_id"), req.ID, req.Name)
+	c.JSON(resp.Code, resp)
+}
+
+// MergeTags godoc
+// @Summary Merge tags
+// @Description Move all bookmarks of the source tags to the target tag and delete the source tags
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.MergeTagsRequest true "Source tag IDs and target tag ID"
+// @Success 200 {object} pkg.Response "Tags merged"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 403 {object} pkg.Response "Tag belongs to another user"
+// @Failure 404 {object} pkg.Response "Tag not found"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/tags/merge [post]
+func (th *TagHandler) MergeTags(c *gin.Context) {
+	var req requests.MergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		th.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := th.TagUseCase.MergeTags(c.GetUint("user_id"), req.SourceIDs, req.TargetID)
+	c.JSON(resp.Code, resp)
+}
+
+// DeleteTag godoc
+// @Summary Delete a tag
+// @Description Delete a tag and detach it from all bookmarks
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.DeleteTagRequest true "Tag ID"
+// @Success 200 {object} pkg.Response "Tag deleted"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 403 {object} pkg.Response "Tag belongs to another user"
+// @Failure 404 {object} pkg.Response "Tag not found"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/tags/delete [delete]
+func (th *TagHandler) DeleteTag(c *gin.Context) {
+	var req requests.DeleteTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		th.Logger.Info(c, "bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: ("Bad request " + err.Error()), Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := th.TagUseCase.DeleteTag(c.GetUint("user_id"), req.ID)
+	c.JSON(resp.Code, resp)
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/requests"
+	"github.com/gin-gonic/gin"
+)
+
+// LoginTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Completes a login that returned a challenge with, when "totp" is among the challenge's methods, a code of the user's authenticator app or one of their recovery codes or, when "passkey" is among the challenge's methods, the credential answering the options of /user/login/2fa/passkey. It opens a session. A challenge is valid for 5 minutes and 5 attempts.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body requests.TwoFactorLoginRequest true "Challenge and code or passkey credential"
+// @Success 200 {object} pkg.LoginResponse "Login successful"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 401 {object} pkg.Response "Invalid code or passkey, or the challenge expired"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /user/login/2fa [post]
+func (uh *UserHandler) LoginTwoFactor(c *gin.Context) {
+	var req requests.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "" && len(req.Credential) == 0) {
+		uh.Logger.Info(context.Background(), "Login 2FA: bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Invalid request body", Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	user, remember, resp := uh.TwoFactorUseCase.VerifyChallenge(req.Challenge, req.Code, req.Credential)
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	if err := uh.startSession(c, user.ID, remember); err != nil {
+		uh.Logger.Error(context.Background(), "Login 2FA: failed to create session", map[string]any{"user_id": user.ID, "error": err})
+		c.JSON(http.StatusInternalServerError, pkg.Response{Code: http.StatusInternalServerError, Message: "Failed to create session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pkg.LoginResponse{
+		Code:     http.StatusOK,
+		Message:  "Login successful",
+		Username: user.Username,
+	})
+}
+
+// BeginTwoFactorPasskey godoc
+// @Summary Answer a two-factor login with a passkey
+// @Description Returns the options for navigator.credentials.get() asking for one of the passkeys of the user logging in. The credential is then sent to /user/login/2fa with the challenge.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body requests.TwoFactorPasskeyRequest true "Challenge"
+// @Success 200 {object} pkg.PasskeyOptionsResponse "Options for the browser"
+// @Failure 400 {object} pkg.PasskeyOptionsResponse "Bad request, or the user has no passkeys"
+// @Failure 401 {object} pkg.PasskeyOptionsResponse "The challenge expired"
+// @Failure 500 {object} pkg.PasskeyOptionsResponse "Internal server error"
+// @Router /user/login/2fa/passkey [post]
+func (uh *UserHandler) BeginTwoFactorPasskey(c *gin.Context) {
+	var req requests.TwoFactorPasskeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		uh.Logger.Info(context.Background(), "Login 2FA passkey: bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Invalid request body", Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := uh.TwoFactorUseCase.BeginPasskeyChallenge(req.Challenge)
+	c.JSON(resp.Code, resp)
+}
+
+// EnrollTOTP godoc
+// @Summary Start enrolling an authenticator app
+// @Description Generates a TOTP secret for the current user, as an otpauth:// URI and a QR code PNG (base64). Two-factor authentication is enabled once a code from the app is confirmed; enrolling again replaces an unconfirmed secret.
+// @Tags User
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} pkg.TOTPEnrollmentResponse "Secret to add to the app"
+// @Failure 409 {object} pkg.TOTPEnrollmentResponse "Two-factor authentication is already enabled"
+// @Failure 500 {object} pkg.TOTPEnrollmentResponse "Internal server error"
+// @Router /api/user/2fa/totp [post]
+func (uh *UserHandler) EnrollTOTP(c *gin.Context) {
+	resp := uh.TwoFactorUseCase.EnrollTOTP(c.GetUint("user_id"))
+	c.JSON(resp.Code, resp)
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm an authenticator app
+// @Description Enables two-factor authentication with a first code from the enrolled app. The response holds recovery codes, each usable once in place of a code; they are not shown again.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.ConfirmTOTPRequest true "Code from the app"
+// @Success 200 {object} pkg.RecoveryCodesResponse "Two-factor authentication enabled"
+// @Failure 400 {object} pkg.RecoveryCodesResponse "Bad request, or no app being enrolled"
+// @Failure 401 {object} pkg.RecoveryCodesResponse "Invalid code"
+// @Failure 409 {object} pkg.RecoveryCodesResponse "Two-factor authentication is already enabled"
+// @Failure 500 {object} pkg.RecoveryCodesResponse "Internal server error"
+// @Router /api/user/2fa/totp/confirm [post]
+func (uh *UserHandler) ConfirmTOTP(c *gin.Context) {
+	var req requests.ConfirmTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		uh.Logger.Info(context.Background(), "Confirm TOTP: bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Invalid request body", Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := uh.TwoFactorUseCase.ConfirmTOTP(c.GetUint("user_id"), req.Code)
+	c.JSON(resp.Code, resp)
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Turns two-factor authentication off for the current user after checking their password. The authenticator app's secret and the recovery codes stop working.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.DisableTwoFactorRequest true "Current password"
+// @Success 200 {object} pkg.Response "Two-factor authentication disabled"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 401 {object} pkg.Response "Wrong password"
+// @Failure 409 {object} pkg.Response "Two-factor authentication is not enabled"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/user/2fa/disable [post]
+func (uh *UserHandler) DisableTwoFactor(c *gin.Context) {
+	var req requests.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		uh.Logger.Info(context.Background(), "Disable 2FA: bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Invalid request body", Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	resp := uh.TwoFactorUseCase.DisableTOTP(c.GetUint("user_id"), req.Password)
+	c.JSON(resp.Code, resp)
+}
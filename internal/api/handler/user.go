@@ -3,28 +3,31 @@ package handler
 import (
 	"context"
 	"net/http"
-	"time"
 
+	"github.com/OxytocinGroup/theca-backend/internal/api/middleware"
 	"github.com/OxytocinGroup/theca-backend/internal/usecase"
 	"github.com/OxytocinGroup/theca-backend/pkg"
 	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
 	"github.com/OxytocinGroup/theca-backend/pkg/logger"
 	"github.com/OxytocinGroup/theca-backend/pkg/requests"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 type UserHandler struct {
-	UserUseCase    usecase.UserUseCase
-	SessionUseCase usecase.SessionUseCase
-	Logger         logger.Logger
+	UserUseCase      usecase.UserUseCase
+	SessionUseCase   usecase.SessionUseCase
+	TwoFactorUseCase usecase.TwoFactorUseCase
+	PasskeyUseCase   usecase.PasskeyUseCase
+	Logger           logger.Logger
 }
 
-func NewUserHandler(usecase usecase.UserUseCase, sessionUseCase usecase.SessionUseCase, log logger.Logger) *UserHandler {
+func NewUserHandler(usecase usecase.UserUseCase, sessionUseCase usecase.SessionUseCase, twoFactorUseCase usecase.TwoFactorUseCase, passkeyUseCase usecase.PasskeyUseCase, log logger.Logger) *UserHandler {
 	return &UserHandler{
-		UserUseCase:    usecase,
-		SessionUseCase: sessionUseCase,
-		Logger:         log,
+		UserUseCase:      usecase,
+		SessionUseCase:   sessionUseCase,
+		TwoFactorUseCase: twoFactorUseCase,
+		PasskeyUseCase:   passkeyUseCase,
+		Logger:           log,
 	}
 }
 
@@ -85,23 +88,24 @@ func (uh *UserHandler) VerifyEmail(c *gin.Context) {
 
 // @Login GoDoc
 // @Summary User login
-// @Description This endpoint allows a user to log in using their username and password. If already logged in, a conflict response is returned.
+// @Description This endpoint allows a user to log in using their username and password. If already logged in, a conflict response is returned. With remember_me the session lasts for weeks of inactivity instead of hours. Users with two-factor authentication or a passkey get 202 and a challenge to complete the login with at /user/login/2fa.
 // @Tags User
 // @Accept  json
 // @Produce  json
 // @Param request body requests.LoginRequest true "Username and password"
 // @Success 200 {object} pkg.LoginResponse "Login successful"
+// @Success 202 {object} pkg.LoginResponse "Password accepted, a second factor is required"
 // @Failure 400 {object} pkg.Response "Bad request - Invalid input"
 // @Failure 401 {object} pkg.Response "Unauthorized - Invalid username or password or not verified"
 // @Failure 409 {object} pkg.Response "Conflict - User already logged in"
 // @Failure 500 {object} pkg.Response "Internal server error"
 // @Router /user/login [post]
 func (uh *UserHandler) Login(c *gin.Context) {
-	session, err := c.Cookie("session_id")
+	sessionToken, err := c.Cookie(middleware.SessionCookie)
 	if err == nil {
-		userID, err := uh.SessionUseCase.ValidateSession(session)
+		session, err := uh.SessionUseCase.ValidateSession(sessionToken)
 		if err == nil {
-			uh.Logger.Info(context.Background(), "Login: user tryed to login when already logged", map[string]any{"user_id": userID})
+			uh.Logger.Info(context.Background(), "Login: user tryed to login when already logged", map[string]any{"user_id": session.UserID})
 			c.JSON(http.StatusConflict, pkg.Response{
 				Code:    http.StatusConflict,
 				Message: "User already logged in",
@@ -139,8 +143,23 @@ func (uh *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	sessionID := uuid.New().String()
-	if err := uh.SessionUseCase.CreateSession(sessionID, user.ID, time.Now().Add(90*24*time.Hour)); err != nil {
+	challenge, methods, resp := uh.TwoFactorUseCase.CreateChallenge(user.ID, req.RememberMe)
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
+	if challenge != "" {
+		c.JSON(http.StatusAccepted, pkg.LoginResponse{
+			Code:      http.StatusAccepted,
+			Message:   "Two-factor authentication required",
+			Username:  user.Username,
+			Challenge: challenge,
+			Methods:   methods,
+		})
+		return
+	}
+
+	if err := uh.startSession(c, user.ID, req.RememberMe); err != nil {
 		uh.Logger.Error(context.Background(), "Login: failed to create session", map[string]any{
 			"error": err,
 		})
@@ -151,9 +170,6 @@ func (uh *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	c.SetSameSite(http.SameSiteStrictMode)
-	c.SetCookie("session_id", sessionID, 3600*24*90, "/", "", true, true)
-
 	c.JSON(http.StatusOK, pkg.LoginResponse{
 		Code:     http.StatusOK,
 		Message:  "Login successful",
@@ -163,17 +179,24 @@ func (uh *UserHandler) Login(c *gin.Context) {
 
 // @Logout GoDoc
 // @Summary User logout
-// @Description This endpoint allows a user to log out by deleting all active sessions associated with the user.
+// @Description This endpoint allows a user to log out by ending the current session. With all=true every session of the user is ended, logging them out on all devices.
 // @Tags User
 // @Accept  json
 // @Produce  json
+// @Param all query bool false "End all sessions of the user, not only the current one"
 // @Success 200 {object} pkg.Response "Logout successful"
 // @Failure 500 {object} pkg.Response "Internal server error"
 // @Router /api/user/logout [delete]
 func (uh *UserHandler) Logout(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
-	if err := uh.SessionUseCase.DeleteAllSessions(userID); err != nil {
+	var err error
+	if c.Query("all") == "true" {
+		err = uh.SessionUseCase.DeleteAllSessions(userID)
+	} else {
+		err = uh.SessionUseCase.DeleteSession(c.GetString("session_id"))
+	}
+	if err != nil {
 		uh.Logger.Error(context.Background(), "Logut: failed to delete sessions", map[string]any{
 			"user_id": userID,
 			"error":   err,
@@ -185,34 +208,93 @@ func (uh *UserHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	c.SetCookie("session_id", "", -1, "/", "", false, true)
+	middleware.ClearSessionCookie(c)
 	c.JSON(http.StatusOK, pkg.Response{
 		Code:    http.StatusOK,
 		Message: "Logout successful",
 	})
 }
 
-// func (uh *UserHandler) ChangePass(c *gin.Context) {
-// 	userID, exists := c.Get("user_id")
-// 	if !exists {
-// 		c.JSON(http.StatusUnauthorized, pkg.Response{
-// 			Code: http.StatusUnauthorized,
-// 		})
-// 		return
-// 	}
+// ListSessions godoc
+// @Summary List active sessions
+// @Description The sessions the current user is logged in with, one per device or browser, most recently used first. The session of the request is marked as current.
+// @Tags User
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {array} domain.Session "Active sessions"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/user/sessions [get]
+func (uh *UserHandler) ListSessions(c *gin.Context) {
+	sessions, resp := uh.SessionUseCase.ListSessions(c.GetUint("user_id"), c.GetString("session_id"))
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
+	c.JSON(resp.Code, sessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Log the current user out on one device by ending the session with the given id.
+// @Tags User
+// @Produce json
+// @Security CookieAuth
+// @Param id path string true "Session id, as listed"
+// @Success 200 {object} pkg.Response "Session revoked"
+// @Failure 404 {object} pkg.Response "Session not found"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/user/sessions/{id} [delete]
+func (uh *UserHandler) RevokeSession(c *gin.Context) {
+	resp := uh.SessionUseCase.RevokeSession(c.GetUint("user_id"), c.Param("id"))
+	c.JSON(resp.Code, resp)
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Change the current user's password after checking the current one. Every session of the user is ended and the device making the request gets a new one.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body requests.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} pkg.Response "Password changed"
+// @Failure 400 {object} pkg.Response "Bad request - Invalid input"
+// @Failure 401 {object} pkg.Response "Current password is wrong"
+// @Failure 500 {object} pkg.Response "Internal server error"
+// @Router /api/user/change-password [post]
+func (uh *UserHandler) ChangePassword(c *gin.Context) {
+	var req requests.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		uh.Logger.Info(context.Background(), "Change password: bad request", map[string]any{"error": err})
+		c.JSON(http.StatusBadRequest, pkg.Response{Code: http.StatusBadRequest, Message: "Invalid request body", Error: cerr.ErrInvalidBody})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	resp := uh.UserUseCase.ChangePass(userID, req.CurrentPassword, req.Password)
+	if resp.Code != http.StatusOK {
+		c.JSON(resp.Code, resp)
+		return
+	}
 
-// 	var req requests.ChangePasswordRequest
-// 	if err := c.ShouldBindJSON(&req); err != nil {
-// 		c.JSON(http.StatusBadRequest, pkg.Response{
-// 			Code:    http.StatusBadRequest,
-// 			Message: "Invalid request body",
-// 		})
-// 		return
-// 	}
+	if err := uh.startSession(c, userID, c.GetBool("remember_me")); err != nil {
+		// The password is changed; the user logs in again with it.
+		uh.Logger.Error(context.Background(), "Change password: failed to create session", map[string]any{"user_id": userID, "error": err})
+		middleware.ClearSessionCookie(c)
+	}
+	c.JSON(resp.Code, resp)
+}
 
-// 	resp := uh.UserUseCase.ChangePass(fmt.Sprint(userID), req.Password)
-// 	c.JSON(resp.Code, resp)
-// }
+// startSession opens a session for the user on the requesting device and
+// sets its cookie. The token is always new, never one sent by the client.
+func (uh *UserHandler) startSession(c *gin.Context, userID uint, remember bool) error {
+	sessionToken, expiresAt, err := uh.SessionUseCase.CreateSession(userID, remember, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return err
+	}
+	middleware.SetSessionCookie(c, sessionToken, expiresAt)
+	return nil
+}
 
 // @RequestPasswordReset godoc
 // @Summary Request a password reset
@@ -289,13 +371,14 @@ func (uh *UserHandler) RequestVerificationToken(c *gin.Context) {
 
 // GetUserInfo godoc
 // @Summary Give user info
-// @Description Gives info about the user by finding him by session
+// @Description Gives info about the user by finding him by session, with the plan and how much of its limits is used (-1 means unlimited)
 // @Tags User
 // @Produce json
 // @Success 200 {object} pkg.UserInfoResponse "User inforamiton got successfully"
 // @Failure 401 {object} pkg.UserInfoResponse "Session isn't valid"
 // @Failure 403 {object} pkg.UserInfoResponse "Session cookie didn't found"
 // @Failure 404 {object} pkg.UserInfoResponse "User not found"
+// @Failure 500 {object} pkg.UserInfoResponse "Internal server error"
 // @Router /api/user/get-info [post]
 func (uh *UserHandler) GetUserInfo(c *gin.Context) {
 	userID := c.GetUint("user_id")
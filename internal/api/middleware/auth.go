@@ -1,30 +1,64 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/OxytocinGroup/theca-backend/internal/usecase"
 	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/logger"
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(sessionUC usecase.SessionUseCase) gin.HandlerFunc {
+// SessionCookie holds the session token.
+const SessionCookie = "session_id"
+
+// SetSessionCookie stores the session token in the browser until the
+// session expires. It is set again whenever the expiry moves, so the
+// browser drops the cookie when the server ends the session.
+func SetSessionCookie(c *gin.Context, sessionToken string, expiresAt time.Time) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(SessionCookie, sessionToken, int(time.Until(expiresAt).Seconds()), "/", "", true, true)
+}
+
+func ClearSessionCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(SessionCookie, "", -1, "/", "", true, true)
+}
+
+// AuthMiddleware sets user_id, session_id, the ID of the session, and
+// remember_me, whether it follows the long timeouts, for the handlers.
+// Sessions are extended and rotated here, updating the response's cookie.
+func AuthMiddleware(sessionUC usecase.SessionUseCase, log logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		sessionID, err := c.Cookie("session_id")
+		sessionToken, err := c.Cookie(SessionCookie)
 		if err != nil {
 			c.JSON(http.StatusForbidden, gin.H{"error": cerr.ErrMissingCookie})
 			c.Abort()
 			return
 		}
 
-		userID, err := sessionUC.ValidateSession(sessionID)
+		session, err := sessionUC.ValidateSession(sessionToken)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": cerr.ErrInvalidSession, "details": err.Error()})
 			c.Abort()
 			return
 		}
 
-		c.Set("user_id", userID)
+		// The session is still valid as it was, so a failed refresh is
+		// retried on the next request.
+		cookieToken, err := sessionUC.RefreshSession(&session, sessionToken)
+		if err != nil {
+			log.Error(context.Background(), "Auth: failed to refresh session", map[string]any{"user_id": session.UserID, "error": err})
+		}
+		if cookieToken != "" {
+			SetSessionCookie(c, cookieToken, session.ExpiresAt)
+		}
+
+		c.Set("user_id", session.UserID)
+		c.Set("session_id", session.ID)
+		c.Set("remember_me", session.Remember)
 		c.Next()
 	}
 }
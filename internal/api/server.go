@@ -1,6 +1,12 @@
 package http
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -13,11 +19,16 @@ import (
 	"github.com/OxytocinGroup/theca-backend/internal/api/middleware"
 )
 
+// shutdownTimeout bounds how long the server waits for requests and
+// background work to finish when stopping.
+const shutdownTimeout = 30 * time.Second
+
 type ServerHTTP struct {
-	engine *gin.Engine
+	engine     *gin.Engine
+	onShutdown []func(context.Context) error
 }
 
-func NewServerHTTP(userHandler *handler.UserHandler, bookmarkHandler *handler.BookmarkHandler) *ServerHTTP {
+func NewServerHTTP(userHandler *handler.UserHandler, bookmarkHandler *handler.BookmarkHandler, folderHandler *handler.FolderHandler, tagHandler *handler.TagHandler, iconHandler *handler.IconHandler) *ServerHTTP {
 	engine := gin.New()
 
 	engine.Use(gin.Logger())
@@ -38,23 +49,95 @@ func NewServerHTTP(userHandler *handler.UserHandler, bookmarkHandler *handler.Bo
 	engine.POST("/user/verify-email", userHandler.VerifyEmail)
 	engine.POST("/user/verify-email/request", userHandler.RequestVerificationToken)
 	engine.POST("/user/login", userHandler.Login)
+	engine.POST("/user/login/2fa", userHandler.LoginTwoFactor)
+	engine.POST("/user/login/2fa/passkey", userHandler.BeginTwoFactorPasskey)
+	engine.POST("/user/login/passkey/begin", userHandler.BeginPasskeyLogin)
+	engine.POST("/user/login/passkey/finish", userHandler.FinishPasskeyLogin)
 	engine.POST("/user/password-reset/request", userHandler.RequestPasswordReset)
 	engine.POST("/user/password-reset/reset", userHandler.ResetPassword)
 
+	// Icons are public so <img> tags can load them without credentials.
+	engine.GET("/icons/:hash", iconHandler.GetIcon)
+
 	// Auth middleware
-	api := engine.Group("/api", middleware.AuthMiddleware(userHandler.SessionUseCase))
-	// api.POST("/change-pass", userHandler.ChangePass)
+	api := engine.Group("/api", middleware.AuthMiddleware(userHandler.SessionUseCase, userHandler.Logger))
 
 	api.DELETE("/user/logout", userHandler.Logout)
 	api.GET("/user/get-info", userHandler.GetUserInfo)
+	api.POST("/user/change-password", userHandler.ChangePassword)
+	api.POST("/user/2fa/totp", userHandler.EnrollTOTP)
+	api.POST("/user/2fa/totp/confirm", userHandler.ConfirmTOTP)
+	api.POST("/user/2fa/disable", userHandler.DisableTwoFactor)
+	api.GET("/user/passkeys", userHandler.ListPasskeys)
+	api.POST("/user/passkeys/register/begin", userHandler.BeginPasskeyRegistration)
+	api.POST("/user/passkeys/register/finish", userHandler.FinishPasskeyRegistration)
+	api.PUT("/user/passkeys/:id", userHandler.RenamePasskey)
+	api.DELETE("/user/passkeys/:id", userHandler.DeletePasskey)
+	api.GET("/user/sessions", userHandler.ListSessions)
+	api.DELETE("/user/sessions/:id", userHandler.RevokeSession)
 	api.POST("/bookmarks/create", bookmarkHandler.CreateBookmark)
+	api.POST("/bookmarks/preview", bookmarkHandler.PreviewBookmark)
 	api.GET("/bookmarks/get", bookmarkHandler.GetBookmarks)
 	api.DELETE("/bookmarks/delete", bookmarkHandler.DeleteBookmark)
 	api.POST("/bookmarks/update", bookmarkHandler.UpdateBookmark)
+	api.POST("/bookmarks/reorder", bookmarkHandler.ReorderBookmark)
+	api.GET("/bookmarks/search", bookmarkHandler.SearchBookmarks)
+	api.POST("/bookmarks/import", bookmarkHandler.ImportBookmarks)
+	api.GET("/bookmarks/export", bookmarkHandler.ExportBookmarks)
+	api.GET("/bookmarks/trash", bookmarkHandler.GetTrash)
+	api.DELETE("/bookmarks/trash", bookmarkHandler.EmptyTrash)
+	api.POST("/bookmarks/restore", bookmarkHandler.RestoreBookmark)
+	api.GET("/bookmarks/duplicates", bookmarkHandler.GetDuplicates)
+	api.POST("/bookmarks/duplicates/merge", bookmarkHandler.MergeDuplicates)
+	api.GET("/bookmarks/health", bookmarkHandler.GetLinkHealth)
+	api.POST("/bookmarks/health/apply-redirects", bookmarkHandler.ApplyRedirects)
+	api.POST("/folders/create", folderHandler.CreateFolder)
+	api.GET("/folders/get", folderHandler.GetFolders)
+	api.POST("/folders/update", folderHandler.UpdateFolder)
+	api.DELETE("/folders/delete", folderHandler.DeleteFolder)
+	api.POST("/tags/create", tagHandler.CreateTag)
+	api.GET("/tags/get", tagHandler.GetTags)
+	api.POST("/tags/rename", tagHandler.RenameTag)
+	api.POST("/tags/merge", tagHandler.MergeTags)
+	api.DELETE("/tags/delete", tagHandler.DeleteTag)
 	// api.GET("/user/verification-status", userHandler.CheckVerificationStatus)
 	return &ServerHTTP{engine: engine}
 }
 
+// OnShutdown registers fn to run after the server stopped serving
+// requests, such as draining background workers.
+func (sh *ServerHTTP) OnShutdown(fn func(context.Context) error) {
+	sh.onShutdown = append(sh.onShutdown, fn)
+}
+
+// Start serves requests until SIGINT or SIGTERM, then lets running
+// requests and the shutdown hooks finish within shutdownTimeout.
 func (sh *ServerHTTP) Start() {
-	sh.engine.Run(":3000")
+	server := &http.Server{Addr: ":3000", Handler: sh.engine}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		log.Printf("server stopped: %v", err)
+	case <-ctx.Done():
+		log.Print("shutting down")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown: %v", err)
+	}
+	for _, fn := range sh.onShutdown {
+		if err := fn(shutdownCtx); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}
 }
@@ -2,7 +2,9 @@ package config
 
 import (
 	"log"
+	"time"
 
+	"github.com/OxytocinGroup/theca-backend/pkg/urlnorm"
 	"github.com/go-playground/validator/v10"
 	"github.com/spf13/viper"
 )
@@ -23,10 +25,84 @@ type Config struct {
 	AppURL string `mapstructure:"APP_URL"`
 
 	ClearTime string `mapstructure:"CLEAR_TIME"`
+
+	TrashRetentionDays int `mapstructure:"TRASH_RETENTION_DAYS"`
+
+	// BlobStore is where icons are kept: "local" for BlobDir on disk or
+	// "s3" for a bucket of an S3-compatible service.
+	BlobStore   string `mapstructure:"BLOB_STORE" validate:"oneof=local s3"`
+	BlobDir     string `mapstructure:"BLOB_DIR"`
+	S3Endpoint  string `mapstructure:"S3_ENDPOINT"`
+	S3Bucket    string `mapstructure:"S3_BUCKET"`
+	S3Region    string `mapstructure:"S3_REGION"`
+	S3AccessKey string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey string `mapstructure:"S3_SECRET_KEY"`
+
+	// Background jobs such as favicon fetching. JobHostInterval is the
+	// least time between two jobs for the same site, e.g. "1s".
+	JobWorkers      int           `mapstructure:"JOB_WORKERS" validate:"gte=1"`
+	JobMaxAttempts  int           `mapstructure:"JOB_MAX_ATTEMPTS" validate:"gte=1"`
+	JobHostInterval time.Duration `mapstructure:"JOB_HOST_INTERVAL"`
+
+	// TrackingParams are the query parameters removed from bookmark URLs,
+	// comma separated. A trailing * matches any suffix, as in "utm_*".
+	TrackingParams []string `mapstructure:"TRACKING_PARAMS"`
+
+	// The link checker checks each URL again after LinkCheckInterval, e.g.
+	// "168h". Every hour it queues checks of at most LinkCheckBatch URLs;
+	// 0 turns it off.
+	LinkCheckInterval time.Duration `mapstructure:"LINK_CHECK_INTERVAL"`
+	LinkCheckBatch    int           `mapstructure:"LINK_CHECK_BATCH" validate:"gte=0"`
+
+	// Sessions expire after SessionIdleTimeout without use and in any case
+	// SessionMaxAge after login, or after the SessionRemember ones when the
+	// user asked to be remembered. Use extends a session at most every
+	// SessionRefreshInterval.
+	SessionIdleTimeout         time.Duration `mapstructure:"SESSION_IDLE_TIMEOUT" validate:"gt=0"`
+	SessionMaxAge              time.Duration `mapstructure:"SESSION_MAX_AGE" validate:"gt=0"`
+	SessionRememberIdleTimeout time.Duration `mapstructure:"SESSION_REMEMBER_IDLE_TIMEOUT" validate:"gt=0"`
+	SessionRememberMaxAge      time.Duration `mapstructure:"SESSION_REMEMBER_MAX_AGE" validate:"gt=0"`
+	SessionRefreshInterval     time.Duration `mapstructure:"SESSION_REFRESH_INTERVAL" validate:"gt=0"`
+
+	// TOTPIssuer names the service in authenticator apps.
+	TOTPIssuer string `mapstructure:"TOTP_ISSUER"`
+
+	// Passkeys are bound to WebAuthnRPID, the domain of the site, and only
+	// used from pages of WebAuthnOrigins, comma separated. Both default to
+	// those of AppURL.
+	WebAuthnRPID    string   `mapstructure:"WEBAUTHN_RP_ID"`
+	WebAuthnRPName  string   `mapstructure:"WEBAUTHN_RP_NAME"`
+	WebAuthnOrigins []string `mapstructure:"WEBAUTHN_ORIGINS"`
 }
 
 var envs = []string{
 	"DB_HOST", "DB_NAME", "DB_USER", "DB_PORT", "DB_PASSWORD", "SMTP_API", "ENVIRONMENT", "LOG_LEVEL", "APP_URL", "CLEAR_TIME",
+	"TRASH_RETENTION_DAYS", "BLOB_STORE", "BLOB_DIR", "S3_ENDPOINT", "S3_BUCKET", "S3_REGION", "S3_ACCESS_KEY", "S3_SECRET_KEY",
+	"JOB_WORKERS", "JOB_MAX_ATTEMPTS", "JOB_HOST_INTERVAL", "TRACKING_PARAMS", "LINK_CHECK_INTERVAL",
+	"LINK_CHECK_BATCH", "SESSION_IDLE_TIMEOUT", "SESSION_MAX_AGE", "SESSION_REMEMBER_IDLE_TIMEOUT", "SESSION_REMEMBER_MAX_AGE",
+	"SESSION_REFRESH_INTERVAL", "TOTP_ISSUER", "WEBAUTHN_RP_ID", "WEBAUTHN_RP_NAME", "WEBAUTHN_ORIGINS",
+}
+
+var defaults = map[string]any{
+	"TRASH_RETENTION_DAYS": 30,
+	"BLOB_STORE":           "local",
+	"BLOB_DIR":             "./data/blobs",
+	"S3_REGION":            "us-east-1",
+	"JOB_WORKERS":          4,
+	"JOB_MAX_ATTEMPTS":     5,
+	"JOB_HOST_INTERVAL":    "1s",
+	"TRACKING_PARAMS":      urlnorm.DefaultTrackingParams,
+	"LINK_CHECK_INTERVAL":  "168h",
+	"LINK_CHECK_BATCH":     500,
+
+	"SESSION_IDLE_TIMEOUT":          "2h",
+	"SESSION_MAX_AGE":               "24h",
+	"SESSION_REMEMBER_IDLE_TIMEOUT": "720h",
+	"SESSION_REMEMBER_MAX_AGE":      "2160h",
+	"SESSION_REFRESH_INTERVAL":      "5m",
+
+	"TOTP_ISSUER":      "Theca",
+	"WEBAUTHN_RP_NAME": "Theca",
 }
 
 func LoadConfig() (Config, error) {
@@ -39,6 +115,10 @@ func LoadConfig() (Config, error) {
 		log.Fatal(err)
 	}
 
+	for key, value := range defaults {
+		viper.SetDefault(key, value)
+	}
+
 	for _, env := range envs {
 		if err := viper.BindEnv(env); err != nil {
 			return config, err
@@ -7,6 +7,7 @@ import (
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	config "github.com/OxytocinGroup/theca-backend/internal/config"
 	domain "github.com/OxytocinGroup/theca-backend/internal/domain"
@@ -40,8 +41,68 @@ func ConnectDatabase(cfg config.Config) Database {
     }
 
     db := &GormDatabase{Conn: conn}
-    if err := db.AutoMigrate(&domain.User{}, &domain.Session{}, &domain.Bookmark{}); err != nil {
+    if err := db.AutoMigrate(&domain.User{}, &domain.Session{}, &domain.Bookmark{}, &domain.Folder{}, &domain.Tag{}, &domain.Plan{}, &domain.Icon{}, &domain.Job{}, &domain.RecoveryCode{}, &domain.LoginChallenge{}, &domain.Passkey{}, &domain.PasskeyCeremony{}); err != nil {
         log.Fatalf("Failed to migrate database: %v", err)
     }
+    for _, statement := range searchMigrations {
+        if err := conn.Exec(statement).Error; err != nil {
+            log.Fatalf("Failed to create search indexes: %v", err)
+        }
+    }
+    for _, statement := range sessionMigrations {
+        if err := conn.Exec(statement).Error; err != nil {
+            log.Fatalf("Failed to migrate sessions: %v", err)
+        }
+    }
+    for _, statement := range duplicateMigrations {
+        if err := conn.Exec(statement).Error; err != nil {
+            log.Fatalf("Failed to create duplicate URL index: %v", err)
+        }
+    }
+    if err := seedPlans(conn); err != nil {
+        log.Fatalf("Failed to create plans: %v", err)
+    }
     return db
 }
+
+// seedPlans creates the default plans that are missing.
+func seedPlans(conn *gorm.DB) error {
+    plans := append([]domain.Plan(nil), domain.DefaultPlans...)
+    return conn.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&plans).Error
+}
+
+// searchMigrations prepare bookmarks for full-text and fuzzy search. The
+// search document holds the title and the host and path of the URL split
+// into words, so "github" matches "https://github.com/...".
+var searchMigrations = []string{
+    `CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+    `ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS search_document tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('simple', regexp_replace(
+            regexp_replace(coalesce(url, ''), '^[a-zA-Z][a-zA-Z0-9+.-]*://|[?#].*$', '', 'g'),
+            '[^[:alnum:]]+', ' ', 'g')), 'B')
+    ) STORED`,
+    `CREATE INDEX IF NOT EXISTS idx_bookmarks_search_document ON bookmarks USING GIN (search_document)`,
+    `CREATE INDEX IF NOT EXISTS idx_bookmarks_title_trgm ON bookmarks USING GIN (title gin_trgm_ops)`,
+    `CREATE INDEX IF NOT EXISTS idx_bookmarks_url_trgm ON bookmarks USING GIN (url gin_trgm_ops)`,
+}
+
+// duplicateMigrations keep a user from having two bookmarks with the same
+// normalized URL outside the trash. Bookmarks not backfilled yet have an
+// empty one and are left out.
+var duplicateMigrations = []string{
+    `CREATE UNIQUE INDEX IF NOT EXISTS idx_bookmarks_user_normalized_url ON bookmarks (user_id, normalized_url)
+        WHERE deleted_at IS NULL AND normalized_url <> ''`,
+}
+
+// sessionMigrations give sessions opened before they had handles one, so
+// they can be revoked like the others, and replace IDs stored before
+// sessions were stored by token hash with the hash, keeping their cookies
+// valid. Such sessions are rotated on their next use. Sessions from before
+// idle timeouts keep their expiry as their maximum and the long policy.
+var sessionMigrations = []string{
+    `UPDATE sessions SET handle = gen_random_uuid()::text WHERE handle IS NULL OR handle = ''`,
+    `UPDATE sessions SET id = encode(sha256(convert_to(id, 'UTF8')), 'hex') WHERE length(id) <> 64`,
+    `UPDATE sessions SET rotated_at = created_at WHERE rotated_at IS NULL`,
+    `UPDATE sessions SET max_expires_at = expires_at, remember = true WHERE max_expires_at IS NULL`,
+}
@@ -4,7 +4,10 @@ import (
 	"github.com/OxytocinGroup/theca-backend/internal/config"
 	"github.com/OxytocinGroup/theca-backend/internal/repository"
 	"github.com/OxytocinGroup/theca-backend/internal/usecase"
+	"github.com/OxytocinGroup/theca-backend/pkg/blobstore"
 	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"github.com/OxytocinGroup/theca-backend/pkg/queue"
+	"github.com/OxytocinGroup/theca-backend/pkg/urlnorm"
 	"gorm.io/gorm"
 )
 
@@ -38,20 +41,88 @@ func (d *DevDeps) UserRepository() repository.UserRepository {
 	return repository.NewUserRepository(d.Db)
 }
 
-func (d *DevDeps) SessionUseCase(repo repository.SessionRepository, log logger.Logger) usecase.SessionUseCase {
-	return usecase.NewSessionUseCase(repo, log)
+func (d *DevDeps) SessionUseCase(repo repository.SessionRepository, cfg config.Config, log logger.Logger) usecase.SessionUseCase {
+	return usecase.NewSessionUseCase(repo, cfg, log)
 }
 
-func (d *DevDeps) UserUseCase(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, cfg config.Config, log logger.Logger) usecase.UserUseCase {
-	return usecase.NewUserUseCase(userRepo, sessionRepo, cfg, log)
+func (d *DevDeps) TwoFactorRepository() repository.TwoFactorRepository {
+	return repository.NewTwoFactorRepository(d.Db)
+}
+
+func (d *DevDeps) TwoFactorUseCase(userRepo repository.UserRepository, twoFactorRepo repository.TwoFactorRepository, passkeyRepo repository.PasskeyRepository, passkeys usecase.PasskeyUseCase, cfg config.Config, log logger.Logger) usecase.TwoFactorUseCase {
+	return usecase.NewTwoFactorUseCase(userRepo, twoFactorRepo, passkeyRepo, passkeys, cfg, log)
+}
+
+func (d *DevDeps) PasskeyRepository() repository.PasskeyRepository {
+	return repository.NewPasskeyRepository(d.Db)
+}
+
+func (d *DevDeps) PasskeyUseCase(userRepo repository.UserRepository, passkeyRepo repository.PasskeyRepository, cfg config.Config, log logger.Logger) (usecase.PasskeyUseCase, error) {
+	return usecase.NewPasskeyUseCase(userRepo, passkeyRepo, cfg, log)
+}
+
+func (d *DevDeps) UserUseCase(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, quota usecase.QuotaService, cfg config.Config, log logger.Logger) usecase.UserUseCase {
+	return usecase.NewUserUseCase(userRepo, sessionRepo, quota, cfg, log)
 }
 
 func (d *DevDeps) BookmarkRepository() repository.BookmarkRepository {
 	return repository.NewBookmarkRepository(d.Db)
 }
 
-func (d *DevDeps) BookmarkUseCase(bookmarkRepo repository.BookmarkRepository, userRepo repository.UserRepository, log logger.Logger) usecase.BookmarkUseCase {
-	return usecase.NewBookmarkUseCase(bookmarkRepo, userRepo, log)
+func (d *DevDeps) BookmarkUseCase(bookmarkRepo repository.BookmarkRepository, folderRepo repository.FolderRepository, tagRepo repository.TagRepository, uow repository.UnitOfWork, quota usecase.QuotaService, icons usecase.IconUseCase, jobs usecase.JobQueue, urls *urlnorm.Normalizer, log logger.Logger) usecase.BookmarkUseCase {
+	return usecase.NewBookmarkUseCase(bookmarkRepo, folderRepo, tagRepo, uow, quota, icons, jobs, urls, log)
+}
+
+func (d *DevDeps) IconRepository() repository.IconRepository {
+	return repository.NewIconRepository(d.Db)
+}
+
+func (d *DevDeps) IconUseCase(iconRepo repository.IconRepository, blobs blobstore.Store, log logger.Logger) usecase.IconUseCase {
+	return usecase.NewIconUseCase(iconRepo, blobs, log)
+}
+
+func (d *DevDeps) BlobStore(cfg config.Config) (blobstore.Store, error) {
+	return blobstore.FromConfig(cfg)
+}
+
+func (d *DevDeps) JobRepository() repository.JobRepository {
+	return repository.NewJobRepository(d.Db)
+}
+
+func (d *DevDeps) JobQueue(repo repository.JobRepository, cfg config.Config, log logger.Logger) *queue.Queue {
+	opts := queue.DefaultOptions
+	opts.Workers = cfg.JobWorkers
+	opts.MaxAttempts = cfg.JobMaxAttempts
+	opts.HostInterval = cfg.JobHostInterval
+	return queue.New(repo, log, opts)
+}
+
+func (d *DevDeps) FolderRepository() repository.FolderRepository {
+	return repository.NewFolderRepository(d.Db)
+}
+
+func (d *DevDeps) FolderUseCase(folderRepo repository.FolderRepository, uow repository.UnitOfWork, quota usecase.QuotaService, log logger.Logger) usecase.FolderUseCase {
+	return usecase.NewFolderUseCase(folderRepo, uow, quota, log)
+}
+
+func (d *DevDeps) TagRepository() repository.TagRepository {
+	return repository.NewTagRepository(d.Db)
+}
+
+func (d *DevDeps) TagUseCase(tagRepo repository.TagRepository, uow repository.UnitOfWork, quota usecase.QuotaService, log logger.Logger) usecase.TagUseCase {
+	return usecase.NewTagUseCase(tagRepo, uow, quota, log)
+}
+
+func (d *DevDeps) PlanRepository() repository.PlanRepository {
+	return repository.NewPlanRepository(d.Db)
+}
+
+func (d *DevDeps) QuotaService(repos repository.Repositories) usecase.QuotaService {
+	return usecase.NewQuotaService(repos)
+}
+
+func (d *DevDeps) UnitOfWork() repository.UnitOfWork {
+	return repository.NewUnitOfWork(d.Db)
 }
 
 func (d *DevDeps) Logger() logger.Logger {
@@ -6,10 +6,14 @@ import (
 	http "github.com/OxytocinGroup/theca-backend/internal/api"
 	"github.com/OxytocinGroup/theca-backend/internal/api/handler"
 	"github.com/OxytocinGroup/theca-backend/internal/config"
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
 	"github.com/OxytocinGroup/theca-backend/internal/repository"
 	"github.com/OxytocinGroup/theca-backend/internal/usecase"
+	"github.com/OxytocinGroup/theca-backend/pkg/blobstore"
 	"github.com/OxytocinGroup/theca-backend/pkg/cron"
 	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"github.com/OxytocinGroup/theca-backend/pkg/queue"
+	"github.com/OxytocinGroup/theca-backend/pkg/urlnorm"
 	"gorm.io/gorm"
 )
 
@@ -18,10 +22,26 @@ type DepsProvider interface {
 	UserRepository() repository.UserRepository
 	SessionRepository() repository.SessionRepository
 	BookmarkRepository() repository.BookmarkRepository
+	FolderRepository() repository.FolderRepository
+	TagRepository() repository.TagRepository
+	PlanRepository() repository.PlanRepository
+	IconRepository() repository.IconRepository
+	JobRepository() repository.JobRepository
+	TwoFactorRepository() repository.TwoFactorRepository
+	PasskeyRepository() repository.PasskeyRepository
+	BlobStore(config.Config) (blobstore.Store, error)
+	UnitOfWork() repository.UnitOfWork
+	JobQueue(repository.JobRepository, config.Config, logger.Logger) *queue.Queue
 
-	UserUseCase(repository.UserRepository, repository.SessionRepository, config.Config, logger.Logger) usecase.UserUseCase
-	SessionUseCase(repository.SessionRepository, logger.Logger) usecase.SessionUseCase
-	BookmarkUseCase(repository.BookmarkRepository, repository.UserRepository, logger.Logger) usecase.BookmarkUseCase
+	QuotaService(repository.Repositories) usecase.QuotaService
+	UserUseCase(repository.UserRepository, repository.SessionRepository, usecase.QuotaService, config.Config, logger.Logger) usecase.UserUseCase
+	SessionUseCase(repository.SessionRepository, config.Config, logger.Logger) usecase.SessionUseCase
+	PasskeyUseCase(repository.UserRepository, repository.PasskeyRepository, config.Config, logger.Logger) (usecase.PasskeyUseCase, error)
+	TwoFactorUseCase(repository.UserRepository, repository.TwoFactorRepository, repository.PasskeyRepository, usecase.PasskeyUseCase, config.Config, logger.Logger) usecase.TwoFactorUseCase
+	IconUseCase(repository.IconRepository, blobstore.Store, logger.Logger) usecase.IconUseCase
+	BookmarkUseCase(repository.BookmarkRepository, repository.FolderRepository, repository.TagRepository, repository.UnitOfWork, usecase.QuotaService, usecase.IconUseCase, usecase.JobQueue, *urlnorm.Normalizer, logger.Logger) usecase.BookmarkUseCase
+	FolderUseCase(repository.FolderRepository, repository.UnitOfWork, usecase.QuotaService, logger.Logger) usecase.FolderUseCase
+	TagUseCase(repository.TagRepository, repository.UnitOfWork, usecase.QuotaService, logger.Logger) usecase.TagUseCase
 
 	Logger() logger.Logger
 
@@ -35,15 +55,56 @@ func InitializeAPI(provider DepsProvider) (*http.ServerHTTP, error) {
 	userRepo := provider.UserRepository()
 	sessionRepo := provider.SessionRepository()
 	bookmarkRepo := provider.BookmarkRepository()
+	folderRepo := provider.FolderRepository()
+	tagRepo := provider.TagRepository()
+	planRepo := provider.PlanRepository()
+	iconRepo := provider.IconRepository()
+	jobRepo := provider.JobRepository()
+	twoFactorRepo := provider.TwoFactorRepository()
+	passkeyRepo := provider.PasskeyRepository()
+	uow := provider.UnitOfWork()
 
-	fmt.Println("init scheduler")
-	cron.InitScheduler(&cfg, log, sessionRepo)
+	blobs, err := provider.BlobStore(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("init blob store: %w", err)
+	}
+	passkeyUC, err := provider.PasskeyUseCase(userRepo, passkeyRepo, cfg, log)
+	if err != nil {
+		return nil, fmt.Errorf("init passkeys: %w", err)
+	}
+
+	quota := provider.QuotaService(repository.Repositories{
+		Users:     userRepo,
+		Bookmarks: bookmarkRepo,
+		Folders:   folderRepo,
+		Tags:      tagRepo,
+		Plans:     planRepo,
+	})
 
-	userUC := provider.UserUseCase(userRepo, sessionRepo, cfg, log)
-	sessionUC := provider.SessionUseCase(sessionRepo, log)
-	bookmarkUC := provider.BookmarkUseCase(bookmarkRepo, userRepo, log)
+	userUC := provider.UserUseCase(userRepo, sessionRepo, quota, cfg, log)
+	sessionUC := provider.SessionUseCase(sessionRepo, cfg, log)
+	twoFactorUC := provider.TwoFactorUseCase(userRepo, twoFactorRepo, passkeyRepo, passkeyUC, cfg, log)
+	iconUC := provider.IconUseCase(iconRepo, blobs, log)
+	jobQueue := provider.JobQueue(jobRepo, cfg, log)
+	bookmarkUC := provider.BookmarkUseCase(bookmarkRepo, folderRepo, tagRepo, uow, quota, iconUC, jobQueue, urlnorm.New(cfg.TrackingParams), log)
+	folderUC := provider.FolderUseCase(folderRepo, uow, quota, log)
+	tagUC := provider.TagUseCase(tagRepo, uow, quota, log)
 
-	userHandler := handler.NewUserHandler(userUC, sessionUC, log)
+	userHandler := handler.NewUserHandler(userUC, sessionUC, twoFactorUC, passkeyUC, log)
 	bookmarkHandler := handler.NewBookmarkHandler(bookmarkUC, log)
-	return http.NewServerHTTP(userHandler, bookmarkHandler), nil
+	folderHandler := handler.NewFolderHandler(folderUC, log)
+	tagHandler := handler.NewTagHandler(tagUC, log)
+	iconHandler := handler.NewIconHandler(iconUC, log)
+
+	jobQueue.Handle(domain.JobFetchIcon, bookmarkUC.FetchFavicon)
+	jobQueue.Handle(domain.JobFetchMetadata, bookmarkUC.FetchMetadata)
+	jobQueue.Handle(domain.JobCheckLink, bookmarkUC.CheckLink)
+	jobQueue.Start()
+
+	fmt.Println("init scheduler")
+	cron.InitScheduler(&cfg, log, sessionRepo, twoFactorRepo, passkeyRepo, bookmarkRepo, jobRepo, jobQueue)
+
+	server := http.NewServerHTTP(userHandler, bookmarkHandler, folderHandler, tagHandler, iconHandler)
+	server.OnShutdown(jobQueue.Shutdown)
+	return server, nil
 }
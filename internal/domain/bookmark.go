@@ -1,10 +1,73 @@
 package domain
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type Bookmark struct {
-	ID uint `json:"id" gorm:"primaryKey;not null;unique"`
-	UserID uint `json:"user_id"`
-	Title string `json:"title" gorm:"size:128"`
-	URL string `json:"url" gorm:"size:255"`
-	IconURL string `json:"icon_url" gorm:"size:255"`
-	ShowText bool `json:"show_text" gorm:"default:false"`
-}
\ No newline at end of file
+	ID        uint      `json:"id" gorm:"primaryKey;not null;unique"`
+	UserID    uint      `json:"user_id"`
+	FolderID  *uint     `json:"folder_id" gorm:"index"`
+	Title     string    `json:"title" gorm:"size:128"`
+	URL       string    `json:"url" gorm:"size:255"`
+	IconURL   string    `json:"icon_url" gorm:"size:255"`
+	IconHash  string    `json:"icon_hash" gorm:"size:64;index"`
+	ShowText  bool      `json:"show_text" gorm:"default:false"`
+	Position  string    `json:"position" gorm:"size:255;index"`
+	Tags      []Tag     `json:"tags" gorm:"many2many:bookmark_tags;"`
+	CreatedAt time.Time `json:"created_at"`
+	// DeletedAt is set while the bookmark is in the trash.
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string"`
+	// ImportSource and ImportTags record where an imported bookmark came
+	// from and the tags it had there, as written by the source.
+	ImportSource string `json:"import_source,omitempty" gorm:"size:32"`
+	ImportTags   string `json:"import_tags,omitempty" gorm:"size:1024"`
+	// DominantColor and AccentColor come from the icon, as "#rrggbb", for
+	// tiles in the site's colors. Empty until the icon is known.
+	DominantColor string `json:"dominant_color" gorm:"size:7"`
+	AccentColor   string `json:"accent_color" gorm:"size:7"`
+	// Description, SiteName, CanonicalURL and ImageURL are read from the
+	// page after the bookmark is saved, for link previews. Empty until
+	// then and when the page does not provide them.
+	Description  string `json:"description" gorm:"size:1024"`
+	SiteName     string `json:"site_name" gorm:"size:128"`
+	CanonicalURL string `json:"canonical_url" gorm:"size:255"`
+	ImageURL     string `json:"image_url" gorm:"size:1024"`
+	// NormalizedURL is the duplicate key of URL, see urlnorm.Normalizer.
+	// A user has at most one bookmark outside the trash per key. Empty for
+	// bookmarks saved before keys were kept, until they are backfilled.
+	NormalizedURL string `json:"-" gorm:"size:255"`
+	// LinkStatus is what the link checker last found at URL, one of the
+	// Link constants, and empty until the first check. LinkStatusCode is
+	// the status of the last response, 0 when none came, and LinkFinalURL
+	// where redirects led, if anywhere.
+	LinkStatus     string     `json:"link_status" gorm:"size:16;index"`
+	LinkStatusCode int        `json:"link_status_code"`
+	LinkFinalURL   string     `json:"link_final_url" gorm:"size:255"`
+	LinkCheckedAt  *time.Time `json:"link_checked_at"`
+}
+
+// DuplicateGroup is a set of a user's bookmarks saved under URLs that
+// lead to the same page, oldest first.
+type DuplicateGroup struct {
+	NormalizedURL string     `json:"normalized_url"`
+	Bookmarks     []Bookmark `json:"bookmarks"`
+}
+
+// BookmarkSearchResult is a bookmark matched by a search query. The
+// highlights are HTML-escaped with the matched words wrapped in <mark>.
+type BookmarkSearchResult struct {
+	Bookmark
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	URLHighlight   string  `json:"url_highlight"`
+}
+
+// BookmarkFilter narrows down a bookmark listing. Bookmarks must carry all
+// of Tags when MatchAll is set and at least one of them otherwise.
+type BookmarkFilter struct {
+	Tags     []string
+	MatchAll bool
+}
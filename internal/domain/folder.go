@@ -0,0 +1,23 @@
+package domain
+
+type Folder struct {
+	ID       uint   `json:"id" gorm:"primaryKey;not null;unique"`
+	UserID   uint   `json:"user_id" gorm:"index"`
+	ParentID *uint  `json:"parent_id" gorm:"index"`
+	Name     string `json:"name" gorm:"size:128;not null"`
+	Position int    `json:"position" gorm:"default:0"`
+}
+
+// FolderNode is a folder together with its nested folders and bookmarks.
+type FolderNode struct {
+	Folder
+	Folders   []FolderNode `json:"folders"`
+	Bookmarks []Bookmark   `json:"bookmarks"`
+}
+
+// BookmarkTree is the root level of a user's bookmarks: top-level folders
+// and the bookmarks that are not placed in any folder.
+type BookmarkTree struct {
+	Folders   []FolderNode `json:"folders"`
+	Bookmarks []Bookmark   `json:"bookmarks"`
+}
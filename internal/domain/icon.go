@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// Icon is a favicon stored by Theca. Icons are content addressed: Hash is
+// the hex SHA-256 of the image, so bookmarks of different users pointing
+// to the same site share one icon. Besides the original, icons have PNG
+// variants of standard sizes unless HasVariants is false, which happens
+// for SVGs that could not be rasterized. Generated icons are letter
+// avatars made for sites without a favicon. The colors are "#rrggbb" and
+// empty when unknown.
+type Icon struct {
+	Hash          string    `json:"hash" gorm:"primaryKey;size:64"`
+	ContentType   string    `json:"content_type" gorm:"size:64"`
+	Size          int       `json:"size"`
+	HasVariants   bool      `json:"has_variants"`
+	Generated     bool      `json:"generated"`
+	DominantColor string    `json:"dominant_color" gorm:"size:7"`
+	AccentColor   string    `json:"accent_color" gorm:"size:7"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// IconPath is where the icon with the given hash is served.
+func IconPath(hash string) string {
+	return "/icons/" + hash
+}
@@ -0,0 +1,68 @@
+package domain
+
+import "time"
+
+// Job statuses. Queued jobs wait for RunAt, running ones are held by a
+// worker, and dead jobs failed MaxAttempts times and are kept for
+// inspection until they are retried by hand.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobDead    = "dead"
+)
+
+// Job kinds.
+const (
+	// JobFetchIcon stores the favicon of a bookmark, see FetchIconPayload.
+	JobFetchIcon = "fetch_icon"
+	// JobFetchMetadata reads the title, description and preview image of
+	// a bookmark's page, see FetchMetadataPayload.
+	JobFetchMetadata = "fetch_metadata"
+	// JobCheckLink checks whether a URL still works for every bookmark
+	// saved under it, see CheckLinkPayload.
+	JobCheckLink = "check_link"
+)
+
+// Job is a unit of background work kept in Postgres so it survives
+// restarts. Payload is the JSON of the kind's payload type. Host is the
+// site the job sends requests to, used to rate limit them; it is empty for
+// jobs that do not go out.
+type Job struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Kind        string     `json:"kind" gorm:"size:64;not null"`
+	Payload     string     `json:"payload" gorm:"type:text"`
+	Host        string     `json:"host" gorm:"size:255"`
+	Status      string     `json:"status" gorm:"size:16;not null;index:idx_jobs_status_run_at,priority:1"`
+	RunAt       time.Time  `json:"run_at" gorm:"not null;index:idx_jobs_status_run_at,priority:2"`
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"max_attempts"`
+	LastError   string     `json:"last_error" gorm:"type:text"`
+	LockedAt    *time.Time `json:"locked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// LastAttempt reports whether a running job fails for good if this
+// attempt fails.
+func (j Job) LastAttempt() bool {
+	return j.Attempts >= j.MaxAttempts
+}
+
+type FetchIconPayload struct {
+	BookmarkID uint `json:"bookmark_id"`
+}
+
+// FetchMetadataPayload names the bookmark whose page is read. The title
+// found replaces the bookmark's title only while it is still
+// PlaceholderTitle, the one given to bookmarks saved without a title.
+type FetchMetadataPayload struct {
+	BookmarkID       uint   `json:"bookmark_id"`
+	PlaceholderTitle string `json:"placeholder_title,omitempty"`
+}
+
+// CheckLinkPayload is the URL checked. Jobs are per URL rather than per
+// bookmark, so a URL many users saved is requested once.
+type CheckLinkPayload struct {
+	URL string `json:"url"`
+}
@@ -0,0 +1,24 @@
+package domain
+
+// Link statuses of a bookmark, see Bookmark.LinkStatus.
+const (
+	LinkOK = "ok"
+	// LinkRedirected links lead to a page that moved for good, to
+	// Bookmark.LinkFinalURL.
+	LinkRedirected = "redirected"
+	// LinkBroken links lead to a missing page, a failing server or a site
+	// that does not resolve.
+	LinkBroken = "broken"
+	// LinkUnknown links could not be checked: the site refuses automated
+	// requests, or the URL is not on the public internet.
+	LinkUnknown = "unknown"
+)
+
+// LinkHealthSummary counts a user's bookmarks by link status.
+type LinkHealthSummary struct {
+	OK         int `json:"ok"`
+	Redirected int `json:"redirected"`
+	Broken     int `json:"broken"`
+	Unknown    int `json:"unknown"`
+	Unchecked  int `json:"unchecked"`
+}
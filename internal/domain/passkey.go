@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+const (
+	PasskeyRegistration = "registration"
+	PasskeyLogin        = "login"
+)
+
+// Passkey is a WebAuthn credential of a user. SignCount is the
+// authenticator's signature counter at the last use; CloneWarning is set
+// when it went backwards, a sign that the key was copied.
+type Passkey struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	UserID          uint       `json:"-" gorm:"index;not null"`
+	Name            string     `json:"name" gorm:"size:64"`
+	CredentialID    []byte     `json:"-" gorm:"uniqueIndex;not null"`
+	PublicKey       []byte     `json:"-" gorm:"not null"`
+	AttestationType string     `json:"-" gorm:"size:32"`
+	AAGUID          []byte     `json:"-"`
+	Transports      string     `json:"-" gorm:"size:255"`
+	SignCount       uint32     `json:"-"`
+	CloneWarning    bool       `json:"clone_warning"`
+	UserVerified    bool       `json:"-"`
+	BackupEligible  bool       `json:"-"`
+	BackedUp        bool       `json:"backed_up"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// PasskeyCeremony keeps the WebAuthn state of a passkey registration or
+// passkey login between its two requests. Its ID is the SHA-256 of the
+// token given to the client. Logins have no UserID until the passkey
+// names the user.
+type PasskeyCeremony struct {
+	ID        string `gorm:"primaryKey;size:64"`
+	Kind      string `gorm:"size:16;not null"`
+	UserID    uint
+	Name      string `gorm:"size:64"`
+	Remember  bool
+	Session   []byte    `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,43 @@
+package domain
+
+// Unlimited is the value of a plan limit without an upper bound.
+const Unlimited = -1
+
+const (
+	PlanFree      = "free"
+	PlanPro       = "pro"
+	PlanUnlimited = "unlimited"
+)
+
+// Plan holds the resource limits of a tier. Users without a plan are on
+// the free plan.
+type Plan struct {
+	ID           uint   `json:"id" gorm:"primaryKey;not null;unique"`
+	Name         string `json:"name" gorm:"size:32;uniqueIndex;not null"`
+	MaxBookmarks int    `json:"max_bookmarks"`
+	MaxFolders   int    `json:"max_folders"`
+	MaxTags      int    `json:"max_tags"`
+}
+
+// DefaultPlans are created on startup when missing. The limits of existing
+// plans are changed in the database and are not overwritten.
+var DefaultPlans = []Plan{
+	{Name: PlanFree, MaxBookmarks: 25, MaxFolders: 10, MaxTags: 20},
+	{Name: PlanPro, MaxBookmarks: 1000, MaxFolders: 200, MaxTags: 500},
+	{Name: PlanUnlimited, MaxBookmarks: Unlimited, MaxFolders: Unlimited, MaxTags: Unlimited},
+}
+
+// ResourceUsage is how much of a resource a user has and may have. Limit
+// is Unlimited when there is no upper bound.
+type ResourceUsage struct {
+	Used  int64 `json:"used"`
+	Limit int   `json:"limit"`
+}
+
+// QuotaUsage reports a user's plan and resource usage.
+type QuotaUsage struct {
+	Plan      string        `json:"plan"`
+	Bookmarks ResourceUsage `json:"bookmarks"`
+	Folders   ResourceUsage `json:"folders"`
+	Tags      ResourceUsage `json:"tags"`
+}
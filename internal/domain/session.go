@@ -3,9 +3,30 @@ package domain
 import "time"
 
 type Session struct {
-	ID        string `gorm:"primaryKey;unique;not null" json:"id"`
-	UserID    uint   `json:"user_id"`
-	ExpiresAt time.Time
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	// ID is the SHA-256 of the session token, the session cookie's value,
+	// in hex. The token itself is never stored. Sessions are shown and
+	// revoked by Handle.
+	ID     string `gorm:"primaryKey;unique;not null" json:"-"`
+	UserID uint   `json:"-"`
+	// ExpiresAt moves forward as the session is used, by the idle timeout
+	// of its policy, but never past MaxExpiresAt. Remember selects the long
+	// policy.
+	ExpiresAt    time.Time `json:"expires_at"`
+	MaxExpiresAt time.Time `json:"-"`
+	Remember     bool      `json:"remember"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"-"`
+	// Handle identifies the session to its user. UserAgent and IP are
+	// those of the device at login. LastSeenAt is only written every few
+	// minutes.
+	Handle     string    `json:"id" gorm:"size:36;index"`
+	UserAgent  string    `json:"user_agent" gorm:"size:512"`
+	IP         string    `json:"ip" gorm:"size:45"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// PreviousID is the ID before the last rotation at RotatedAt. It stays
+	// valid for a moment, for requests that were sent with the old token.
+	PreviousID string    `json:"-" gorm:"size:64;index"`
+	RotatedAt  time.Time `json:"-"`
+	// Current marks the session of the request listing sessions.
+	Current bool `json:"current" gorm:"-"`
 }
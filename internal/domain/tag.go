@@ -0,0 +1,9 @@
+package domain
+
+type Tag struct {
+	ID     uint   `json:"id" gorm:"primaryKey;not null;unique"`
+	UserID uint   `json:"user_id" gorm:"uniqueIndex:idx_tags_user_name"`
+	Name   string `json:"name" gorm:"size:64;not null;uniqueIndex:idx_tags_user_name"`
+	// BookmarkCount is only filled when tags are listed with their usage.
+	BookmarkCount int64 `json:"bookmark_count,omitempty" gorm:"->;-:migration"`
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// RecoveryCode lets a user who lost their authenticator complete a login
+// once. Only the SHA-256 of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"-" gorm:"primaryKey"`
+	UserID    uint       `json:"-" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	UsedAt    *time.Time `json:"-"`
+	CreatedAt time.Time  `json:"-"`
+}
+
+// LoginChallenge is a login whose password was checked and which waits
+// for the second factor. Its ID is the SHA-256 of the token given to the
+// client, like a session's. Remember is carried over to the session.
+// PasskeySession is the WebAuthn state when a passkey was asked for.
+type LoginChallenge struct {
+	ID             string `gorm:"primaryKey;size:64"`
+	UserID         uint   `gorm:"index;not null"`
+	Remember       bool
+	Attempts       int
+	PasskeySession []byte
+	ExpiresAt      time.Time `gorm:"index"`
+	CreatedAt      time.Time
+}
@@ -12,4 +12,12 @@ type User struct {
 	AmountOfBookmarks uint `json:"amount_of_bookmarks"`
 	ResetToken       string    `json:"reset_token" gorm:"size:255"`
 	ResetTokenExpire time.Time `json:"reset_token_expire"`
+	// PlanID is nil for users on the free plan.
+	PlanID *uint `json:"plan_id" gorm:"index"`
+	// TOTPSecret is set when the user starts enrolling an authenticator
+	// app; TOTPEnabled once a first code confirmed it. TOTPLastStep is the
+	// time step of the last code used, which cannot be used again.
+	TOTPSecret   string `json:"-" gorm:"size:64"`
+	TOTPEnabled  bool   `json:"totp_enabled" gorm:"default:false"`
+	TOTPLastStep int64  `json:"-" gorm:"default:0"`
 }
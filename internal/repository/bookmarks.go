@@ -1,17 +1,73 @@
 package repository
 
 import (
+	"errors"
+	"time"
+
 	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
+// ErrDuplicateURL is returned when saving a bookmark would give the user
+// two bookmarks outside the trash with the same normalized URL.
+var ErrDuplicateURL = errors.New("bookmark with this URL already exists")
+
+// normalizedURLIndex keeps normalized URLs unique per user, see
+// db.duplicateMigrations.
+const normalizedURLIndex = "idx_bookmarks_user_normalized_url"
+
 type BookmarkRepository interface {
 	CreateBookmark(bookmark *domain.Bookmark) error
 	GetBookmarksByUser(userID uint) ([]domain.Bookmark, error)
+	GetBookmarksByTags(userID uint, tags []string, matchAll bool) ([]domain.Bookmark, error)
+	ReplaceBookmarkTags(bookmarkID uint, tagIDs []uint) error
+	SearchBookmarks(userID uint, query string, limit, offset int) ([]domain.BookmarkSearchResult, int64, error)
+	GetLastPosition(userID uint) (string, error)
+	UpdateBookmarkPosition(bookmarkID uint, position string) error
+	MoveBookmarkToFolder(bookmarkID uint, folderID *uint) error
+	RenumberBookmarks(bookmarkIDs []uint, positions []string) error
 	UpdateBookmark(bookmark *domain.Bookmark) error
 	DeleteBookmarkByID(bookmarkID uint) error
 	GetBookmarkOwner(bookmarkID uint) (uint, error)
-	UploadBookmarkFavicon(bookmarkID uint, faviconURL string) error
+	GetBookmarkByID(bookmarkID uint) (domain.Bookmark, error)
+	UploadBookmarkFavicon(bookmarkID uint, icon domain.Icon) error
+	// UpdateBookmarkMetadata stores the description, site name, canonical
+	// URL and image URL of metadata. Its title is only stored while the
+	// bookmark's title is still placeholderTitle, and never when that is
+	// empty.
+	UpdateBookmarkMetadata(bookmarkID uint, metadata domain.Bookmark, placeholderTitle string) error
+	// CopyIconColors sets the colors of bookmarks, including those in the
+	// trash, to the colors of their icons and returns how many changed.
+	CopyIconColors() (int64, error)
+	// GetBookmarksWithoutFavicon returns bookmarks with no icon or with a
+	// generated one.
+	GetBookmarksWithoutFavicon() ([]domain.Bookmark, error)
+	GetDeletedBookmarks(userID uint) ([]domain.Bookmark, error)
+	GetDeletedBookmarkByID(bookmarkID uint) (domain.Bookmark, error)
+	RestoreBookmark(bookmarkID uint, folderID *uint) error
+	// GetBookmarkByNormalizedURL returns the user's bookmark outside the
+	// trash with the normalized URL.
+	GetBookmarkByNormalizedURL(userID uint, normalizedURL string) (domain.Bookmark, error)
+	// GetAllBookmarks returns every bookmark, including those in the trash.
+	GetAllBookmarks() ([]domain.Bookmark, error)
+	SetNormalizedURL(bookmarkID uint, normalizedURL string) error
+	// MergeBookmarks gives keepID the tags of mergeIDs and moves those to
+	// the trash, then sets the normalized URL of keepID, which they no
+	// longer hold.
+	MergeBookmarks(keepID uint, mergeIDs []uint, normalizedURL string) error
+	// GetURLsToCheck returns up to limit http and https URLs of bookmarks
+	// outside the trash that were not checked since checkedBefore and have
+	// no check queued, least recently checked first.
+	GetURLsToCheck(checkedBefore time.Time, limit int) ([]string, error)
+	// UpdateLinkHealth stores the link status fields of health on every
+	// bookmark saved under resourceURL.
+	UpdateLinkHealth(resourceURL string, health domain.Bookmark) error
+	// ApplyRedirect moves a bookmark to the URL its link redirects to. Its
+	// link status is cleared until the new URL is checked.
+	ApplyRedirect(bookmarkID uint, resourceURL, normalizedURL string) error
+	EmptyTrash(userID uint) (int64, error)
+	PurgeDeletedBookmarks(deletedBefore time.Time) (int64, error)
 }
 
 type bookmarkDatabase struct {
@@ -23,24 +79,184 @@ func NewBookmarkRepository(DB *gorm.DB) BookmarkRepository {
 }
 
 func (bdb *bookmarkDatabase) CreateBookmark(bookmark *domain.Bookmark) error {
-	return bdb.DB.Model(&domain.Bookmark{}).Create(bookmark).Error
+	return duplicateURLError(bdb.DB.Model(&domain.Bookmark{}).Omit("Tags").Create(bookmark).Error)
+}
+
+// duplicateURLError turns a violation of normalizedURLIndex into
+// ErrDuplicateURL. Other errors are returned unchanged.
+func duplicateURLError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == normalizedURLIndex {
+		return ErrDuplicateURL
+	}
+	return err
 }
 
 func (bdb *bookmarkDatabase) GetBookmarksByUser(userID uint) ([]domain.Bookmark, error) {
 	var results []domain.Bookmark
-	err := bdb.DB.Model(&domain.Bookmark{}).Where("user_id = ?", userID).Find(&results).Error
+	err := bdb.DB.Model(&domain.Bookmark{}).Preload("Tags", preloadTagsOrder).
+		Where("user_id = ?", userID).Order(positionOrder).Find(&results).Error
 	if err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
+func (bdb *bookmarkDatabase) GetBookmarksByTags(userID uint, tags []string, matchAll bool) ([]domain.Bookmark, error) {
+	tagged := bdb.DB.Table("bookmark_tags").
+		Select("bookmark_tags.bookmark_id").
+		Joins("JOIN tags ON tags.id = bookmark_tags.tag_id").
+		Where("tags.user_id = ? AND tags.name IN ?", userID, tags).
+		Group("bookmark_tags.bookmark_id")
+	if matchAll {
+		tagged = tagged.Having("COUNT(DISTINCT tags.id) = ?", len(tags))
+	}
+
+	var results []domain.Bookmark
+	err := bdb.DB.Model(&domain.Bookmark{}).Preload("Tags", preloadTagsOrder).
+		Where("user_id = ? AND id IN (?)", userID, tagged).Order(positionOrder).Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func preloadTagsOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("tags.name")
+}
+
+// searchCondition matches bookmarks by the full-text search document or,
+// for typos and partial words, by trigram similarity of the title and URL.
+const searchCondition = `bookmarks.user_id = @user AND bookmarks.deleted_at IS NULL AND (
+	bookmarks.search_document @@ websearch_to_tsquery('simple', @query)
+	OR bookmarks.title % @query
+	OR @query <% bookmarks.url)`
+
+// SearchBookmarks returns one page of the user's bookmarks matching query,
+// best matches first, together with the total number of matches.
+func (bdb *bookmarkDatabase) SearchBookmarks(userID uint, query string, limit, offset int) ([]domain.BookmarkSearchResult, int64, error) {
+	args := map[string]any{"user": userID, "query": query, "limit": limit, "offset": offset}
+
+	var total int64
+	if err := bdb.DB.Raw("SELECT COUNT(*) FROM bookmarks WHERE "+searchCondition, args).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []domain.BookmarkSearchResult{}, 0, nil
+	}
+
+	var ranked []struct {
+		ID   uint
+		Rank float64
+	}
+	err := bdb.DB.Raw(`SELECT bookmarks.id,
+			ts_rank(bookmarks.search_document, websearch_to_tsquery('simple', @query))
+			+ GREATEST(similarity(bookmarks.title, @query), word_similarity(@query, bookmarks.url)) AS rank
+		FROM bookmarks WHERE `+searchCondition+`
+		ORDER BY rank DESC, bookmarks.id
+		LIMIT @limit OFFSET @offset`, args).Scan(&ranked).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uint, len(ranked))
+	for i, row := range ranked {
+		ids[i] = row.ID
+	}
+	var bookmarks []domain.Bookmark
+	if err := bdb.DB.Model(&domain.Bookmark{}).Preload("Tags", preloadTagsOrder).Where("id IN ?", ids).Find(&bookmarks).Error; err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[uint]domain.Bookmark, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		byID[bookmark.ID] = bookmark
+	}
+
+	results := make([]domain.BookmarkSearchResult, 0, len(ranked))
+	for _, row := range ranked {
+		if bookmark, ok := byID[row.ID]; ok {
+			results = append(results, domain.BookmarkSearchResult{Bookmark: bookmark, Rank: row.Rank})
+		}
+	}
+	return results, total, nil
+}
+
+// ReplaceBookmarkTags sets the tags of a bookmark to exactly tagIDs.
+func (bdb *bookmarkDatabase) ReplaceBookmarkTags(bookmarkID uint, tagIDs []uint) error {
+	return bdb.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM bookmark_tags WHERE bookmark_id = ?", bookmarkID).Error; err != nil {
+			return err
+		}
+		for _, tagID := range tagIDs {
+			err := tx.Exec("INSERT INTO bookmark_tags (bookmark_id, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING", bookmarkID, tagID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// positionOrder sorts by the rank key byte-wise, matching pkg/rank,
+// regardless of the database collation.
+const positionOrder = `position COLLATE "C", id`
+
+func (bdb *bookmarkDatabase) GetLastPosition(userID uint) (string, error) {
+	var positions []string
+	err := bdb.DB.Model(&domain.Bookmark{}).Where("user_id = ?", userID).
+		Order(`position COLLATE "C" DESC`).Limit(1).Pluck("position", &positions).Error
+	if err != nil || len(positions) == 0 {
+		return "", err
+	}
+	return positions[0], nil
+}
+
+func (bdb *bookmarkDatabase) UpdateBookmarkPosition(bookmarkID uint, position string) error {
+	return bdb.DB.Model(&domain.Bookmark{}).Where("id = ?", bookmarkID).Update("position", position).Error
+}
+
+func (bdb *bookmarkDatabase) MoveBookmarkToFolder(bookmarkID uint, folderID *uint) error {
+	return bdb.DB.Model(&domain.Bookmark{}).Where("id = ?", bookmarkID).Update("folder_id", folderID).Error
+}
+
+func (bdb *bookmarkDatabase) RenumberBookmarks(bookmarkIDs []uint, positions []string) error {
+	return bdb.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range bookmarkIDs {
+			if err := tx.Model(&domain.Bookmark{}).Where("id = ?", id).Update("position", positions[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateBookmark saves the editable fields of a bookmark. The position is
+// only changed through UpdateBookmarkPosition. The link status is cleared
+// when the URL changes.
 func (bdb *bookmarkDatabase) UpdateBookmark(bookmark *domain.Bookmark) error {
-	return bdb.DB.Model(&domain.Bookmark{}).Where("id = ?", bookmark.ID).Save(bookmark).Error
+	err := bdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.Bookmark{}).Where("id = ? AND url IS DISTINCT FROM ?", bookmark.ID, bookmark.URL).
+			Updates(map[string]any{"link_status": "", "link_status_code": 0, "link_final_url": "", "link_checked_at": nil}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&domain.Bookmark{}).Where("id = ?", bookmark.ID).
+			Omit("position", "Tags", "created_at", "deleted_at", "import_source", "import_tags", "icon_url", "icon_hash", "dominant_color", "accent_color",
+				"description", "site_name", "canonical_url", "image_url", "link_status", "link_status_code", "link_final_url", "link_checked_at").
+			Save(bookmark).Error
+	})
+	return duplicateURLError(err)
 }
 
+// DeleteBookmarkByID moves a bookmark to the trash. Its tags are kept so a
+// restored bookmark comes back unchanged. It returns gorm.ErrRecordNotFound
+// when the bookmark is missing or already in the trash.
 func (bdb *bookmarkDatabase) DeleteBookmarkByID(bookmarkID uint) error {
-	return bdb.DB.Model(&domain.Bookmark{}).Where("id = ?", bookmarkID).Delete(&domain.Bookmark{}).Error
+	result := bdb.DB.Model(&domain.Bookmark{}).Where("id = ?", bookmarkID).Delete(&domain.Bookmark{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
 }
 
 func (bdb *bookmarkDatabase) GetBookmarkOwner(bookmarkID uint) (uint, error) {
@@ -49,6 +265,172 @@ func (bdb *bookmarkDatabase) GetBookmarkOwner(bookmarkID uint) (uint, error) {
 	return userID, err
 }
 
-func (bdb *bookmarkDatabase) UploadBookmarkFavicon(bookmarkID uint, faviconURL string) error {
-	return bdb.DB.Model(&domain.Bookmark{}).Where("id = ?", bookmarkID).Update("icon_url", faviconURL).Error
+func (bdb *bookmarkDatabase) GetBookmarkByID(bookmarkID uint) (domain.Bookmark, error) {
+	var bookmark domain.Bookmark
+	err := bdb.DB.Model(&domain.Bookmark{}).Where("id = ?", bookmarkID).First(&bookmark).Error
+	return bookmark, err
+}
+
+func (bdb *bookmarkDatabase) UploadBookmarkFavicon(bookmarkID uint, icon domain.Icon) error {
+	return bdb.DB.Model(&domain.Bookmark{}).Where("id = ?", bookmarkID).Updates(map[string]any{
+		"icon_hash":      icon.Hash,
+		"icon_url":       domain.IconPath(icon.Hash),
+		"dominant_color": icon.DominantColor,
+		"accent_color":   icon.AccentColor,
+	}).Error
+}
+
+func (bdb *bookmarkDatabase) UpdateBookmarkMetadata(bookmarkID uint, metadata domain.Bookmark, placeholderTitle string) error {
+	updates := map[string]any{
+		"description":   metadata.Description,
+		"site_name":     metadata.SiteName,
+		"canonical_url": metadata.CanonicalURL,
+		"image_url":     metadata.ImageURL,
+	}
+	if placeholderTitle != "" && metadata.Title != "" {
+		updates["title"] = gorm.Expr("CASE WHEN title = ? THEN ? ELSE title END", placeholderTitle, metadata.Title)
+	}
+	return bdb.DB.Model(&domain.Bookmark{}).Where("id = ?", bookmarkID).Updates(updates).Error
+}
+
+func (bdb *bookmarkDatabase) CopyIconColors() (int64, error) {
+	result := bdb.DB.Exec(`UPDATE bookmarks SET dominant_color = icons.dominant_color, accent_color = icons.accent_color
+		FROM icons
+		WHERE icons.hash = bookmarks.icon_hash
+		AND (bookmarks.dominant_color IS DISTINCT FROM icons.dominant_color OR bookmarks.accent_color IS DISTINCT FROM icons.accent_color)`)
+	return result.RowsAffected, result.Error
+}
+
+func (bdb *bookmarkDatabase) GetBookmarksWithoutFavicon() ([]domain.Bookmark, error) {
+	generated := bdb.DB.Model(&domain.Icon{}).Select("hash").Where("generated = ?", true)
+	var results []domain.Bookmark
+	err := bdb.DB.Model(&domain.Bookmark{}).
+		Where("icon_hash = '' OR icon_hash IS NULL OR icon_hash IN (?)", generated).
+		Order("url, id").Find(&results).Error
+	return results, err
+}
+
+func (bdb *bookmarkDatabase) GetDeletedBookmarks(userID uint) ([]domain.Bookmark, error) {
+	var results []domain.Bookmark
+	err := bdb.DB.Unscoped().Model(&domain.Bookmark{}).Preload("Tags", preloadTagsOrder).
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).Order("deleted_at DESC, id").Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (bdb *bookmarkDatabase) GetDeletedBookmarkByID(bookmarkID uint) (domain.Bookmark, error) {
+	var bookmark domain.Bookmark
+	err := bdb.DB.Unscoped().Model(&domain.Bookmark{}).
+		Where("id = ? AND deleted_at IS NOT NULL", bookmarkID).First(&bookmark).Error
+	return bookmark, err
+}
+
+// RestoreBookmark takes a bookmark out of the trash and places it into
+// folderID, nil meaning the root level. It returns gorm.ErrRecordNotFound
+// when the bookmark is not in the trash.
+func (bdb *bookmarkDatabase) RestoreBookmark(bookmarkID uint, folderID *uint) error {
+	result := bdb.DB.Unscoped().Model(&domain.Bookmark{}).Where("id = ? AND deleted_at IS NOT NULL", bookmarkID).
+		Updates(map[string]any{"deleted_at": nil, "folder_id": folderID})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return duplicateURLError(result.Error)
+}
+
+func (bdb *bookmarkDatabase) GetBookmarkByNormalizedURL(userID uint, normalizedURL string) (domain.Bookmark, error) {
+	var bookmark domain.Bookmark
+	err := bdb.DB.Model(&domain.Bookmark{}).
+		Where("user_id = ? AND normalized_url = ?", userID, normalizedURL).First(&bookmark).Error
+	return bookmark, err
+}
+
+func (bdb *bookmarkDatabase) GetAllBookmarks() ([]domain.Bookmark, error) {
+	var results []domain.Bookmark
+	err := bdb.DB.Unscoped().Model(&domain.Bookmark{}).Order("id").Find(&results).Error
+	return results, err
+}
+
+func (bdb *bookmarkDatabase) SetNormalizedURL(bookmarkID uint, normalizedURL string) error {
+	err := bdb.DB.Unscoped().Model(&domain.Bookmark{}).Where("id = ?", bookmarkID).Update("normalized_url", normalizedURL).Error
+	return duplicateURLError(err)
+}
+
+func (bdb *bookmarkDatabase) MergeBookmarks(keepID uint, mergeIDs []uint, normalizedURL string) error {
+	return bdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO bookmark_tags (bookmark_id, tag_id)
+			SELECT ?, tag_id FROM bookmark_tags WHERE bookmark_id IN ?
+			ON CONFLICT DO NOTHING`, keepID, mergeIDs).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&domain.Bookmark{}).Where("id IN ?", mergeIDs).Delete(&domain.Bookmark{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(mergeIDs)) {
+			return gorm.ErrRecordNotFound
+		}
+		return duplicateURLError(tx.Model(&domain.Bookmark{}).Where("id = ?", keepID).Update("normalized_url", normalizedURL).Error)
+	})
+}
+
+// EmptyTrash permanently removes every trashed bookmark of the user.
+func (bdb *bookmarkDatabase) EmptyTrash(userID uint) (int64, error) {
+	return bdb.purgeBookmarks("user_id = ? AND deleted_at IS NOT NULL", userID)
+}
+
+// PurgeDeletedBookmarks permanently removes bookmarks that were moved to the
+// trash before deletedBefore.
+func (bdb *bookmarkDatabase) PurgeDeletedBookmarks(deletedBefore time.Time) (int64, error) {
+	return bdb.purgeBookmarks("deleted_at < ?", deletedBefore)
+}
+
+func (bdb *bookmarkDatabase) purgeBookmarks(condition string, args ...any) (int64, error) {
+	var purged int64
+	err := bdb.DB.Transaction(func(tx *gorm.DB) error {
+		trashed := tx.Unscoped().Model(&domain.Bookmark{}).Select("id").Where(condition, args...)
+		if err := tx.Exec("DELETE FROM bookmark_tags WHERE bookmark_id IN (?)", trashed).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where(condition, args...).Delete(&domain.Bookmark{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+func (bdb *bookmarkDatabase) GetURLsToCheck(checkedBefore time.Time, limit int) ([]string, error) {
+	queued := bdb.DB.Model(&domain.Job{}).Select("1").
+		Where("kind = ? AND status IN ? AND payload::jsonb ->> 'url' = bookmarks.url", domain.JobCheckLink, []string{domain.JobQueued, domain.JobRunning})
+	var urls []string
+	err := bdb.DB.Model(&domain.Bookmark{}).
+		Where("(url ILIKE 'http://%' OR url ILIKE 'https://%') AND NOT EXISTS (?)", queued).
+		Group("url").
+		Having("MIN(COALESCE(link_checked_at, '-infinity')) < ?", checkedBefore).
+		Order("MIN(COALESCE(link_checked_at, '-infinity')), url").
+		Limit(limit).Pluck("url", &urls).Error
+	return urls, err
+}
+
+func (bdb *bookmarkDatabase) UpdateLinkHealth(resourceURL string, health domain.Bookmark) error {
+	return bdb.DB.Unscoped().Model(&domain.Bookmark{}).Where("url = ?", resourceURL).Updates(map[string]any{
+		"link_status":      health.LinkStatus,
+		"link_status_code": health.LinkStatusCode,
+		"link_final_url":   health.LinkFinalURL,
+		"link_checked_at":  health.LinkCheckedAt,
+	}).Error
+}
+
+func (bdb *bookmarkDatabase) ApplyRedirect(bookmarkID uint, resourceURL, normalizedURL string) error {
+	err := bdb.DB.Model(&domain.Bookmark{}).Where("id = ?", bookmarkID).Updates(map[string]any{
+		"url":              resourceURL,
+		"normalized_url":   normalizedURL,
+		"link_status":      "",
+		"link_status_code": 0,
+		"link_final_url":   "",
+		"link_checked_at":  nil,
+	}).Error
+	return duplicateURLError(err)
 }
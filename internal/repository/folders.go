@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type FolderRepository interface {
+	CreateFolder(folder *domain.Folder) error
+	GetFoldersByUser(userID uint) ([]domain.Folder, error)
+	GetFolderByID(folderID uint) (domain.Folder, error)
+	UpdateFolder(folder *domain.Folder) error
+	DeleteFolderByID(folderID uint) error
+	MoveFolderContents(folderID uint, parentID *uint) error
+	DeleteFoldersWithBookmarks(folderIDs []uint) (int64, error)
+	CountFoldersByUser(userID uint) (int64, error)
+}
+
+type folderDatabase struct {
+	DB *gorm.DB
+}
+
+func NewFolderRepository(DB *gorm.DB) FolderRepository {
+	return &folderDatabase{DB}
+}
+
+func (fdb *folderDatabase) CreateFolder(folder *domain.Folder) error {
+	return fdb.DB.Model(&domain.Folder{}).Create(folder).Error
+}
+
+func (fdb *folderDatabase) GetFoldersByUser(userID uint) ([]domain.Folder, error) {
+	var folders []domain.Folder
+	err := fdb.DB.Model(&domain.Folder{}).Where("user_id = ?", userID).Order("position, id").Find(&folders).Error
+	if err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (fdb *folderDatabase) GetFolderByID(folderID uint) (domain.Folder, error) {
+	var folder domain.Folder
+	err := fdb.DB.Model(&domain.Folder{}).Where("id = ?", folderID).First(&folder).Error
+	return folder, err
+}
+
+func (fdb *folderDatabase) UpdateFolder(folder *domain.Folder) error {
+	return fdb.DB.Model(&domain.Folder{}).Where("id = ?", folder.ID).Save(folder).Error
+}
+
+func (fdb *folderDatabase) DeleteFolderByID(folderID uint) error {
+	return fdb.DB.Model(&domain.Folder{}).Where("id = ?", folderID).Delete(&domain.Folder{}).Error
+}
+
+// MoveFolderContents re-parents the subfolders and bookmarks of a folder to
+// parentID (nil means the root level) and removes the folder itself.
+func (fdb *folderDatabase) MoveFolderContents(folderID uint, parentID *uint) error {
+	return fdb.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Folder{}).Where("parent_id = ?", folderID).Update("parent_id", parentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&domain.Bookmark{}).Where("folder_id = ?", folderID).Update("folder_id", parentID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.Folder{}).Where("id = ?", folderID).Delete(&domain.Folder{}).Error
+	})
+}
+
+// DeleteFoldersWithBookmarks removes the given folders and moves every
+// bookmark inside them to the trash. It returns the number of trashed
+// bookmarks.
+func (fdb *folderDatabase) DeleteFoldersWithBookmarks(folderIDs []uint) (int64, error) {
+	var deleted int64
+	err := fdb.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Bookmark{}).Where("folder_id IN ?", folderIDs).Delete(&domain.Bookmark{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return tx.Model(&domain.Folder{}).Where("id IN ?", folderIDs).Delete(&domain.Folder{}).Error
+	})
+	return deleted, err
+}
+
+func (fdb *folderDatabase) CountFoldersByUser(userID uint) (int64, error) {
+	var count int64
+	err := fdb.DB.Model(&domain.Folder{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
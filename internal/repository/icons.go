@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IconRepository interface {
+	GetIcon(hash string) (domain.Icon, error)
+	// CreateIcon saves the icon unless one with the same hash exists.
+	CreateIcon(icon *domain.Icon) error
+	GetIconsWithoutColors() ([]domain.Icon, error)
+	SetIconColors(hash, dominant, accent string) error
+}
+
+type iconDatabase struct {
+	DB *gorm.DB
+}
+
+func NewIconRepository(DB *gorm.DB) IconRepository {
+	return &iconDatabase{DB}
+}
+
+func (idb *iconDatabase) GetIcon(hash string) (domain.Icon, error) {
+	var icon domain.Icon
+	err := idb.DB.Model(&domain.Icon{}).Where("hash = ?", hash).First(&icon).Error
+	return icon, err
+}
+
+func (idb *iconDatabase) CreateIcon(icon *domain.Icon) error {
+	return idb.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(icon).Error
+}
+
+func (idb *iconDatabase) GetIconsWithoutColors() ([]domain.Icon, error) {
+	var icons []domain.Icon
+	err := idb.DB.Model(&domain.Icon{}).Where("dominant_color = '' OR dominant_color IS NULL").Order("hash").Find(&icons).Error
+	return icons, err
+}
+
+func (idb *iconDatabase) SetIconColors(hash, dominant, accent string) error {
+	return idb.DB.Model(&domain.Icon{}).Where("hash = ?", hash).
+		Updates(map[string]any{"dominant_color": dominant, "accent_color": accent}).Error
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository interface {
+	EnqueueJob(job *domain.Job) error
+	// ClaimJob marks the oldest due job of one of kinds as running and
+	// returns it, skipping jobs for skipHosts and jobs claimed by other
+	// workers. It returns gorm.ErrRecordNotFound when no job is due.
+	ClaimJob(kinds []string, skipHosts []string) (domain.Job, error)
+	CompleteJob(jobID uint) error
+	// RetryJob queues a failed job again at runAt.
+	RetryJob(jobID uint, runAt time.Time, lastError string) error
+	// ReleaseJob queues a claimed job again at runAt without counting the
+	// attempt, for jobs that were put off or interrupted.
+	ReleaseJob(jobID uint, runAt time.Time) error
+	KillJob(jobID uint, lastError string) error
+	// RequeueStaleJobs queues again the jobs still running since before
+	// lockedBefore, whose worker must have died.
+	RequeueStaleJobs(lockedBefore time.Time) (int64, error)
+	// RetryDeadJobs queues dead jobs of kind again, of every kind when
+	// kind is empty, with fresh attempts.
+	RetryDeadJobs(kind string) (int64, error)
+	DeleteDoneJobs(doneBefore time.Time) (int64, error)
+}
+
+type jobDatabase struct {
+	DB *gorm.DB
+}
+
+func NewJobRepository(DB *gorm.DB) JobRepository {
+	return &jobDatabase{DB}
+}
+
+func (jdb *jobDatabase) EnqueueJob(job *domain.Job) error {
+	return jdb.DB.Create(job).Error
+}
+
+// ClaimJob picks and updates the job in one statement. FOR UPDATE SKIP
+// LOCKED lets concurrent workers, in this process or another, each take
+// a different job instead of waiting for each other.
+func (jdb *jobDatabase) ClaimJob(kinds []string, skipHosts []string) (domain.Job, error) {
+	due := jdb.DB.Model(&domain.Job{}).Select("id").
+		Where("status = ? AND run_at <= now() AND kind IN ?", domain.JobQueued, kinds)
+	if len(skipHosts) > 0 {
+		due = due.Where("host NOT IN ?", skipHosts)
+	}
+	due = due.Order("run_at, id").Limit(1).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var jobs []domain.Job
+	result := jdb.DB.Model(&jobs).Clauses(clause.Returning{}).Where("id = (?)", due).Updates(map[string]any{
+		"status":     domain.JobRunning,
+		"attempts":   gorm.Expr("attempts + 1"),
+		"locked_at":  gorm.Expr("now()"),
+		"updated_at": gorm.Expr("now()"),
+	})
+	if result.Error != nil {
+		return domain.Job{}, result.Error
+	}
+	if len(jobs) == 0 {
+		return domain.Job{}, gorm.ErrRecordNotFound
+	}
+	return jobs[0], nil
+}
+
+func (jdb *jobDatabase) CompleteJob(jobID uint) error {
+	return jdb.DB.Model(&domain.Job{}).Where("id = ?", jobID).Updates(map[string]any{
+		"status":     domain.JobDone,
+		"last_error": "",
+		"locked_at":  nil,
+	}).Error
+}
+
+func (jdb *jobDatabase) RetryJob(jobID uint, runAt time.Time, lastError string) error {
+	return jdb.DB.Model(&domain.Job{}).Where("id = ?", jobID).Updates(map[string]any{
+		"status":     domain.JobQueued,
+		"run_at":     runAt,
+		"last_error": lastError,
+		"locked_at":  nil,
+	}).Error
+}
+
+func (jdb *jobDatabase) ReleaseJob(jobID uint, runAt time.Time) error {
+	return jdb.DB.Model(&domain.Job{}).Where("id = ?", jobID).Updates(map[string]any{
+		"status":    domain.JobQueued,
+		"run_at":    runAt,
+		"attempts":  gorm.Expr("GREATEST(attempts - 1, 0)"),
+		"locked_at": nil,
+	}).Error
+}
+
+func (jdb *jobDatabase) KillJob(jobID uint, lastError string) error {
+	return jdb.DB.Model(&domain.Job{}).Where("id = ?", jobID).Updates(map[string]any{
+		"status":     domain.JobDead,
+		"last_error": lastError,
+		"locked_at":  nil,
+	}).Error
+}
+
+func (jdb *jobDatabase) RequeueStaleJobs(lockedBefore time.Time) (int64, error) {
+	result := jdb.DB.Model(&domain.Job{}).Where("status = ? AND locked_at < ?", domain.JobRunning, lockedBefore).
+		Updates(map[string]any{
+			"status":     domain.JobQueued,
+			"run_at":     gorm.Expr("now()"),
+			"last_error": "worker stopped while running the job",
+			"locked_at":  nil,
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (jdb *jobDatabase) RetryDeadJobs(kind string) (int64, error) {
+	query := jdb.DB.Model(&domain.Job{}).Where("status = ?", domain.JobDead)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	result := query.Updates(map[string]any{
+		"status":   domain.JobQueued,
+		"run_at":   gorm.Expr("now()"),
+		"attempts": 0,
+	})
+	return result.RowsAffected, result.Error
+}
+
+func (jdb *jobDatabase) DeleteDoneJobs(doneBefore time.Time) (int64, error) {
+	result := jdb.DB.Where("status = ? AND updated_at < ?", domain.JobDone, doneBefore).Delete(&domain.Job{})
+	return result.RowsAffected, result.Error
+}
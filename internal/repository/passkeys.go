@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	domain "github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrDuplicatePasskey is returned when a passkey is registered twice.
+var ErrDuplicatePasskey = errors.New("passkey already registered")
+
+type PasskeyRepository interface {
+	// CreatePasskey returns ErrDuplicatePasskey when the credential is
+	// already registered.
+	CreatePasskey(passkey *domain.Passkey) error
+	GetPasskeysByUser(userID uint) ([]domain.Passkey, error)
+	GetPasskeyByCredentialID(credentialID []byte) (domain.Passkey, error)
+	CountPasskeys(userID uint) (int64, error)
+	// RenamePasskey and DeletePasskey return gorm.ErrRecordNotFound when
+	// the user has no such passkey.
+	RenamePasskey(userID, passkeyID uint, name string) error
+	DeletePasskey(userID, passkeyID uint) error
+	// RecordPasskeyUse stores the state of the authenticator after a login.
+	RecordPasskeyUse(passkey domain.Passkey, usedAt time.Time) error
+
+	CreateCeremony(ceremony *domain.PasskeyCeremony) error
+	// TakeCeremony removes the unexpired ceremony of the kind and returns
+	// it, so each is finished at most once. It returns
+	// gorm.ErrRecordNotFound when there is none.
+	TakeCeremony(ceremonyID, kind string, now time.Time) (domain.PasskeyCeremony, error)
+	DeleteExpiredCeremonies(now time.Time) (int64, error)
+}
+
+type passkeyDatabase struct {
+	DB *gorm.DB
+}
+
+func NewPasskeyRepository(DB *gorm.DB) PasskeyRepository {
+	return &passkeyDatabase{DB}
+}
+
+func (pdb *passkeyDatabase) CreatePasskey(passkey *domain.Passkey) error {
+	err := pdb.DB.Create(passkey).Error
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicatePasskey
+	}
+	return err
+}
+
+func (pdb *passkeyDatabase) GetPasskeysByUser(userID uint) ([]domain.Passkey, error) {
+	var passkeys []domain.Passkey
+	err := pdb.DB.Where("user_id = ?", userID).Order("created_at, id").Find(&passkeys).Error
+	return passkeys, err
+}
+
+func (pdb *passkeyDatabase) GetPasskeyByCredentialID(credentialID []byte) (domain.Passkey, error) {
+	var passkey domain.Passkey
+	err := pdb.DB.Where("credential_id = ?", credentialID).First(&passkey).Error
+	return passkey, err
+}
+
+func (pdb *passkeyDatabase) CountPasskeys(userID uint) (int64, error) {
+	var count int64
+	err := pdb.DB.Model(&domain.Passkey{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (pdb *passkeyDatabase) RenamePasskey(userID, passkeyID uint, name string) error {
+	result := pdb.DB.Model(&domain.Passkey{}).Where("id = ? AND user_id = ?", passkeyID, userID).UpdateColumn("name", name)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func (pdb *passkeyDatabase) DeletePasskey(userID, passkeyID uint) error {
+	result := pdb.DB.Where("id = ? AND user_id = ?", passkeyID, userID).Delete(&domain.Passkey{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func (pdb *passkeyDatabase) RecordPasskeyUse(passkey domain.Passkey, usedAt time.Time) error {
+	return pdb.DB.Model(&domain.Passkey{}).Where("id = ?", passkey.ID).UpdateColumns(map[string]any{
+		"sign_count":    passkey.SignCount,
+		"clone_warning": passkey.CloneWarning,
+		"user_verified": passkey.UserVerified,
+		"backed_up":     passkey.BackedUp,
+		"last_used_at":  usedAt,
+	}).Error
+}
+
+func (pdb *passkeyDatabase) CreateCeremony(ceremony *domain.PasskeyCeremony) error {
+	return pdb.DB.Create(ceremony).Error
+}
+
+func (pdb *passkeyDatabase) TakeCeremony(ceremonyID, kind string, now time.Time) (domain.PasskeyCeremony, error) {
+	var ceremonies []domain.PasskeyCeremony
+	err := pdb.DB.Clauses(clause.Returning{}).Where("id = ? AND kind = ? AND expires_at > ?", ceremonyID, kind, now).
+		Delete(&ceremonies).Error
+	if err == nil && len(ceremonies) == 0 {
+		return domain.PasskeyCeremony{}, gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return domain.PasskeyCeremony{}, err
+	}
+	return ceremonies[0], nil
+}
+
+func (pdb *passkeyDatabase) DeleteExpiredCeremonies(now time.Time) (int64, error) {
+	result := pdb.DB.Where("expires_at <= ?", now).Delete(&domain.PasskeyCeremony{})
+	return result.RowsAffected, result.Error
+}
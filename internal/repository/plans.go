@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type PlanRepository interface {
+	GetPlans() ([]domain.Plan, error)
+	GetPlanByID(planID uint) (domain.Plan, error)
+	GetPlanByName(name string) (domain.Plan, error)
+}
+
+type planDatabase struct {
+	DB *gorm.DB
+}
+
+func NewPlanRepository(DB *gorm.DB) PlanRepository {
+	return &planDatabase{DB}
+}
+
+func (pdb *planDatabase) GetPlans() ([]domain.Plan, error) {
+	var plans []domain.Plan
+	err := pdb.DB.Model(&domain.Plan{}).Order("id").Find(&plans).Error
+	return plans, err
+}
+
+func (pdb *planDatabase) GetPlanByID(planID uint) (domain.Plan, error) {
+	var plan domain.Plan
+	err := pdb.DB.Model(&domain.Plan{}).Where("id = ?", planID).First(&plan).Error
+	return plan, err
+}
+
+func (pdb *planDatabase) GetPlanByName(name string) (domain.Plan, error) {
+	var plan domain.Plan
+	err := pdb.DB.Model(&domain.Plan{}).Where("name = ?", name).First(&plan).Error
+	return plan, err
+}
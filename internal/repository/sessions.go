@@ -8,11 +8,27 @@ import (
 )
 
 type SessionRepository interface {
-	CreateSession(sessionID string, userID uint, expiresAt time.Time) error
+	CreateSession(session *domain.Session) error
 	GetSessionByID(sessionID string) (domain.Session, error)
 	DeleteSessionByID(sessionID string) error
 	DeleteAllSessions(userID uint) error
 	GetAllSessions() ([]domain.Session, error)
+	// GetSessionsByUser returns the user's unexpired sessions, most
+	// recently used first.
+	GetSessionsByUser(userID uint) ([]domain.Session, error)
+	// DeleteUserSession ends the user's session with the handle. It
+	// returns gorm.ErrRecordNotFound when the user has no such session.
+	DeleteUserSession(userID uint, handle string) error
+	// TouchSession records use of the session at seenAt, extending it to
+	// expiresAt.
+	TouchSession(sessionID string, seenAt, expiresAt time.Time) error
+	// GetSessionByPreviousID returns the session whose ID was previousID
+	// before a rotation after rotatedAfter.
+	GetSessionByPreviousID(previousID string, rotatedAfter time.Time) (domain.Session, error)
+	// RotateSession replaces the session's ID, keeping the old one as its
+	// previous ID. It returns gorm.ErrRecordNotFound when the session no
+	// longer has oldID, such as after a concurrent rotation.
+	RotateSession(oldID, newID string, rotatedAt time.Time) error
 }
 
 type sessionDatabase struct {
@@ -23,13 +39,8 @@ func NewSessionRepository(DB *gorm.DB) SessionRepository {
 	return &sessionDatabase{DB}
 }
 
-func (sdb *sessionDatabase) CreateSession(sessionID string, userID uint, expiresAt time.Time) error {
-	session := &domain.Session{
-		ID:        sessionID,
-		UserID:    userID,
-		ExpiresAt: expiresAt,
-	}
-	return sdb.DB.Model(&domain.Session{}).Create(&session).Error
+func (sdb *sessionDatabase) CreateSession(session *domain.Session) error {
+	return sdb.DB.Model(&domain.Session{}).Create(session).Error
 }
 
 func (sdb *sessionDatabase) GetSessionByID(sessionID string) (domain.Session, error) {
@@ -51,3 +62,43 @@ func (db *sessionDatabase) GetAllSessions() ([]domain.Session, error) {
 	err := db.DB.Model(&domain.Session{}).Find(&sessions).Error
 	return sessions, err
 }
+
+func (sdb *sessionDatabase) GetSessionsByUser(userID uint) ([]domain.Session, error) {
+	var sessions []domain.Session
+	err := sdb.DB.Model(&domain.Session{}).Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC, created_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (sdb *sessionDatabase) DeleteUserSession(userID uint, handle string) error {
+	result := sdb.DB.Where("user_id = ? AND handle = ?", userID, handle).Delete(&domain.Session{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func (sdb *sessionDatabase) TouchSession(sessionID string, seenAt, expiresAt time.Time) error {
+	return sdb.DB.Model(&domain.Session{}).Where("id = ?", sessionID).UpdateColumns(map[string]any{
+		"last_seen_at": seenAt,
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+func (sdb *sessionDatabase) GetSessionByPreviousID(previousID string, rotatedAfter time.Time) (domain.Session, error) {
+	var session domain.Session
+	err := sdb.DB.Model(&domain.Session{}).Where("previous_id = ? AND rotated_at > ?", previousID, rotatedAfter).First(&session).Error
+	return session, err
+}
+
+func (sdb *sessionDatabase) RotateSession(oldID, newID string, rotatedAt time.Time) error {
+	result := sdb.DB.Model(&domain.Session{}).Where("id = ?", oldID).UpdateColumns(map[string]any{
+		"id":          newID,
+		"previous_id": oldID,
+		"rotated_at":  rotatedAt,
+	})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type TagRepository interface {
+	CreateTag(tag *domain.Tag) error
+	GetTagsByUser(userID uint) ([]domain.Tag, error)
+	GetTagByID(tagID uint) (domain.Tag, error)
+	GetTagsByIDs(tagIDs []uint) ([]domain.Tag, error)
+	GetTagByName(userID uint, name string) (domain.Tag, error)
+	UpdateTag(tag *domain.Tag) error
+	DeleteTagByID(tagID uint) error
+	MergeTags(sourceIDs []uint, targetID uint) error
+	CountTagsByUser(userID uint) (int64, error)
+}
+
+type tagDatabase struct {
+	DB *gorm.DB
+}
+
+func NewTagRepository(DB *gorm.DB) TagRepository {
+	return &tagDatabase{DB}
+}
+
+func (tdb *tagDatabase) CreateTag(tag *domain.Tag) error {
+	return tdb.DB.Model(&domain.Tag{}).Create(tag).Error
+}
+
+// GetTagsByUser returns the user's tags with BookmarkCount filled in.
+// Bookmarks in the trash are not counted.
+func (tdb *tagDatabase) GetTagsByUser(userID uint) ([]domain.Tag, error) {
+	var tags []domain.Tag
+	err := tdb.DB.Model(&domain.Tag{}).
+		Select("tags.*, COUNT(bookmarks.id) AS bookmark_count").
+		Joins("LEFT JOIN bookmark_tags ON bookmark_tags.tag_id = tags.id").
+		Joins("LEFT JOIN bookmarks ON bookmarks.id = bookmark_tags.bookmark_id AND bookmarks.deleted_at IS NULL").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id").
+		Order("tags.name").
+		Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (tdb *tagDatabase) GetTagByID(tagID uint) (domain.Tag, error) {
+	var tag domain.Tag
+	err := tdb.DB.Model(&domain.Tag{}).Where("id = ?", tagID).First(&tag).Error
+	return tag, err
+}
+
+func (tdb *tagDatabase) GetTagsByIDs(tagIDs []uint) ([]domain.Tag, error) {
+	var tags []domain.Tag
+	err := tdb.DB.Model(&domain.Tag{}).Where("id IN ?", tagIDs).Find(&tags).Error
+	return tags, err
+}
+
+func (tdb *tagDatabase) GetTagByName(userID uint, name string) (domain.Tag, error) {
+	var tag domain.Tag
+	err := tdb.DB.Model(&domain.Tag{}).Where("user_id = ? AND name = ?", userID, name).First(&tag).Error
+	return tag, err
+}
+
+func (tdb *tagDatabase) UpdateTag(tag *domain.Tag) error {
+	return tdb.DB.Model(&domain.Tag{}).Where("id = ?", tag.ID).Update("name", tag.Name).Error
+}
+
+func (tdb *tagDatabase) DeleteTagByID(tagID uint) error {
+	return tdb.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM bookmark_tags WHERE tag_id = ?", tagID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.Tag{}).Where("id = ?", tagID).Delete(&domain.Tag{}).Error
+	})
+}
+
+// MergeTags moves every bookmark tagged with one of sourceIDs to targetID
+// and removes the source tags.
+func (tdb *tagDatabase) MergeTags(sourceIDs []uint, targetID uint) error {
+	return tdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO bookmark_tags (bookmark_id, tag_id)
+			SELECT DISTINCT bookmark_id, ? FROM bookmark_tags WHERE tag_id IN ?
+			ON CONFLICT DO NOTHING`, targetID, sourceIDs).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM bookmark_tags WHERE tag_id IN ?", sourceIDs).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.Tag{}).Where("id IN ?", sourceIDs).Delete(&domain.Tag{}).Error
+	})
+}
+
+func (tdb *tagDatabase) CountTagsByUser(userID uint) (int64, error) {
+	var count int64
+	err := tdb.DB.Model(&domain.Tag{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
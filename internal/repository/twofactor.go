@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"time"
+
+	domain "github.com/OxytocinGroup/theca-backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TwoFactorRepository interface {
+	// SetTOTPSecret stores the secret of an enrollment that is not
+	// confirmed yet.
+	SetTOTPSecret(userID uint, secret string) error
+	// EnableTOTP turns TOTP on, with the code of step used, and replaces
+	// the user's recovery codes.
+	EnableTOTP(userID uint, step int64, recoveryCodes []domain.RecoveryCode) error
+	// DisableTOTP turns TOTP off and removes the secret and recovery codes.
+	DisableTOTP(userID uint) error
+	// UseTOTPStep records that the code of step was used. It returns
+	// gorm.ErrRecordNotFound when that step or a later one already was, so
+	// a code cannot be replayed.
+	UseTOTPStep(userID uint, step int64) error
+	// UseRecoveryCode marks the user's unused recovery code with the hash
+	// as used. It returns gorm.ErrRecordNotFound when there is none.
+	UseRecoveryCode(userID uint, codeHash string) error
+
+	CreateChallenge(challenge *domain.LoginChallenge) error
+	// GetChallenge returns the challenge unless it expired or has had
+	// maxAttempts.
+	GetChallenge(challengeID string, maxAttempts int, now time.Time) (domain.LoginChallenge, error)
+	SetChallengePasskeySession(challengeID string, session []byte) error
+	// UseChallengeAttempt counts an attempt at the unexpired challenge. It
+	// returns gorm.ErrRecordNotFound when the challenge does not exist, has
+	// expired or has had maxAttempts.
+	UseChallengeAttempt(challengeID string, maxAttempts int, now time.Time) (domain.LoginChallenge, error)
+	DeleteChallenge(challengeID string) error
+	DeleteExpiredChallenges(now time.Time) (int64, error)
+}
+
+type twoFactorDatabase struct {
+	DB *gorm.DB
+}
+
+func NewTwoFactorRepository(DB *gorm.DB) TwoFactorRepository {
+	return &twoFactorDatabase{DB}
+}
+
+func (tdb *twoFactorDatabase) SetTOTPSecret(userID uint, secret string) error {
+	return tdb.DB.Model(&domain.User{}).Where("id = ?", userID).UpdateColumn("totp_secret", secret).Error
+}
+
+func (tdb *twoFactorDatabase) EnableTOTP(userID uint, step int64, recoveryCodes []domain.RecoveryCode) error {
+	return tdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.User{}).Where("id = ?", userID).UpdateColumns(map[string]any{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&recoveryCodes).Error
+	})
+}
+
+func (tdb *twoFactorDatabase) DisableTOTP(userID uint) error {
+	return tdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.User{}).Where("id = ?", userID).UpdateColumns(map[string]any{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error
+	})
+}
+
+func (tdb *twoFactorDatabase) UseTOTPStep(userID uint, step int64) error {
+	result := tdb.DB.Model(&domain.User{}).Where("id = ? AND totp_last_step < ?", userID, step).UpdateColumn("totp_last_step", step)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func (tdb *twoFactorDatabase) UseRecoveryCode(userID uint, codeHash string) error {
+	result := tdb.DB.Model(&domain.RecoveryCode{}).Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		UpdateColumn("used_at", time.Now())
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func (tdb *twoFactorDatabase) CreateChallenge(challenge *domain.LoginChallenge) error {
+	return tdb.DB.Create(challenge).Error
+}
+
+func (tdb *twoFactorDatabase) GetChallenge(challengeID string, maxAttempts int, now time.Time) (domain.LoginChallenge, error) {
+	var challenge domain.LoginChallenge
+	err := tdb.DB.Where("id = ? AND attempts < ? AND expires_at > ?", challengeID, maxAttempts, now).First(&challenge).Error
+	return challenge, err
+}
+
+func (tdb *twoFactorDatabase) SetChallengePasskeySession(challengeID string, session []byte) error {
+	return tdb.DB.Model(&domain.LoginChallenge{}).Where("id = ?", challengeID).UpdateColumn("passkey_session", session).Error
+}
+
+func (tdb *twoFactorDatabase) UseChallengeAttempt(challengeID string, maxAttempts int, now time.Time) (domain.LoginChallenge, error) {
+	var challenge domain.LoginChallenge
+	result := tdb.DB.Model(&challenge).Clauses(clause.Returning{}).
+		Where("id = ? AND attempts < ? AND expires_at > ?", challengeID, maxAttempts, now).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if result.Error == nil && result.RowsAffected == 0 {
+		return challenge, gorm.ErrRecordNotFound
+	}
+	return challenge, result.Error
+}
+
+func (tdb *twoFactorDatabase) DeleteChallenge(challengeID string) error {
+	return tdb.DB.Where("id = ?", challengeID).Delete(&domain.LoginChallenge{}).Error
+}
+
+func (tdb *twoFactorDatabase) DeleteExpiredChallenges(now time.Time) (int64, error) {
+	result := tdb.DB.Where("expires_at <= ?", now).Delete(&domain.LoginChallenge{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,38 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Repositories are the repositories bound to a single transaction.
+type Repositories struct {
+	Users     UserRepository
+	Bookmarks BookmarkRepository
+	Folders   FolderRepository
+	Tags      TagRepository
+	Plans     PlanRepository
+}
+
+// UnitOfWork runs several repository calls as one transaction: fn's changes
+// are committed when it returns nil and rolled back otherwise.
+type UnitOfWork interface {
+	Do(fn func(repos Repositories) error) error
+}
+
+type unitOfWork struct {
+	DB *gorm.DB
+}
+
+func NewUnitOfWork(DB *gorm.DB) UnitOfWork {
+	return &unitOfWork{DB}
+}
+
+func (uow *unitOfWork) Do(fn func(repos Repositories) error) error {
+	return uow.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(Repositories{
+			Users:     NewUserRepository(tx),
+			Bookmarks: NewBookmarkRepository(tx),
+			Folders:   NewFolderRepository(tx),
+			Tags:      NewTagRepository(tx),
+			Plans:     NewPlanRepository(tx),
+		})
+	})
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	domain "github.com/OxytocinGroup/theca-backend/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
@@ -16,6 +17,10 @@ type UserRepository interface {
 	CheckVerificationStatus(userID uint) (bool, error)
 	GetByToken(token string) (domain.User, error)
 	GetByVerificationCode(code string) (domain.User, error)
+	GetByIDForUpdate(id uint) (domain.User, error)
+	AdjustBookmarkCount(userID uint, delta int) error
+	RepairBookmarkCounters() (int64, error)
+	SetPlan(userID uint, planID *uint) error
 }
 
 type userDatabase struct {
@@ -54,8 +59,13 @@ func (udb *userDatabase) UsernameExists(username string) (bool, error) {
 	return count > 0, err
 }
 
+// Update saves the user. The bookmark counter, plan and two-factor state are
+// left out: they only change through AdjustBookmarkCount, SetPlan and the
+// TwoFactorRepository, and a user loaded before such a change must not
+// write it back.
 func (udb *userDatabase) Update(user *domain.User) error {
-	return udb.DB.Model(&domain.User{}).Where("id = ?", user.ID).Save(user).Error
+	return udb.DB.Model(&domain.User{}).Where("id = ?", user.ID).
+		Omit("amount_of_bookmarks", "plan_id", "totp_secret", "totp_enabled", "totp_last_step").Save(user).Error
 }
 
 func (udb *userDatabase) GetByID(id uint) (domain.User, error) {
@@ -81,3 +91,33 @@ func (udb *userDatabase) GetByVerificationCode(code string) (domain.User, error)
 	err := udb.DB.Model(&domain.User{}).Where("verification_code = ?", code).First(&user).Error
 	return user, err
 }
+
+// GetByIDForUpdate reads the user and locks the row until the surrounding
+// transaction ends, so concurrent quota checks for the user run one by one.
+func (udb *userDatabase) GetByIDForUpdate(id uint) (domain.User, error) {
+	var user domain.User
+	err := udb.DB.Model(&domain.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&user).Error
+	return user, err
+}
+
+func (udb *userDatabase) AdjustBookmarkCount(userID uint, delta int) error {
+	return udb.DB.Model(&domain.User{}).Where("id = ?", userID).
+		Update("amount_of_bookmarks", gorm.Expr("GREATEST(amount_of_bookmarks + ?, 0)", delta)).Error
+}
+
+// RepairBookmarkCounters recomputes every user's bookmark counter from the
+// bookmarks outside the trash and returns the number of corrected users.
+func (udb *userDatabase) RepairBookmarkCounters() (int64, error) {
+	result := udb.DB.Exec(`UPDATE users SET amount_of_bookmarks = counts.total
+		FROM (
+			SELECT users.id, COUNT(bookmarks.id) AS total FROM users
+			LEFT JOIN bookmarks ON bookmarks.user_id = users.id AND bookmarks.deleted_at IS NULL
+			GROUP BY users.id
+		) AS counts
+		WHERE users.id = counts.id AND users.amount_of_bookmarks <> counts.total`)
+	return result.RowsAffected, result.Error
+}
+
+func (udb *userDatabase) SetPlan(userID uint, planID *uint) error {
+	return udb.DB.Model(&domain.User{}).Where("id = ?", userID).Update("plan_id", planID).Error
+}
@@ -2,56 +2,153 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/OxytocinGroup/theca-backend/internal/domain"
 	"github.com/OxytocinGroup/theca-backend/internal/repository"
 	"github.com/OxytocinGroup/theca-backend/pkg"
 	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/importers"
 	"github.com/OxytocinGroup/theca-backend/pkg/logger"
-	"github.com/OxytocinGroup/theca-backend/pkg/parsers"
+	"github.com/OxytocinGroup/theca-backend/pkg/queue"
+	"github.com/OxytocinGroup/theca-backend/pkg/rank"
+	"github.com/OxytocinGroup/theca-backend/pkg/urlnorm"
+	"gorm.io/gorm"
 )
 
 type BookmarkUseCase interface {
 	CreateBookmark(bookmark domain.Bookmark) pkg.Response
-	GetBookmarksByUser(userID uint) ([]domain.Bookmark, pkg.Response)
+	GetBookmarksByUser(userID uint, filter domain.BookmarkFilter) ([]domain.Bookmark, pkg.Response)
+	GetBookmarkTree(userID uint, filter domain.BookmarkFilter) (domain.BookmarkTree, pkg.Response)
 	DeleteBookmark(userID, bookmarkID uint) pkg.Response
 	UpdateBookmark(userID uint, bookmark *domain.Bookmark) pkg.Response
+	ReorderBookmark(userID, bookmarkID, targetID uint, placement string, moveToFolder bool) pkg.Response
+	SearchBookmarks(userID uint, query string, page, pageSize int) pkg.BookmarkSearchResponse
+	ImportBookmarks(userID uint, source string, entries []importers.Bookmark) pkg.ImportResponse
+	GetTrash(userID uint) ([]domain.Bookmark, pkg.Response)
+	RestoreBookmark(userID, bookmarkID uint) pkg.Response
+	EmptyTrash(userID uint) pkg.Response
+	// GetDuplicates groups the user's bookmarks whose URLs lead to the same
+	// page. Bookmarks without duplicates are left out.
+	GetDuplicates(userID uint) ([]domain.DuplicateGroup, pkg.Response)
+	// MergeDuplicates keeps keepID and moves the bookmarks of mergeIDs,
+	// duplicates of it, to the trash. Their tags are added to keepID.
+	MergeDuplicates(userID, keepID uint, mergeIDs []uint) pkg.Response
+	// GetLinkHealth lists the user's bookmarks by link status, broken and
+	// redirected ones unless statuses says otherwise.
+	GetLinkHealth(userID uint, statuses []string) pkg.BookmarkHealthResponse
+	// ApplyRedirects moves redirected bookmarks to their new URLs.
+	ApplyRedirects(userID uint, bookmarkIDs []uint) pkg.Response
+	// PreviewBookmark reads the metadata of the page at resourceURL, for
+	// filling in a bookmark before it is saved. The page load is given up
+	// when ctx is canceled.
+	PreviewBookmark(ctx context.Context, resourceURL string) pkg.BookmarkPreviewResponse
+	// FetchFavicon runs a domain.JobFetchIcon job.
+	FetchFavicon(ctx context.Context, job domain.Job) error
+	// FetchMetadata runs a domain.JobFetchMetadata job.
+	FetchMetadata(ctx context.Context, job domain.Job) error
+	// CheckLink runs a domain.JobCheckLink job.
+	CheckLink(ctx context.Context, job domain.Job) error
 }
 
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+)
+
 type bookmarkUseCase struct {
 	bookmarkRepo repository.BookmarkRepository
-	userRepo     repository.UserRepository
+	folderRepo   repository.FolderRepository
+	tagRepo      repository.TagRepository
+	uow          repository.UnitOfWork
+	quota        QuotaService
+	icons        IconUseCase
+	jobs         JobQueue
+	urls         *urlnorm.Normalizer
 	log          logger.Logger
 }
 
-func NewBookmarkUseCase(bookmarkRepo repository.BookmarkRepository, userRepo repository.UserRepository, log logger.Logger) BookmarkUseCase {
+func NewBookmarkUseCase(bookmarkRepo repository.BookmarkRepository, folderRepo repository.FolderRepository, tagRepo repository.TagRepository, uow repository.UnitOfWork, quota QuotaService, icons IconUseCase, jobs JobQueue, urls *urlnorm.Normalizer, log logger.Logger) BookmarkUseCase {
 	return &bookmarkUseCase{
 		bookmarkRepo: bookmarkRepo,
-		userRepo:     userRepo,
+		folderRepo:   folderRepo,
+		tagRepo:      tagRepo,
+		uow:          uow,
+		quota:        quota,
+		icons:        icons,
+		jobs:         jobs,
+		urls:         urls,
 		log:          log,
 	}
 }
 
 func (buc *bookmarkUseCase) CreateBookmark(bookmark domain.Bookmark) pkg.Response {
-	user, err := buc.userRepo.GetByID(bookmark.UserID)
-	if err != nil {
-		buc.log.Error(context.Background(), "Create bookmark: error while get user by id", map[string]any{"error": err, "user_id": bookmark.UserID})
-		return pkg.Response{Code: http.StatusInternalServerError, Message: "Failed to get user"}
+	// Import metadata is only set by ImportBookmarks, the icon, page
+	// metadata and link status are found by Theca. A new bookmark is never
+	// in the trash, and its ID, creation time and position are the server's.
+	bookmark.ID, bookmark.CreatedAt, bookmark.DeletedAt, bookmark.Position = 0, time.Time{}, gorm.DeletedAt{}, ""
+	bookmark.ImportSource, bookmark.ImportTags = "", ""
+	bookmark.IconURL, bookmark.IconHash = "", ""
+	bookmark.DominantColor, bookmark.AccentColor = "", ""
+	bookmark.Description, bookmark.SiteName, bookmark.CanonicalURL, bookmark.ImageURL = "", "", "", ""
+	bookmark.LinkStatus, bookmark.LinkStatusCode, bookmark.LinkFinalURL, bookmark.LinkCheckedAt = "", 0, "", nil
+
+	bookmark.URL = buc.urls.Canonical(bookmark.URL)
+	bookmark.NormalizedURL = buc.urls.Key(bookmark.URL)
+	if resp := buc.checkDuplicate(bookmark.UserID, 0, bookmark.NormalizedURL); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	// A bookmark saved with just a URL gets the title of its page.
+	placeholder := ""
+	if strings.TrimSpace(bookmark.Title) == "" {
+		bookmark.Title = placeholderTitle(bookmark.URL)
+		placeholder = bookmark.Title
 	}
 
-	if user.AmountOfBookmarks >= 25 {
-		buc.log.Info(context.Background(), "Create bookmark: limit of bookmarks for user", map[string]any{"user_id": user.ID})
-		return pkg.Response{Code: http.StatusConflict, Message: "Limit of bookmarks: 25", Error: cerr.ErrLimitOfBookmarks}
+	if bookmark.FolderID != nil {
+		if resp := checkFolderOwner(buc.folderRepo, buc.log, bookmark.UserID, *bookmark.FolderID); resp.Code != http.StatusOK {
+			return resp
+		}
 	}
 
-	user.AmountOfBookmarks += 1
-	if err := buc.userRepo.Update(&user); err != nil {
-		buc.log.Error(context.Background(), "Create bookmark: failed to update user", map[string]any{"error": err})
-		return pkg.Response{Code: http.StatusInternalServerError, Message: "Failed to update user"}
+	tags, resp := checkTags(buc.tagRepo, buc.log, bookmark.UserID, bookmark.Tags)
+	if resp.Code != http.StatusOK {
+		return resp
 	}
 
-	err = buc.bookmarkRepo.CreateBookmark(&bookmark)
+	err := buc.uow.Do(func(repos repository.Repositories) error {
+		if err := createWithinQuota(repos, buc.quota, &bookmark); err != nil {
+			return err
+		}
+		tagIDs, err := resolveTags(repos, buc.quota, bookmark.UserID, tags)
+		if err != nil {
+			return err
+		}
+		if len(tagIDs) > 0 {
+			if err := repos.Bookmarks.ReplaceBookmarkTags(bookmark.ID, tagIDs); err != nil {
+				return fmt.Errorf("attach tags: %w", err)
+			}
+		}
+		return nil
+	})
+	if exceeded, ok := asQuotaExceeded(err); ok {
+		buc.log.Info(context.Background(), "Create bookmark: limit of plan reached", map[string]any{"user_id": bookmark.UserID, "resource": exceeded.Resource})
+		return quotaExceededResponse(exceeded)
+	}
+	if errors.Is(err, repository.ErrDuplicateURL) {
+		return duplicateBookmarkResponse()
+	}
 	if err != nil {
 		buc.log.Error(context.Background(), "Create bookmark: failed to create bookmark", map[string]any{"error": err})
 		return pkg.Response{
@@ -60,18 +157,8 @@ func (buc *bookmarkUseCase) CreateBookmark(bookmark domain.Bookmark) pkg.Respons
 		}
 	}
 
-	go func() {
-		iconURL, err := parsers.FetchFavicon(bookmark.URL)
-		if err != nil {
-			buc.log.Error(context.Background(), "Create bookmark: failed to fetch favicon", map[string]any{"error": err})
-			return
-		}
-		bookmark.IconURL = iconURL
-		if err := buc.bookmarkRepo.UploadBookmarkFavicon(bookmark.ID, iconURL); err != nil {
-			buc.log.Error(context.Background(), "Create bookmark: failed to upload favicon url to bookmark", map[string]any{"error": err})
-			return
-		}
-	}()
+	buc.queueFavicon(bookmark)
+	buc.queueMetadata(bookmark, placeholder)
 
 	buc.log.Info(context.Background(), "Create bookmark: created succesfully", map[string]any{})
 	return pkg.Response{
@@ -80,8 +167,81 @@ func (buc *bookmarkUseCase) CreateBookmark(bookmark domain.Bookmark) pkg.Respons
 	}
 }
 
-func (buc *bookmarkUseCase) GetBookmarksByUser(userID uint) ([]domain.Bookmark, pkg.Response) {
-	bookmarks, err := buc.bookmarkRepo.GetBookmarksByUser(userID)
+// createWithinQuota appends the bookmark after the user's last bookmark and
+// counts it, or returns a *QuotaExceededError when the plan has no room left.
+func createWithinQuota(repos repository.Repositories, quota QuotaService, bookmark *domain.Bookmark) error {
+	if err := quota.Reserve(repos, bookmark.UserID, ResourceBookmarks, 1); err != nil {
+		return err
+	}
+
+	lastPosition, err := repos.Bookmarks.GetLastPosition(bookmark.UserID)
+	if err != nil {
+		return fmt.Errorf("get last position: %w", err)
+	}
+	if bookmark.Position, err = rank.After(lastPosition); err != nil {
+		bookmark.Position = ""
+	}
+
+	if err := repos.Bookmarks.CreateBookmark(bookmark); err != nil {
+		return fmt.Errorf("create bookmark: %w", err)
+	}
+	return repos.Users.AdjustBookmarkCount(bookmark.UserID, 1)
+}
+
+// queueFavicon schedules fetching the favicon of a saved bookmark. A
+// failure only costs the bookmark its icon, so it is logged.
+func (buc *bookmarkUseCase) queueFavicon(bookmark domain.Bookmark) {
+	if err := buc.jobs.Enqueue(domain.JobFetchIcon, bookmarkHost(bookmark.URL), domain.FetchIconPayload{BookmarkID: bookmark.ID}); err != nil {
+		buc.log.Error(context.Background(), "Queue favicon: failed to enqueue job", map[string]any{"error": err, "bookmarkID": bookmark.ID})
+	}
+}
+
+// bookmarkHost is the host jobs for a bookmark send requests to.
+func bookmarkHost(resourceURL string) string {
+	parsed, err := url.Parse(resourceURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// FetchFavicon stores the favicon of the bookmark's page, or a generated
+// icon when the site has none, and gives the bookmark that icon and its
+// colors. The bookmark is read again so edits made since the job was
+// queued are taken into account.
+func (buc *bookmarkUseCase) FetchFavicon(ctx context.Context, job domain.Job) error {
+	var payload domain.FetchIconPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return queue.Permanent(fmt.Errorf("decode payload: %w", err))
+	}
+
+	bookmark, err := buc.bookmarkRepo.GetBookmarkByID(payload.BookmarkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Deleted in the meantime.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get bookmark: %w", err)
+	}
+
+	icon, err := buc.icons.FetchIcon(ctx, bookmark.Title, bookmark.URL, job.LastAttempt())
+	if err != nil {
+		return fmt.Errorf("fetch icon: %w", err)
+	}
+	if err := buc.bookmarkRepo.UploadBookmarkFavicon(bookmark.ID, icon); err != nil {
+		return fmt.Errorf("update bookmark: %w", err)
+	}
+	return nil
+}
+
+func (buc *bookmarkUseCase) GetBookmarksByUser(userID uint, filter domain.BookmarkFilter) ([]domain.Bookmark, pkg.Response) {
+	var bookmarks []domain.Bookmark
+	var err error
+	if tags := uniqueNames(filter.Tags); len(tags) > 0 {
+		bookmarks, err = buc.bookmarkRepo.GetBookmarksByTags(userID, tags, filter.MatchAll)
+	} else {
+		bookmarks, err = buc.bookmarkRepo.GetBookmarksByUser(userID)
+	}
 	if err != nil {
 		buc.log.Error(context.Background(), "Get bookmarks by user: failed to get bookmarks by user", map[string]any{
 			"user_id": userID,
@@ -99,19 +259,30 @@ func (buc *bookmarkUseCase) GetBookmarksByUser(userID uint) ([]domain.Bookmark,
 	}
 }
 
-func (buc *bookmarkUseCase) DeleteBookmark(userID, bookmarkID uint) pkg.Response {
-	user, err := buc.userRepo.GetByID(userID)
+func (buc *bookmarkUseCase) GetBookmarkTree(userID uint, filter domain.BookmarkFilter) (domain.BookmarkTree, pkg.Response) {
+	bookmarks, resp := buc.GetBookmarksByUser(userID, filter)
+	if resp.Code != http.StatusOK {
+		return domain.BookmarkTree{}, resp
+	}
+
+	folders, err := buc.folderRepo.GetFoldersByUser(userID)
 	if err != nil {
-		buc.log.Error(context.Background(), "Delete bookmark: error while get user by id", map[string]any{"error": err, "user_id": userID})
-		return pkg.Response{Code: http.StatusInternalServerError, Message: "Failed to get user"}
+		buc.log.Error(context.Background(), "Get bookmark tree: failed to get folders by user", map[string]any{
+			"user_id": userID,
+			"error":   err,
+		})
+		return domain.BookmarkTree{}, pkg.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "failed to get folders",
+		}
 	}
 
-	user.AmountOfBookmarks -= 1
-	if err := buc.userRepo.Update(&user); err != nil {
-		buc.log.Error(context.Background(), "Delete bookmark: failed to update user", map[string]any{"error": err})
-		return pkg.Response{Code: http.StatusInternalServerError, Message: "Failed to update user"}
+	return buildBookmarkTree(folders, bookmarks), pkg.Response{
+		Code: http.StatusOK,
 	}
+}
 
+func (buc *bookmarkUseCase) DeleteBookmark(userID, bookmarkID uint) pkg.Response {
 	bookmarkOwner, err := buc.bookmarkRepo.GetBookmarkOwner(bookmarkID)
 	if err != nil {
 		buc.log.Error(context.Background(), "Delete bookmark: failed to get bookmark owner", map[string]any{
@@ -137,7 +308,15 @@ func (buc *bookmarkUseCase) DeleteBookmark(userID, bookmarkID uint) pkg.Response
 		}
 	}
 
-	err = buc.bookmarkRepo.DeleteBookmarkByID(bookmarkID)
+	err = buc.uow.Do(func(repos repository.Repositories) error {
+		if err := repos.Bookmarks.DeleteBookmarkByID(bookmarkID); err != nil {
+			return err
+		}
+		return repos.Users.AdjustBookmarkCount(userID, -1)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkg.Response{Code: http.StatusNotFound, Message: "bookmark not found", Error: cerr.ErrBookmarkNotFound}
+	}
 	if err != nil {
 		buc.log.Error(context.Background(), "Delete bookmark: failed to delete bookmark", map[string]any{
 			"bookmarkID": bookmarkID,
@@ -152,32 +331,78 @@ func (buc *bookmarkUseCase) DeleteBookmark(userID, bookmarkID uint) pkg.Response
 	buc.log.Info(context.Background(), "Delete bookmark: success", map[string]any{})
 	return pkg.Response{
 		Code:    200,
-		Message: "Bookmark deleted",
+		Message: "Bookmark moved to trash",
 	}
 }
 
 func (buc *bookmarkUseCase) UpdateBookmark(userID uint, bookmark *domain.Bookmark) pkg.Response {
-	go func() {
-		iconURL, err := parsers.FetchFavicon(bookmark.URL)
-		if err != nil {
-			buc.log.Error(context.Background(), "Update bookmark: failed to fetch favicon", map[string]any{"error": err})
-			return
-		}
-		if iconURL == "" {
-			buc.log.Warn(context.Background(), "Update bookmark: empty icon url", map[string]any{
-				"bookmark url": bookmark.URL,
-			})
-			return
+	bookmarkOwner, err := buc.bookmarkRepo.GetBookmarkOwner(bookmark.ID)
+	if err != nil {
+		buc.log.Error(context.Background(), "Update bookmark: failed to get bookmark owner", map[string]any{
+			"bookmarkID": bookmark.ID,
+			"error":      err,
+		})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get bookmark owner"}
+	}
+	if userID != bookmarkOwner {
+		buc.log.Info(context.Background(), "Update bookmark: bookmark belongs to another user", map[string]any{
+			"userID":     userID,
+			"ownerID":    bookmarkOwner,
+			"bookmarkID": bookmark.ID,
+		})
+		return pkg.Response{Code: http.StatusForbidden, Message: "bookmark belongs to another user", Error: cerr.BelongsToAnotherUser}
+	}
+
+	if bookmark.FolderID != nil {
+		if resp := checkFolderOwner(buc.folderRepo, buc.log, userID, *bookmark.FolderID); resp.Code != http.StatusOK {
+			return resp
 		}
+	}
 
-		bookmark.IconURL = iconURL
-		if err := buc.bookmarkRepo.UploadBookmarkFavicon(bookmark.ID, iconURL); err != nil {
-			buc.log.Error(context.Background(), "Update bookmark: failed to upload favicon url to bookmark", map[string]any{"error": err})
-			return
+	current, err := buc.bookmarkRepo.GetBookmarkByID(bookmark.ID)
+	if err != nil {
+		buc.log.Error(context.Background(), "Update bookmark: failed to get bookmark", map[string]any{"bookmarkID": bookmark.ID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to update bookmark"}
+	}
+
+	bookmark.URL = buc.urls.Canonical(bookmark.URL)
+	bookmark.NormalizedURL = buc.urls.Key(bookmark.URL)
+	if resp := buc.checkDuplicate(userID, bookmark.ID, bookmark.NormalizedURL); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	// A missing tags field keeps the current tags, an empty list removes them.
+	var tags tagRefs
+	if bookmark.Tags != nil {
+		var resp pkg.Response
+		if tags, resp = checkTags(buc.tagRepo, buc.log, userID, bookmark.Tags); resp.Code != http.StatusOK {
+			return resp
 		}
-	}()
+	}
 
-	err := buc.bookmarkRepo.UpdateBookmark(bookmark)
+	err = buc.uow.Do(func(repos repository.Repositories) error {
+		if err := repos.Bookmarks.UpdateBookmark(bookmark); err != nil {
+			return err
+		}
+		if bookmark.Tags == nil {
+			return nil
+		}
+		tagIDs, err := resolveTags(repos, buc.quota, userID, tags)
+		if err != nil {
+			return err
+		}
+		if err := repos.Bookmarks.ReplaceBookmarkTags(bookmark.ID, tagIDs); err != nil {
+			return fmt.Errorf("replace tags: %w", err)
+		}
+		return nil
+	})
+	if exceeded, ok := asQuotaExceeded(err); ok {
+		buc.log.Info(context.Background(), "Update bookmark: limit of tags for user", map[string]any{"user_id": userID})
+		return quotaExceededResponse(exceeded)
+	}
+	if errors.Is(err, repository.ErrDuplicateURL) {
+		return duplicateBookmarkResponse()
+	}
 	if err != nil {
 		buc.log.Error(context.Background(), "Update bookmark: failed to update bookmark", map[string]any{
 			"bookmarkID": bookmark.ID,
@@ -188,7 +413,217 @@ func (buc *bookmarkUseCase) UpdateBookmark(userID uint, bookmark *domain.Bookmar
 			Message: "failed to update bookmark",
 		}
 	}
+
+	// The icon and metadata only need fetching again for another page.
+	if bookmark.URL != current.URL {
+		buc.queueFavicon(*bookmark)
+		buc.queueMetadata(*bookmark, "")
+	}
 	return pkg.Response{
 		Code: 200,
 	}
 }
+
+// ReorderBookmark runs in one transaction holding the user's row lock, so
+// a failure leaves no list half renumbered and concurrent reorders of the
+// same user each see the positions the previous one left.
+func (buc *bookmarkUseCase) ReorderBookmark(userID, bookmarkID, targetID uint, placement string, moveToFolder bool) pkg.Response {
+	err := buc.uow.Do(func(repos repository.Repositories) error {
+		if _, err := repos.Users.GetByIDForUpdate(userID); err != nil {
+			return fmt.Errorf("lock user: %w", err)
+		}
+		bookmarks, err := repos.Bookmarks.GetBookmarksByUser(userID)
+		if err != nil {
+			return fmt.Errorf("get bookmarks: %w", err)
+		}
+
+		if !positionsAreOrdered(bookmarks) {
+			positions := rank.Sequence(len(bookmarks))
+			ids := make([]uint, len(bookmarks))
+			for i := range bookmarks {
+				ids[i] = bookmarks[i].ID
+				bookmarks[i].Position = positions[i]
+			}
+			if err := repos.Bookmarks.RenumberBookmarks(ids, positions); err != nil {
+				return fmt.Errorf("renumber bookmarks: %w", err)
+			}
+		}
+
+		var moving *domain.Bookmark
+		others := make([]domain.Bookmark, 0, len(bookmarks))
+		for i := range bookmarks {
+			if bookmarks[i].ID == bookmarkID {
+				moving = &bookmarks[i]
+			} else {
+				others = append(others, bookmarks[i])
+			}
+		}
+
+		target := -1
+		for i := range others {
+			if others[i].ID == targetID {
+				target = i
+			}
+		}
+		if moving == nil || target < 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var lower, upper string
+		if placement == "before" {
+			if target > 0 {
+				lower = others[target-1].Position
+			}
+			upper = others[target].Position
+		} else {
+			lower = others[target].Position
+			if target+1 < len(others) {
+				upper = others[target+1].Position
+			}
+		}
+
+		var position string
+		if upper == "" {
+			position, err = rank.After(lower)
+		} else {
+			position, err = rank.Between(lower, upper)
+		}
+		if err != nil {
+			return fmt.Errorf("compute position between %q and %q: %w", lower, upper, err)
+		}
+
+		if err := repos.Bookmarks.UpdateBookmarkPosition(moving.ID, position); err != nil {
+			return fmt.Errorf("update position: %w", err)
+		}
+		if moveToFolder {
+			if err := repos.Bookmarks.MoveBookmarkToFolder(moving.ID, others[target].FolderID); err != nil {
+				return fmt.Errorf("move to folder: %w", err)
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		buc.log.Info(context.Background(), "Reorder bookmark: bookmark not found", map[string]any{
+			"userID":     userID,
+			"bookmarkID": bookmarkID,
+			"targetID":   targetID,
+		})
+		return pkg.Response{Code: http.StatusNotFound, Message: "bookmark not found", Error: cerr.ErrBookmarkNotFound}
+	}
+	if err != nil {
+		buc.log.Error(context.Background(), "Reorder bookmark: failed to reorder bookmarks", map[string]any{"user_id": userID, "bookmarkID": bookmarkID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to reorder bookmarks"}
+	}
+
+	buc.log.Info(context.Background(), "Reorder bookmark: success", map[string]any{})
+	return pkg.Response{Code: http.StatusOK, Message: "Bookmark moved"}
+}
+
+// positionsAreOrdered reports whether every bookmark has a usable rank key
+// and the keys are strictly increasing. Bookmarks created before ordering
+// existed have no key and need to be numbered once.
+func positionsAreOrdered(bookmarks []domain.Bookmark) bool {
+	for i := range bookmarks {
+		if !rank.Valid(bookmarks[i].Position) {
+			return false
+		}
+		if i > 0 && bookmarks[i-1].Position >= bookmarks[i].Position {
+			return false
+		}
+	}
+	return true
+}
+
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var unique []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+func (buc *bookmarkUseCase) SearchBookmarks(userID uint, query string, page, pageSize int) pkg.BookmarkSearchResponse {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return pkg.BookmarkSearchResponse{Code: http.StatusBadRequest, Message: "search query is empty", Error: cerr.ErrInvalidBody}
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultSearchPageSize
+	}
+	if pageSize > maxSearchPageSize {
+		pageSize = maxSearchPageSize
+	}
+
+	results, total, err := buc.bookmarkRepo.SearchBookmarks(userID, query, pageSize, (page-1)*pageSize)
+	if err != nil {
+		buc.log.Error(context.Background(), "Search bookmarks: failed to search bookmarks", map[string]any{
+			"user_id": userID,
+			"error":   err,
+		})
+		return pkg.BookmarkSearchResponse{Code: http.StatusInternalServerError, Message: "failed to search bookmarks"}
+	}
+
+	terms := searchTerms(query)
+	for i := range results {
+		results[i].TitleHighlight = highlightTerms(results[i].Title, terms)
+		results[i].URLHighlight = highlightTerms(results[i].URL, terms)
+	}
+
+	return pkg.BookmarkSearchResponse{
+		Code:     http.StatusOK,
+		Results:  results,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+}
+
+// searchTerms extracts the words of a web-search style query, dropping
+// quotes, exclusions and the "or" operator.
+func searchTerms(query string) []string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		if strings.HasPrefix(field, "-") || strings.EqualFold(field, "or") {
+			continue
+		}
+		terms = append(terms, strings.FieldsFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+	return terms
+}
+
+// highlightTerms HTML-escapes text and wraps case-insensitive occurrences
+// of terms in <mark> tags.
+func highlightTerms(text string, terms []string) string {
+	if len(terms) == 0 {
+		return html.EscapeString(text)
+	}
+
+	sorted := append([]string(nil), terms...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	for i := range sorted {
+		sorted[i] = regexp.QuoteMeta(sorted[i])
+	}
+	pattern := regexp.MustCompile("(?i)" + strings.Join(sorted, "|"))
+
+	var b strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:match[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[match[0]:match[1]]))
+		b.WriteString("</mark>")
+		last = match[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
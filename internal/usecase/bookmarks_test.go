@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+)
+
+func TestPositionsAreOrdered(t *testing.T) {
+	tests := []struct {
+		name      string
+		positions []string
+		want      bool
+	}{
+		{name: "no bookmarks", want: true},
+		{name: "increasing", positions: []string{"1", "1V", "2", "z"}, want: true},
+		{name: "saved before ordering", positions: []string{"1", "", "2"}},
+		{name: "equal keys", positions: []string{"1", "1"}},
+		{name: "decreasing", positions: []string{"2", "1"}},
+		{name: "invalid key", positions: []string{"1", "2-"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookmarks := make([]domain.Bookmark, len(tt.positions))
+			for i, position := range tt.positions {
+				bookmarks[i].Position = position
+			}
+			if got := positionsAreOrdered(bookmarks); got != tt.want {
+				t.Fatalf("positionsAreOrdered(%q) = %v, want %v", tt.positions, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "go tutorial", want: []string{"go", "tutorial"}},
+		{query: `"exact phrase" -excluded`, want: []string{"exact", "phrase"}},
+		{query: "go OR rust or zig", want: []string{"go", "rust", "zig"}},
+		{query: "c++ node.js", want: []string{"c", "node", "js"}},
+		{query: "привет мир", want: []string{"привет", "мир"}},
+		{query: "-only -exclusions"},
+		{query: "  "},
+	}
+	for _, tt := range tests {
+		if got := searchTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("searchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{name: "no terms", text: "a <b>", want: "a &lt;b&gt;"},
+		{name: "case-insensitive", text: "Go and GO", terms: []string{"go"}, want: "<mark>Go</mark> and <mark>GO</mark>"},
+		{name: "longest term first", text: "golang", terms: []string{"go", "golang"}, want: "<mark>golang</mark>"},
+		{name: "escapes around and inside marks", text: "<go> & go&", terms: []string{"go&"}, want: "&lt;go&gt; &amp; <mark>go&amp;</mark>"},
+		{name: "regexp characters", text: "c++ and c", terms: []string{"c++"}, want: "<mark>c++</mark> and c"},
+		{name: "no match", text: "rust", terms: []string{"go"}, want: "rust"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightTerms(tt.text, tt.terms); got != tt.want {
+				t.Fatalf("highlightTerms(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+			}
+		})
+	}
+}
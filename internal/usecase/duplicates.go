@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"gorm.io/gorm"
+)
+
+func duplicateBookmarkResponse() pkg.Response {
+	return pkg.Response{Code: http.StatusConflict, Message: "a bookmark with this URL already exists", Error: cerr.ErrDuplicateBookmark}
+}
+
+// checkDuplicate fails with 409 when a bookmark of the user other than
+// bookmarkID is saved under normalizedURL. Zero stands for a new bookmark.
+func (buc *bookmarkUseCase) checkDuplicate(userID, bookmarkID uint, normalizedURL string) pkg.Response {
+	if normalizedURL == "" {
+		return pkg.Response{Code: http.StatusOK}
+	}
+	existing, err := buc.bookmarkRepo.GetBookmarkByNormalizedURL(userID, normalizedURL)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkg.Response{Code: http.StatusOK}
+	}
+	if err != nil {
+		buc.log.Error(context.Background(), "Check duplicate: failed to get bookmark", map[string]any{"user_id": userID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to check for duplicates"}
+	}
+	if existing.ID != bookmarkID {
+		buc.log.Info(context.Background(), "Check duplicate: URL already saved", map[string]any{"user_id": userID, "existingID": existing.ID})
+		resp := duplicateBookmarkResponse()
+		resp.Message = fmt.Sprintf("a bookmark with this URL already exists: %d", existing.ID)
+		return resp
+	}
+	return pkg.Response{Code: http.StatusOK}
+}
+
+// GetDuplicates groups by the duplicate key computed now rather than the
+// stored one, so bookmarks saved before keys were kept, or under an older
+// list of tracking parameters, are found too.
+func (buc *bookmarkUseCase) GetDuplicates(userID uint) ([]domain.DuplicateGroup, pkg.Response) {
+	bookmarks, err := buc.bookmarkRepo.GetBookmarksByUser(userID)
+	if err != nil {
+		buc.log.Error(context.Background(), "Get duplicates: failed to get bookmarks by user", map[string]any{"user_id": userID, "error": err})
+		return nil, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get bookmarks"}
+	}
+
+	byKey := make(map[string][]domain.Bookmark)
+	for _, bookmark := range bookmarks {
+		key := buc.urls.Key(bookmark.URL)
+		byKey[key] = append(byKey[key], bookmark)
+	}
+
+	groups := []domain.DuplicateGroup{}
+	for key, group := range byKey {
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].CreatedAt.Equal(group[j].CreatedAt) {
+				return group[i].ID < group[j].ID
+			}
+			return group[i].CreatedAt.Before(group[j].CreatedAt)
+		})
+		groups = append(groups, domain.DuplicateGroup{NormalizedURL: key, Bookmarks: group})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Bookmarks[0].ID < groups[j].Bookmarks[0].ID
+	})
+	return groups, pkg.Response{Code: http.StatusOK}
+}
+
+func (buc *bookmarkUseCase) MergeDuplicates(userID, keepID uint, mergeIDs []uint) pkg.Response {
+	mergeIDs = slices.Compact(slices.Sorted(slices.Values(mergeIDs)))
+	if len(mergeIDs) == 0 || slices.Contains(mergeIDs, keepID) {
+		return pkg.Response{Code: http.StatusBadRequest, Message: "merge_ids must name bookmarks other than keep_id", Error: cerr.ErrInvalidBody}
+	}
+
+	bookmarks, err := buc.bookmarkRepo.GetBookmarksByUser(userID)
+	if err != nil {
+		buc.log.Error(context.Background(), "Merge duplicates: failed to get bookmarks by user", map[string]any{"user_id": userID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get bookmarks"}
+	}
+	byID := make(map[uint]domain.Bookmark, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		byID[bookmark.ID] = bookmark
+	}
+
+	keep, ok := byID[keepID]
+	if !ok {
+		return pkg.Response{Code: http.StatusNotFound, Message: fmt.Sprintf("bookmark %d not found", keepID), Error: cerr.ErrBookmarkNotFound}
+	}
+	key := buc.urls.Key(keep.URL)
+	for _, id := range mergeIDs {
+		bookmark, ok := byID[id]
+		if !ok {
+			return pkg.Response{Code: http.StatusNotFound, Message: fmt.Sprintf("bookmark %d not found", id), Error: cerr.ErrBookmarkNotFound}
+		}
+		if buc.urls.Key(bookmark.URL) != key {
+			return pkg.Response{Code: http.StatusBadRequest, Message: fmt.Sprintf("bookmark %d is not a duplicate of %d", id, keepID), Error: cerr.ErrInvalidBody}
+		}
+	}
+
+	// The kept bookmark takes over the key unless a duplicate left out of
+	// the merge holds it.
+	normalizedURL := key
+	for _, bookmark := range bookmarks {
+		if bookmark.ID != keepID && bookmark.NormalizedURL == key && !slices.Contains(mergeIDs, bookmark.ID) {
+			normalizedURL = keep.NormalizedURL
+		}
+	}
+
+	err = buc.uow.Do(func(repos repository.Repositories) error {
+		if err := repos.Bookmarks.MergeBookmarks(keepID, mergeIDs, normalizedURL); err != nil {
+			return err
+		}
+		return repos.Users.AdjustBookmarkCount(userID, -len(mergeIDs))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkg.Response{Code: http.StatusNotFound, Message: "bookmark not found", Error: cerr.ErrBookmarkNotFound}
+	}
+	if errors.Is(err, repository.ErrDuplicateURL) {
+		return duplicateBookmarkResponse()
+	}
+	if err != nil {
+		buc.log.Error(context.Background(), "Merge duplicates: failed to merge bookmarks", map[string]any{"keepID": keepID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to merge bookmarks"}
+	}
+
+	buc.log.Info(context.Background(), "Merge duplicates: success", map[string]any{"user_id": userID, "merged": len(mergeIDs)})
+	return pkg.Response{Code: http.StatusOK, Message: fmt.Sprintf("Merged %d bookmarks", len(mergeIDs))}
+}
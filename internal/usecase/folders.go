@@ -0,0 +1,251 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+type FolderUseCase interface {
+	CreateFolder(folder domain.Folder) pkg.Response
+	GetFoldersByUser(userID uint) ([]domain.Folder, pkg.Response)
+	UpdateFolder(userID uint, folder *domain.Folder) pkg.Response
+	DeleteFolder(userID, folderID uint, cascade bool) pkg.Response
+}
+
+type folderUseCase struct {
+	folderRepo repository.FolderRepository
+	uow        repository.UnitOfWork
+	quota      QuotaService
+	log        logger.Logger
+}
+
+func NewFolderUseCase(folderRepo repository.FolderRepository, uow repository.UnitOfWork, quota QuotaService, log logger.Logger) FolderUseCase {
+	return &folderUseCase{
+		folderRepo: folderRepo,
+		uow:        uow,
+		quota:      quota,
+		log:        log,
+	}
+}
+
+func (fuc *folderUseCase) CreateFolder(folder domain.Folder) pkg.Response {
+	if folder.ParentID != nil {
+		if resp := checkFolderOwner(fuc.folderRepo, fuc.log, folder.UserID, *folder.ParentID); resp.Code != http.StatusOK {
+			return resp
+		}
+	}
+
+	err := createFolder(fuc.uow, fuc.quota, &folder)
+	if exceeded, ok := asQuotaExceeded(err); ok {
+		fuc.log.Info(context.Background(), "Create folder: limit of folders for user", map[string]any{"user_id": folder.UserID})
+		return quotaExceededResponse(exceeded)
+	}
+	if err != nil {
+		fuc.log.Error(context.Background(), "Create folder: failed to create folder", map[string]any{"error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "Failed to create folder"}
+	}
+
+	fuc.log.Info(context.Background(), "Create folder: created successfully", map[string]any{})
+	return pkg.Response{Code: http.StatusCreated, Message: "folder created successfully"}
+}
+
+func (fuc *folderUseCase) GetFoldersByUser(userID uint) ([]domain.Folder, pkg.Response) {
+	folders, err := fuc.folderRepo.GetFoldersByUser(userID)
+	if err != nil {
+		fuc.log.Error(context.Background(), "Get folders by user: failed to get folders", map[string]any{
+			"user_id": userID,
+			"error":   err,
+		})
+		return nil, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get folders"}
+	}
+
+	return folders, pkg.Response{Code: http.StatusOK}
+}
+
+func (fuc *folderUseCase) UpdateFolder(userID uint, folder *domain.Folder) pkg.Response {
+	if resp := checkFolderOwner(fuc.folderRepo, fuc.log, userID, folder.ID); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	if folder.ParentID != nil {
+		if resp := checkFolderOwner(fuc.folderRepo, fuc.log, userID, *folder.ParentID); resp.Code != http.StatusOK {
+			return resp
+		}
+
+		folders, err := fuc.folderRepo.GetFoldersByUser(userID)
+		if err != nil {
+			fuc.log.Error(context.Background(), "Update folder: failed to get folders", map[string]any{"user_id": userID, "error": err})
+			return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get folders"}
+		}
+		for _, id := range collectFolderSubtree(folders, folder.ID) {
+			if id == *folder.ParentID {
+				fuc.log.Info(context.Background(), "Update folder: folder can't be moved into itself", map[string]any{
+					"folderID": folder.ID,
+					"parentID": *folder.ParentID,
+				})
+				return pkg.Response{
+					Code:    http.StatusBadRequest,
+					Message: "folder can't be moved into itself or its subfolder",
+					Error:   cerr.ErrInvalidParent,
+				}
+			}
+		}
+	}
+
+	folder.UserID = userID
+	if err := fuc.folderRepo.UpdateFolder(folder); err != nil {
+		fuc.log.Error(context.Background(), "Update folder: failed to update folder", map[string]any{"folderID": folder.ID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to update folder"}
+	}
+
+	return pkg.Response{Code: http.StatusOK, Message: "Folder updated"}
+}
+
+func (fuc *folderUseCase) DeleteFolder(userID, folderID uint, cascade bool) pkg.Response {
+	if resp := checkFolderOwner(fuc.folderRepo, fuc.log, userID, folderID); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	if !cascade {
+		folder, err := fuc.folderRepo.GetFolderByID(folderID)
+		if err != nil {
+			fuc.log.Error(context.Background(), "Delete folder: failed to get folder", map[string]any{"folderID": folderID, "error": err})
+			return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get folder"}
+		}
+		if err := fuc.folderRepo.MoveFolderContents(folderID, folder.ParentID); err != nil {
+			fuc.log.Error(context.Background(), "Delete folder: failed to move folder contents", map[string]any{"folderID": folderID, "error": err})
+			return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to delete folder"}
+		}
+
+		fuc.log.Info(context.Background(), "Delete folder: success", map[string]any{})
+		return pkg.Response{Code: http.StatusOK, Message: "Folder deleted"}
+	}
+
+	folders, err := fuc.folderRepo.GetFoldersByUser(userID)
+	if err != nil {
+		fuc.log.Error(context.Background(), "Delete folder: failed to get folders", map[string]any{"user_id": userID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get folders"}
+	}
+
+	var deleted int64
+	err = fuc.uow.Do(func(repos repository.Repositories) error {
+		var err error
+		if deleted, err = repos.Folders.DeleteFoldersWithBookmarks(collectFolderSubtree(folders, folderID)); err != nil || deleted == 0 {
+			return err
+		}
+		return repos.Users.AdjustBookmarkCount(userID, -int(deleted))
+	})
+	if err != nil {
+		fuc.log.Error(context.Background(), "Delete folder: failed to delete folders", map[string]any{"folderID": folderID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to delete folder"}
+	}
+
+	fuc.log.Info(context.Background(), "Delete folder: success", map[string]any{"bookmarks_deleted": deleted})
+	return pkg.Response{Code: http.StatusOK, Message: "Folder deleted"}
+}
+
+// createFolder saves a new folder if the user's plan allows another one.
+func createFolder(uow repository.UnitOfWork, quota QuotaService, folder *domain.Folder) error {
+	return uow.Do(func(repos repository.Repositories) error {
+		if err := quota.Reserve(repos, folder.UserID, ResourceFolders, 1); err != nil {
+			return err
+		}
+		return repos.Folders.CreateFolder(folder)
+	})
+}
+
+// checkFolderOwner returns a 200 response when the folder exists and belongs
+// to the user, otherwise the response that should be sent to the client.
+func checkFolderOwner(folderRepo repository.FolderRepository, log logger.Logger, userID, folderID uint) pkg.Response {
+	folder, err := folderRepo.GetFolderByID(folderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Info(context.Background(), "folder not found", map[string]any{"folderID": folderID})
+		return pkg.Response{Code: http.StatusNotFound, Message: "folder not found", Error: cerr.ErrFolderNotFound}
+	}
+	if err != nil {
+		log.Error(context.Background(), "failed to get folder", map[string]any{"folderID": folderID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get folder"}
+	}
+
+	if folder.UserID != userID {
+		log.Info(context.Background(), "folder belongs to another user", map[string]any{
+			"userID":   userID,
+			"ownerID":  folder.UserID,
+			"folderID": folderID,
+		})
+		return pkg.Response{Code: http.StatusForbidden, Message: "folder belongs to another user", Error: cerr.BelongsToAnotherUser}
+	}
+	return pkg.Response{Code: http.StatusOK}
+}
+
+// collectFolderSubtree returns rootID followed by the IDs of all folders
+// nested below it.
+func collectFolderSubtree(folders []domain.Folder, rootID uint) []uint {
+	children := make(map[uint][]uint)
+	for _, folder := range folders {
+		if folder.ParentID != nil {
+			children[*folder.ParentID] = append(children[*folder.ParentID], folder.ID)
+		}
+	}
+
+	ids := []uint{rootID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
+
+// buildBookmarkTree arranges folders and bookmarks into a tree, keeping the
+// order in which they were passed in. Items whose parent folder is unknown
+// are placed on the root level.
+func buildBookmarkTree(folders []domain.Folder, bookmarks []domain.Bookmark) domain.BookmarkTree {
+	known := make(map[uint]bool, len(folders))
+	for _, folder := range folders {
+		known[folder.ID] = true
+	}
+
+	subfolders := make(map[uint][]domain.Folder)
+	var rootFolders []domain.Folder
+	for _, folder := range folders {
+		if folder.ParentID != nil && known[*folder.ParentID] && *folder.ParentID != folder.ID {
+			subfolders[*folder.ParentID] = append(subfolders[*folder.ParentID], folder)
+		} else {
+			rootFolders = append(rootFolders, folder)
+		}
+	}
+
+	contents := make(map[uint][]domain.Bookmark)
+	tree := domain.BookmarkTree{Folders: []domain.FolderNode{}, Bookmarks: []domain.Bookmark{}}
+	for _, bookmark := range bookmarks {
+		if bookmark.FolderID != nil && known[*bookmark.FolderID] {
+			contents[*bookmark.FolderID] = append(contents[*bookmark.FolderID], bookmark)
+		} else {
+			tree.Bookmarks = append(tree.Bookmarks, bookmark)
+		}
+	}
+
+	var build func(folder domain.Folder) domain.FolderNode
+	build = func(folder domain.Folder) domain.FolderNode {
+		node := domain.FolderNode{Folder: folder, Folders: []domain.FolderNode{}, Bookmarks: contents[folder.ID]}
+		if node.Bookmarks == nil {
+			node.Bookmarks = []domain.Bookmark{}
+		}
+		for _, child := range subfolders[folder.ID] {
+			node.Folders = append(node.Folders, build(child))
+		}
+		return node
+	}
+
+	for _, folder := range rootFolders {
+		tree.Folders = append(tree.Folders, build(folder))
+	}
+	return tree
+}
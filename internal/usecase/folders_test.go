@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+)
+
+func folderID(id uint) *uint {
+	return &id
+}
+
+// treeShape lists the folders of a tree with their bookmarks, as
+// "folder: bookmark bookmark" lines in display order, for comparison.
+func treeShape(tree domain.BookmarkTree) []string {
+	shape := []string{"root:" + bookmarkTitles(tree.Bookmarks)}
+	var visit func(prefix string, nodes []domain.FolderNode)
+	visit = func(prefix string, nodes []domain.FolderNode) {
+		for _, node := range nodes {
+			shape = append(shape, prefix+node.Name+":"+bookmarkTitles(node.Bookmarks))
+			visit(prefix+node.Name+"/", node.Folders)
+		}
+	}
+	visit("", tree.Folders)
+	return shape
+}
+
+func bookmarkTitles(bookmarks []domain.Bookmark) string {
+	titles := ""
+	for _, bookmark := range bookmarks {
+		titles += " " + bookmark.Title
+	}
+	return titles
+}
+
+func TestBuildBookmarkTree(t *testing.T) {
+	tests := []struct {
+		name      string
+		folders   []domain.Folder
+		bookmarks []domain.Bookmark
+		want      []string
+	}{
+		{name: "empty", want: []string{"root:"}},
+		{
+			name:    "nested folders keep their order",
+			folders: []domain.Folder{{ID: 1, Name: "b"}, {ID: 2, Name: "a"}, {ID: 3, Name: "c", ParentID: folderID(1)}, {ID: 4, Name: "d", ParentID: folderID(3)}},
+			bookmarks: []domain.Bookmark{
+				{Title: "x", FolderID: folderID(4)}, {Title: "y"}, {Title: "z", FolderID: folderID(1)}, {Title: "w", FolderID: folderID(4)},
+			},
+			want: []string{"root: y", "b: z", "b/c:", "b/c/d: x w", "a:"},
+		},
+		{
+			name:      "unknown parents put items on the root level",
+			folders:   []domain.Folder{{ID: 1, Name: "orphan", ParentID: folderID(99)}},
+			bookmarks: []domain.Bookmark{{Title: "x", FolderID: folderID(42)}},
+			want:      []string{"root: x", "orphan:"},
+		},
+		{
+			name:    "folder that is its own parent",
+			folders: []domain.Folder{{ID: 1, Name: "self", ParentID: folderID(1)}},
+			want:    []string{"root:", "self:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildBookmarkTree(tt.folders, tt.bookmarks)
+			if got := treeShape(tree); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("tree = %q, want %q", got, tt.want)
+			}
+			if tree.Folders == nil || tree.Bookmarks == nil {
+				t.Fatal("empty levels must be empty lists, not null")
+			}
+		})
+	}
+}
+
+func TestCollectFolderSubtree(t *testing.T) {
+	folders := []domain.Folder{
+		{ID: 1}, {ID: 2, ParentID: folderID(1)}, {ID: 3, ParentID: folderID(2)}, {ID: 4, ParentID: folderID(1)}, {ID: 5},
+	}
+	tests := []struct {
+		root uint
+		want []uint
+	}{
+		{root: 1, want: []uint{1, 2, 4, 3}},
+		{root: 2, want: []uint{2, 3}},
+		{root: 5, want: []uint{5}},
+		{root: 9, want: []uint{9}},
+	}
+	for _, tt := range tests {
+		if got := collectFolderSubtree(folders, tt.root); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("collectFolderSubtree(%d) = %v, want %v", tt.root, got, tt.want)
+		}
+	}
+}
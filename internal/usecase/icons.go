@@ -0,0 +1,231 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/blobstore"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/imaging"
+	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"github.com/OxytocinGroup/theca-backend/pkg/parsers"
+	"gorm.io/gorm"
+)
+
+// DefaultIconSize is the variant served when no size is asked for.
+const DefaultIconSize = 64
+
+// Formats icons are served in.
+const (
+	IconFormatPNG = "png"
+	IconFormatSVG = "svg"
+)
+
+type IconUseCase interface {
+	// FetchIcon stores the favicon of the page at resourceURL. Sites
+	// without a usable favicon get a generated letter avatar instead.
+	// When the site could not be reached and lastAttempt is false, it
+	// returns an error wrapping parsers.ErrPageUnavailable so the fetch
+	// can be retried later rather than settling for an avatar.
+	FetchIcon(ctx context.Context, title, resourceURL string, lastAttempt bool) (domain.Icon, error)
+	// GetIcon opens the PNG variant of the given size, or the original
+	// when the icon has no variants. The SVG format serves the original
+	// of SVG icons, such as generated ones.
+	GetIcon(hash string, size int, format string) (IconFile, pkg.Response)
+	// BackfillColors extracts the colors of icons stored before colors
+	// were, and returns how many icons got them.
+	BackfillColors() (int, error)
+}
+
+// IconFile is an icon image ready to be sent. The caller closes Body.
+type IconFile struct {
+	ContentType string
+	Size        int64
+	Body        io.ReadCloser
+}
+
+var iconHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type iconUseCase struct {
+	iconRepo repository.IconRepository
+	blobs    blobstore.Store
+	log      logger.Logger
+}
+
+func NewIconUseCase(iconRepo repository.IconRepository, blobs blobstore.Store, log logger.Logger) IconUseCase {
+	return &iconUseCase{
+		iconRepo: iconRepo,
+		blobs:    blobs,
+		log:      log,
+	}
+}
+
+// iconKey spreads icons over 256 directories so none grows too large.
+func iconKey(hash string) string {
+	return "icons/" + hash[:2] + "/" + hash
+}
+
+func iconVariantKey(hash string, size int) string {
+	return iconKey(hash) + "." + strconv.Itoa(size) + ".png"
+}
+
+func (iuc *iconUseCase) FetchIcon(ctx context.Context, title, resourceURL string, lastAttempt bool) (domain.Icon, error) {
+	favicon, err := parsers.FetchFavicon(ctx, resourceURL)
+	if err == nil {
+		return iuc.storeIcon(favicon.Icon, false)
+	}
+	if ctx.Err() != nil {
+		return domain.Icon{}, ctx.Err()
+	}
+	if !lastAttempt && errors.Is(err, parsers.ErrPageUnavailable) {
+		return domain.Icon{}, err
+	}
+	iuc.log.Info(context.Background(), "Fetch icon: no favicon, generating one", map[string]any{"error": err, "url": resourceURL})
+
+	host := ""
+	if parsed, err := url.Parse(resourceURL); err == nil {
+		host = strings.TrimPrefix(parsed.Hostname(), "www.")
+	}
+	avatar, err := imaging.LetterAvatar(imaging.AvatarLetter(title, host), imaging.AvatarColor(host))
+	if err != nil {
+		return domain.Icon{}, fmt.Errorf("generate icon: %w", err)
+	}
+	return iuc.storeIcon(avatar, true)
+}
+
+// storeIcon saves the icon and its variants unless it is already stored.
+func (iuc *iconUseCase) storeIcon(icon *imaging.Icon, generated bool) (domain.Icon, error) {
+	sum := sha256.Sum256(icon.Original)
+	hash := hex.EncodeToString(sum[:])
+
+	existing, err := iuc.iconRepo.GetIcon(hash)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.Icon{}, fmt.Errorf("get icon: %w", err)
+	}
+
+	// Blobs are written before the row, so a saved icon always has its
+	// data. Blobs left behind by a failed insert are reused next time.
+	ctx := context.Background()
+	if err := iuc.blobs.Put(ctx, iconKey(hash), icon.ContentType, icon.Original); err != nil {
+		return domain.Icon{}, fmt.Errorf("store icon: %w", err)
+	}
+	for size, variant := range icon.Variants {
+		if err := iuc.blobs.Put(ctx, iconVariantKey(hash, size), "image/png", variant); err != nil {
+			return domain.Icon{}, fmt.Errorf("store %dpx icon: %w", size, err)
+		}
+	}
+
+	row := domain.Icon{
+		Hash:          hash,
+		ContentType:   icon.ContentType,
+		Size:          len(icon.Original),
+		HasVariants:   len(icon.Variants) > 0,
+		Generated:     generated,
+		DominantColor: icon.DominantColor,
+		AccentColor:   icon.AccentColor,
+	}
+	if err := iuc.iconRepo.CreateIcon(&row); err != nil {
+		return domain.Icon{}, fmt.Errorf("create icon: %w", err)
+	}
+	return row, nil
+}
+
+// BackfillColors reads the colors from the PNG variants, so icons without
+// variants are skipped.
+func (iuc *iconUseCase) BackfillColors() (int, error) {
+	icons, err := iuc.iconRepo.GetIconsWithoutColors()
+	if err != nil {
+		return 0, fmt.Errorf("get icons: %w", err)
+	}
+
+	updated := 0
+	for _, icon := range icons {
+		if !icon.HasVariants {
+			continue
+		}
+		dominant, accent, err := iuc.variantColors(icon.Hash)
+		if err != nil {
+			iuc.log.Error(context.Background(), "Backfill colors: failed to read icon", map[string]any{"error": err, "hash": icon.Hash})
+			continue
+		}
+		if dominant == "" {
+			continue
+		}
+		if err := iuc.iconRepo.SetIconColors(icon.Hash, dominant, accent); err != nil {
+			return updated, fmt.Errorf("update icon %s: %w", icon.Hash, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+func (iuc *iconUseCase) variantColors(hash string) (string, string, error) {
+	body, _, err := iuc.blobs.Get(context.Background(), iconVariantKey(hash, DefaultIconSize))
+	if err != nil {
+		return "", "", err
+	}
+	defer body.Close()
+
+	img, err := png.Decode(body)
+	if err != nil {
+		return "", "", err
+	}
+	dominant, accent, _ := imaging.ExtractColors(img)
+	return dominant, accent, nil
+}
+
+func (iuc *iconUseCase) GetIcon(hash string, size int, format string) (IconFile, pkg.Response) {
+	if !slices.Contains(imaging.VariantSizes, size) {
+		return IconFile{}, pkg.Response{Code: http.StatusBadRequest, Message: fmt.Sprintf("size must be one of %v", imaging.VariantSizes), Error: cerr.ErrInvalidBody}
+	}
+	if format != IconFormatPNG && format != IconFormatSVG {
+		return IconFile{}, pkg.Response{Code: http.StatusBadRequest, Message: "format must be png or svg", Error: cerr.ErrInvalidBody}
+	}
+	if !iconHashPattern.MatchString(hash) {
+		return IconFile{}, pkg.Response{Code: http.StatusNotFound, Message: "icon not found", Error: cerr.ErrIconNotFound}
+	}
+
+	icon, err := iuc.iconRepo.GetIcon(hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return IconFile{}, pkg.Response{Code: http.StatusNotFound, Message: "icon not found", Error: cerr.ErrIconNotFound}
+	}
+	if err != nil {
+		iuc.log.Error(context.Background(), "Get icon: failed to get icon", map[string]any{"error": err, "hash": hash})
+		return IconFile{}, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get icon"}
+	}
+
+	key, contentType := iconKey(hash), icon.ContentType
+	switch {
+	case format == IconFormatSVG && icon.ContentType != "image/svg+xml":
+		return IconFile{}, pkg.Response{Code: http.StatusNotFound, Message: "icon has no SVG version", Error: cerr.ErrIconNotFound}
+	case format == IconFormatPNG && icon.HasVariants:
+		key, contentType = iconVariantKey(hash, size), "image/png"
+	}
+	body, length, err := iuc.blobs.Get(context.Background(), key)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		iuc.log.Error(context.Background(), "Get icon: icon data is missing", map[string]any{"hash": hash, "key": key})
+		return IconFile{}, pkg.Response{Code: http.StatusNotFound, Message: "icon not found", Error: cerr.ErrIconNotFound}
+	}
+	if err != nil {
+		iuc.log.Error(context.Background(), "Get icon: failed to read icon", map[string]any{"error": err, "hash": hash})
+		return IconFile{}, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get icon"}
+	}
+	return IconFile{ContentType: contentType, Size: length, Body: body}, pkg.Response{Code: http.StatusOK}
+}
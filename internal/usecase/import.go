@@ -0,0 +1,239 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/importers"
+)
+
+const (
+	maxTitleLength = 128
+	maxURLLength   = 255
+
+	maxImportTagsLength = 1024
+)
+
+// ImportBookmarks saves bookmarks read from an export file. Entries are
+// placed into folders matching their original folder path, URLs that are
+// already saved are skipped and the import stops adding bookmarks once the
+// user reaches the bookmark limit.
+func (buc *bookmarkUseCase) ImportBookmarks(userID uint, source string, entries []importers.Bookmark) pkg.ImportResponse {
+	existing, err := buc.bookmarkRepo.GetBookmarksByUser(userID)
+	if err != nil {
+		buc.log.Error(context.Background(), "Import bookmarks: failed to get bookmarks by user", map[string]any{"error": err, "user_id": userID})
+		return pkg.ImportResponse{Code: http.StatusInternalServerError, Message: "failed to get bookmarks"}
+	}
+	saved := make(map[string]bool, len(existing))
+	for _, bookmark := range existing {
+		saved[buc.urls.Key(bookmark.URL)] = true
+	}
+
+	folders, err := newFolderResolver(buc, userID)
+	if err != nil {
+		buc.log.Error(context.Background(), "Import bookmarks: failed to get folders", map[string]any{"error": err, "user_id": userID})
+		return pkg.ImportResponse{Code: http.StatusInternalServerError, Message: "failed to get folders"}
+	}
+
+	resp := pkg.ImportResponse{Code: http.StatusOK, Items: make([]pkg.ImportItem, 0, len(entries))}
+	report := func(item pkg.ImportItem, status, reason string) {
+		item.Status, item.Reason = status, reason
+		resp.Items = append(resp.Items, item)
+		switch status {
+		case pkg.ImportStatusImported:
+			resp.Imported++
+		case pkg.ImportStatusSkipped:
+			resp.Skipped++
+		default:
+			resp.Failed++
+		}
+	}
+
+	// limitReached holds the reason once the plan has no room left.
+	limitReached := ""
+	for _, entry := range entries {
+		item := pkg.ImportItem{Title: entry.Title, URL: entry.URL, Folder: strings.Join(entry.FolderPath, " / ")}
+
+		parsed, err := url.Parse(entry.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			report(item, pkg.ImportStatusFailed, "unsupported or invalid URL")
+			continue
+		}
+		resourceURL := buc.urls.Canonical(entry.URL)
+		if len(resourceURL) > maxURLLength {
+			report(item, pkg.ImportStatusFailed, "URL is too long")
+			continue
+		}
+		key := buc.urls.Key(resourceURL)
+		if saved[key] {
+			report(item, pkg.ImportStatusSkipped, "duplicate URL")
+			continue
+		}
+		if limitReached != "" {
+			report(item, pkg.ImportStatusSkipped, limitReached)
+			continue
+		}
+
+		folderID, err := folders.resolve(entry.FolderPath)
+		if exceeded, ok := asQuotaExceeded(err); ok {
+			report(item, pkg.ImportStatusSkipped, exceeded.Error())
+			continue
+		}
+		if err != nil {
+			buc.log.Error(context.Background(), "Import bookmarks: failed to create folder", map[string]any{"error": err, "folder": item.Folder})
+			report(item, pkg.ImportStatusFailed, "failed to create folder")
+			continue
+		}
+
+		title := entry.Title
+		if title == "" {
+			title = parsed.Host
+		}
+		bookmark := domain.Bookmark{
+			UserID:        userID,
+			FolderID:      folderID,
+			Title:         truncateRunes(title, maxTitleLength),
+			URL:           resourceURL,
+			NormalizedURL: key,
+			ShowText:      entry.ShowText,
+			CreatedAt:     entry.AddDate,
+			ImportSource:  source,
+			ImportTags:    truncateRunes(entry.RawTags, maxImportTagsLength),
+		}
+		err = buc.uow.Do(func(repos repository.Repositories) error {
+			return createWithinQuota(repos, buc.quota, &bookmark)
+		})
+		if exceeded, ok := asQuotaExceeded(err); ok {
+			limitReached = exceeded.Error()
+			report(item, pkg.ImportStatusSkipped, limitReached)
+			continue
+		}
+		if errors.Is(err, repository.ErrDuplicateURL) {
+			report(item, pkg.ImportStatusSkipped, "duplicate URL")
+			continue
+		}
+		if err != nil {
+			buc.log.Error(context.Background(), "Import bookmarks: failed to create bookmark", map[string]any{"error": err})
+			report(item, pkg.ImportStatusFailed, "failed to save bookmark")
+			continue
+		}
+
+		saved[key] = true
+		buc.importTags(userID, bookmark.ID, entry.Tags)
+		report(item, pkg.ImportStatusImported, "")
+		buc.queueFavicon(bookmark)
+		buc.queueMetadata(bookmark, "")
+	}
+
+	buc.log.Info(context.Background(), "Import bookmarks: done", map[string]any{
+		"user_id":  userID,
+		"imported": resp.Imported,
+		"skipped":  resp.Skipped,
+		"failed":   resp.Failed,
+		"source":   source,
+	})
+	resp.Message = fmt.Sprintf("Imported %d of %d bookmarks", resp.Imported, len(entries))
+	return resp
+}
+
+// importTags attaches tags found in the import file. A failure here keeps
+// the bookmark, it is only logged.
+func (buc *bookmarkUseCase) importTags(userID, bookmarkID uint, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	tags := make([]domain.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, domain.Tag{Name: truncateRunes(name, maxTagLength)})
+	}
+	refs, resp := checkTags(buc.tagRepo, buc.log, userID, tags)
+	if resp.Code != http.StatusOK {
+		return
+	}
+	err := buc.uow.Do(func(repos repository.Repositories) error {
+		tagIDs, err := resolveTags(repos, buc.quota, userID, refs)
+		if err != nil {
+			return err
+		}
+		return repos.Bookmarks.ReplaceBookmarkTags(bookmarkID, tagIDs)
+	})
+	if _, ok := asQuotaExceeded(err); ok {
+		buc.log.Info(context.Background(), "Import bookmarks: limit of tags for user", map[string]any{"user_id": userID, "bookmarkID": bookmarkID})
+	} else if err != nil {
+		buc.log.Error(context.Background(), "Import bookmarks: failed to save tags", map[string]any{"error": err, "bookmarkID": bookmarkID})
+	}
+}
+
+// folderResolver maps folder paths from an import onto the user's folders,
+// creating the missing ones on first use.
+type folderResolver struct {
+	buc    *bookmarkUseCase
+	userID uint
+	byName map[folderKey]uint
+}
+
+type folderKey struct {
+	parent uint
+	name   string
+}
+
+func newFolderResolver(buc *bookmarkUseCase, userID uint) (*folderResolver, error) {
+	folders, err := buc.folderRepo.GetFoldersByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := &folderResolver{buc: buc, userID: userID, byName: make(map[folderKey]uint, len(folders))}
+	for _, folder := range folders {
+		key := folderKey{name: folder.Name}
+		if folder.ParentID != nil {
+			key.parent = *folder.ParentID
+		}
+		if _, ok := resolver.byName[key]; !ok {
+			resolver.byName[key] = folder.ID
+		}
+	}
+	return resolver, nil
+}
+
+func (fr *folderResolver) resolve(path []string) (*uint, error) {
+	var parent *uint
+	for _, name := range path {
+		name = truncateRunes(strings.TrimSpace(name), maxTitleLength)
+		if name == "" {
+			continue
+		}
+
+		key := folderKey{name: name}
+		if parent != nil {
+			key.parent = *parent
+		}
+		id, ok := fr.byName[key]
+		if !ok {
+			folder := domain.Folder{UserID: fr.userID, ParentID: parent, Name: name}
+			if err := createFolder(fr.buc.uow, fr.buc.quota, &folder); err != nil {
+				return nil, err
+			}
+			id = folder.ID
+			fr.byName[key] = id
+		}
+		parent = &id
+	}
+	return parent, nil
+}
+
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit])
+}
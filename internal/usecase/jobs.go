@@ -0,0 +1,8 @@
+package usecase
+
+// JobQueue stores background jobs for workers to run, see pkg/queue.
+type JobQueue interface {
+	// Enqueue stores a job of kind with payload as its JSON. host is the
+	// site the job sends requests to, if any.
+	Enqueue(kind, host string, payload any) error
+}
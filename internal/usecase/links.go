@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/parsers"
+	"github.com/OxytocinGroup/theca-backend/pkg/queue"
+)
+
+// linkUnchecked selects bookmarks not checked yet in GetLinkHealth.
+const linkUnchecked = "unchecked"
+
+// defaultHealthStatuses are the bookmarks GetLinkHealth lists unless told
+// otherwise: those that need fixing.
+var defaultHealthStatuses = []string{domain.LinkBroken, domain.LinkRedirected}
+
+// CheckLink records the health of a URL on the bookmarks saved under it.
+// Failures that may go away, such as timeouts and server errors, are
+// retried and only count on the last attempt.
+func (buc *bookmarkUseCase) CheckLink(ctx context.Context, job domain.Job) error {
+	var payload domain.CheckLinkPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return queue.Permanent(fmt.Errorf("decode payload: %w", err))
+	}
+
+	check, err := parsers.CheckLink(ctx, payload.URL)
+	status, transient := buc.linkStatus(payload.URL, check, err)
+	if transient && !job.LastAttempt() {
+		if err == nil {
+			err = fmt.Errorf("received response code %d", check.StatusCode)
+		}
+		return err
+	}
+
+	checkedAt := time.Now()
+	health := domain.Bookmark{LinkStatus: status, LinkStatusCode: check.StatusCode, LinkCheckedAt: &checkedAt}
+	if len(check.FinalURL) <= maxURLLength {
+		health.LinkFinalURL = check.FinalURL
+	}
+	if err := buc.bookmarkRepo.UpdateLinkHealth(payload.URL, health); err != nil {
+		return fmt.Errorf("update bookmarks: %w", err)
+	}
+	return nil
+}
+
+// linkStatus classifies the outcome of a link check and reports whether
+// it may change when checked again soon.
+func (buc *bookmarkUseCase) linkStatus(resourceURL string, check parsers.LinkCheck, err error) (string, bool) {
+	switch code := check.StatusCode; {
+	case errors.Is(err, parsers.ErrBlockedURL):
+		return domain.LinkUnknown, false
+	case err != nil:
+		return domain.LinkBroken, true
+	case code == http.StatusTooManyRequests:
+		return domain.LinkUnknown, true
+	case code >= http.StatusInternalServerError:
+		return domain.LinkBroken, true
+	// The page may well be there for people, just not for Theca.
+	case code == http.StatusUnauthorized || code == http.StatusForbidden || code == http.StatusProxyAuthRequired ||
+		code == http.StatusUnavailableForLegalReasons:
+		return domain.LinkUnknown, false
+	case code >= http.StatusBadRequest:
+		return domain.LinkBroken, false
+	// Redirects that keep the duplicate key, such as to https or to the
+	// www. host, are not worth a change of the bookmark.
+	case check.PermanentRedirect && buc.urls.Key(check.FinalURL) != buc.urls.Key(resourceURL):
+		return domain.LinkRedirected, false
+	}
+	return domain.LinkOK, false
+}
+
+// GetLinkHealth lists the user's bookmarks with one of statuses, the Link
+// constants or "unchecked", together with counts of every status.
+func (buc *bookmarkUseCase) GetLinkHealth(userID uint, statuses []string) pkg.BookmarkHealthResponse {
+	if len(statuses) == 0 {
+		statuses = defaultHealthStatuses
+	}
+	for _, status := range statuses {
+		if !slices.Contains([]string{domain.LinkOK, domain.LinkRedirected, domain.LinkBroken, domain.LinkUnknown, linkUnchecked}, status) {
+			return pkg.BookmarkHealthResponse{Code: http.StatusBadRequest, Message: fmt.Sprintf("unknown link status %q", status), Error: cerr.ErrInvalidBody}
+		}
+	}
+
+	bookmarks, err := buc.bookmarkRepo.GetBookmarksByUser(userID)
+	if err != nil {
+		buc.log.Error(context.Background(), "Get link health: failed to get bookmarks by user", map[string]any{"user_id": userID, "error": err})
+		return pkg.BookmarkHealthResponse{Code: http.StatusInternalServerError, Message: "failed to get bookmarks"}
+	}
+
+	resp := pkg.BookmarkHealthResponse{Code: http.StatusOK, Bookmarks: []domain.Bookmark{}}
+	for _, bookmark := range bookmarks {
+		status := bookmark.LinkStatus
+		switch status {
+		case domain.LinkOK:
+			resp.Summary.OK++
+		case domain.LinkRedirected:
+			resp.Summary.Redirected++
+		case domain.LinkBroken:
+			resp.Summary.Broken++
+		case domain.LinkUnknown:
+			resp.Summary.Unknown++
+		default:
+			resp.Summary.Unchecked++
+			status = linkUnchecked
+		}
+		if slices.Contains(statuses, status) {
+			resp.Bookmarks = append(resp.Bookmarks, bookmark)
+		}
+	}
+	return resp
+}
+
+// ApplyRedirects moves the user's redirected bookmarks among bookmarkIDs
+// to the URLs they redirect to. Bookmarks whose new URL is already saved
+// are left alone, they are duplicates to merge instead.
+func (buc *bookmarkUseCase) ApplyRedirects(userID uint, bookmarkIDs []uint) pkg.Response {
+	bookmarks, err := buc.bookmarkRepo.GetBookmarksByUser(userID)
+	if err != nil {
+		buc.log.Error(context.Background(), "Apply redirects: failed to get bookmarks by user", map[string]any{"user_id": userID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get bookmarks"}
+	}
+
+	updated, skipped := 0, 0
+	for _, bookmark := range bookmarks {
+		if !slices.Contains(bookmarkIDs, bookmark.ID) {
+			continue
+		}
+		if bookmark.LinkStatus != domain.LinkRedirected || bookmark.LinkFinalURL == "" {
+			skipped++
+			continue
+		}
+
+		resourceURL := buc.urls.Canonical(bookmark.LinkFinalURL)
+		key := buc.urls.Key(resourceURL)
+		if len(resourceURL) > maxURLLength {
+			skipped++
+			continue
+		}
+		if resp := buc.checkDuplicate(userID, bookmark.ID, key); resp.Code != http.StatusOK {
+			if resp.Code != http.StatusConflict {
+				return resp
+			}
+			skipped++
+			continue
+		}
+
+		err := buc.bookmarkRepo.ApplyRedirect(bookmark.ID, resourceURL, key)
+		if errors.Is(err, repository.ErrDuplicateURL) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			buc.log.Error(context.Background(), "Apply redirects: failed to update bookmark", map[string]any{"bookmarkID": bookmark.ID, "error": err})
+			return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to update bookmarks"}
+		}
+		updated++
+
+		bookmark.URL = resourceURL
+		buc.queueFavicon(bookmark)
+		buc.queueMetadata(bookmark, "")
+	}
+
+	buc.log.Info(context.Background(), "Apply redirects: done", map[string]any{"user_id": userID, "updated": updated, "skipped": skipped})
+	return pkg.Response{Code: http.StatusOK, Message: fmt.Sprintf("Updated %d bookmarks, skipped %d", updated, skipped)}
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/pkg/parsers"
+	"github.com/OxytocinGroup/theca-backend/pkg/urlnorm"
+)
+
+func TestLinkStatus(t *testing.T) {
+	buc := &bookmarkUseCase{urls: urlnorm.New(urlnorm.DefaultTrackingParams)}
+	const bookmarkURL = "http://example.com/docs"
+
+	tests := []struct {
+		name          string
+		check         parsers.LinkCheck
+		err           error
+		wantStatus    string
+		wantTransient bool
+	}{
+		{name: "ok", check: parsers.LinkCheck{StatusCode: 200}, wantStatus: domain.LinkOK},
+		{name: "no content", check: parsers.LinkCheck{StatusCode: 204}, wantStatus: domain.LinkOK},
+		{name: "not modified", check: parsers.LinkCheck{StatusCode: 304}, wantStatus: domain.LinkOK},
+		{name: "not found", check: parsers.LinkCheck{StatusCode: 404}, wantStatus: domain.LinkBroken},
+		{name: "gone", check: parsers.LinkCheck{StatusCode: 410}, wantStatus: domain.LinkBroken},
+		{name: "bad request", check: parsers.LinkCheck{StatusCode: 400}, wantStatus: domain.LinkBroken},
+		{name: "unauthorized", check: parsers.LinkCheck{StatusCode: 401}, wantStatus: domain.LinkUnknown},
+		{name: "forbidden", check: parsers.LinkCheck{StatusCode: 403}, wantStatus: domain.LinkUnknown},
+		{name: "proxy authentication", check: parsers.LinkCheck{StatusCode: 407}, wantStatus: domain.LinkUnknown},
+		{name: "unavailable for legal reasons", check: parsers.LinkCheck{StatusCode: 451}, wantStatus: domain.LinkUnknown},
+		{name: "too many requests", check: parsers.LinkCheck{StatusCode: 429}, wantStatus: domain.LinkUnknown, wantTransient: true},
+		{name: "server error", check: parsers.LinkCheck{StatusCode: 500}, wantStatus: domain.LinkBroken, wantTransient: true},
+		{name: "service unavailable", check: parsers.LinkCheck{StatusCode: 503}, wantStatus: domain.LinkBroken, wantTransient: true},
+		{name: "request failed", err: context.DeadlineExceeded, wantStatus: domain.LinkBroken, wantTransient: true},
+		{name: "blocked URL", err: fmt.Errorf("dial: %w", parsers.ErrBlockedURL), wantStatus: domain.LinkUnknown},
+		{
+			name:       "permanent redirect elsewhere",
+			check:      parsers.LinkCheck{StatusCode: 200, FinalURL: "https://example.com/manual", PermanentRedirect: true},
+			wantStatus: domain.LinkRedirected,
+		},
+		{
+			name:       "permanent redirect to https and www",
+			check:      parsers.LinkCheck{StatusCode: 200, FinalURL: "https://www.example.com/docs/", PermanentRedirect: true},
+			wantStatus: domain.LinkOK,
+		},
+		{
+			name:       "temporary redirect",
+			check:      parsers.LinkCheck{StatusCode: 200, FinalURL: "https://example.com/login"},
+			wantStatus: domain.LinkOK,
+		},
+		{
+			name:       "permanent redirect to a missing page",
+			check:      parsers.LinkCheck{StatusCode: 404, FinalURL: "https://example.com/manual", PermanentRedirect: true},
+			wantStatus: domain.LinkBroken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, transient := buc.linkStatus(bookmarkURL, tt.check, tt.err)
+			if status != tt.wantStatus || transient != tt.wantTransient {
+				t.Fatalf("linkStatus = %q, transient %v, want %q, transient %v", status, transient, tt.wantStatus, tt.wantTransient)
+			}
+		})
+	}
+}
+
+func TestLinkStatusErrorWins(t *testing.T) {
+	buc := &bookmarkUseCase{urls: urlnorm.New(nil)}
+	// A failed request has no status of its own, whatever came before.
+	status, transient := buc.linkStatus("https://example.com/", parsers.LinkCheck{StatusCode: 200}, errors.New("connection reset"))
+	if status != domain.LinkBroken || !transient {
+		t.Fatalf("linkStatus = %q, transient %v, want broken and transient", status, transient)
+	}
+}
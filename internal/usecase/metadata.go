@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/parsers"
+	"github.com/OxytocinGroup/theca-backend/pkg/queue"
+	"gorm.io/gorm"
+)
+
+// Column sizes of the metadata fields of domain.Bookmark.
+const (
+	maxDescriptionLength  = 1024
+	maxSiteNameLength     = 128
+	maxCanonicalURLLength = 255
+	maxImageURLLength     = 1024
+)
+
+// placeholderTitle names a bookmark saved without a title until the title
+// of its page is known: its URL without the scheme.
+func placeholderTitle(resourceURL string) string {
+	title := resourceURL
+	if parsed, err := url.Parse(resourceURL); err == nil && parsed.Host != "" {
+		title = strings.TrimSuffix(parsed.Host+parsed.RequestURI(), "/")
+	}
+	return truncateRunes(title, maxTitleLength)
+}
+
+// queueMetadata schedules reading the metadata of a saved bookmark's page.
+// A failure only costs the bookmark its preview, so it is logged.
+func (buc *bookmarkUseCase) queueMetadata(bookmark domain.Bookmark, placeholderTitle string) {
+	payload := domain.FetchMetadataPayload{BookmarkID: bookmark.ID, PlaceholderTitle: placeholderTitle}
+	if err := buc.jobs.Enqueue(domain.JobFetchMetadata, bookmarkHost(bookmark.URL), payload); err != nil {
+		buc.log.Error(context.Background(), "Queue metadata: failed to enqueue job", map[string]any{"error": err, "bookmarkID": bookmark.ID})
+	}
+}
+
+// FetchMetadata stores the metadata of the bookmark's page. Pages that
+// cannot be read leave the bookmark as it is; only pages that may load
+// later are retried.
+func (buc *bookmarkUseCase) FetchMetadata(ctx context.Context, job domain.Job) error {
+	var payload domain.FetchMetadataPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return queue.Permanent(fmt.Errorf("decode payload: %w", err))
+	}
+
+	bookmark, err := buc.bookmarkRepo.GetBookmarkByID(payload.BookmarkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get bookmark: %w", err)
+	}
+
+	metadata, err := parsers.FetchMetadata(ctx, bookmark.URL)
+	if errors.Is(err, parsers.ErrPageUnavailable) && !job.LastAttempt() {
+		return err
+	}
+	if err != nil {
+		buc.log.Info(context.Background(), "Fetch metadata: page not readable", map[string]any{"error": err, "bookmarkID": bookmark.ID})
+		return nil
+	}
+
+	// URLs are dropped rather than cut when too long, since a cut URL
+	// leads nowhere.
+	update := domain.Bookmark{
+		Title:       truncateRunes(metadata.Title, maxTitleLength),
+		Description: truncateRunes(metadata.Description, maxDescriptionLength),
+		SiteName:    truncateRunes(metadata.SiteName, maxSiteNameLength),
+	}
+	if len(metadata.CanonicalURL) <= maxCanonicalURLLength {
+		update.CanonicalURL = metadata.CanonicalURL
+	}
+	if len(metadata.ImageURL) <= maxImageURLLength {
+		update.ImageURL = metadata.ImageURL
+	}
+	if err := buc.bookmarkRepo.UpdateBookmarkMetadata(bookmark.ID, update, payload.PlaceholderTitle); err != nil {
+		return fmt.Errorf("update bookmark: %w", err)
+	}
+	return nil
+}
+
+func (buc *bookmarkUseCase) PreviewBookmark(ctx context.Context, resourceURL string) pkg.BookmarkPreviewResponse {
+	metadata, err := parsers.FetchMetadata(ctx, strings.TrimSpace(resourceURL))
+	switch {
+	case errors.Is(err, parsers.ErrBlockedURL):
+		buc.log.Info(context.Background(), "Preview bookmark: URL not allowed", map[string]any{"error": err, "url": resourceURL})
+		return pkg.BookmarkPreviewResponse{Code: http.StatusBadRequest, Message: "URL must be a public http or https address", Error: cerr.ErrInvalidURL}
+	case err != nil:
+		buc.log.Info(context.Background(), "Preview bookmark: failed to load page", map[string]any{"error": err, "url": resourceURL})
+		return pkg.BookmarkPreviewResponse{Code: http.StatusBadGateway, Message: "failed to load page", Error: cerr.ErrPageUnavailable}
+	}
+	return pkg.BookmarkPreviewResponse{Code: http.StatusOK, Preview: metadata}
+}
@@ -0,0 +1,463 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/config"
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/internal/utils/token"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"gorm.io/gorm"
+)
+
+const (
+	// A passkey ceremony must be finished within passkeyCeremonyTTL.
+	passkeyCeremonyTTL = 5 * time.Minute
+
+	maxPasskeys           = 20
+	maxPasskeyNameLength  = 64
+	webAuthnUserIDLength  = 8
+	passkeyCloneWarning   = "this passkey may have been copied; remove it and add it again"
+	passkeyInvalidMessage = "invalid passkey"
+)
+
+// Passkeys are discoverable WebAuthn credentials, so a user can log in
+// with one alone, without a username or password. They also answer the
+// second step of a password login.
+type PasskeyUseCase interface {
+	// BeginRegistration starts adding a passkey with the name to the user's
+	// account and returns the options for the browser.
+	BeginRegistration(userID uint, name string) pkg.PasskeyOptionsResponse
+	FinishRegistration(userID uint, ceremonyToken string, credential []byte) (domain.Passkey, pkg.Response)
+	ListPasskeys(userID uint) ([]domain.Passkey, pkg.Response)
+	RenamePasskey(userID, passkeyID uint, name string) pkg.Response
+	DeletePasskey(userID, passkeyID uint) pkg.Response
+	// BeginLogin starts a login with any passkey of the site.
+	BeginLogin(remember bool) pkg.PasskeyOptionsResponse
+	// FinishLogin returns the user whose passkey answered the ceremony and
+	// whether the session is remembered.
+	FinishLogin(ceremonyToken string, credential []byte) (*domain.User, bool, pkg.Response)
+	// BeginAssertion asks for one of the user's passkeys. It returns the
+	// options for the browser and the state to finish with.
+	BeginAssertion(userID uint) (*protocol.CredentialAssertion, []byte, pkg.Response)
+	// FinishAssertion checks that credential answers the assertion.
+	FinishAssertion(userID uint, session, credential []byte) pkg.Response
+}
+
+type passkeyUseCase struct {
+	userRepo    repository.UserRepository
+	passkeyRepo repository.PasskeyRepository
+	webAuthn    *webauthn.WebAuthn
+	log         logger.Logger
+}
+
+func NewPasskeyUseCase(userRepo repository.UserRepository, passkeyRepo repository.PasskeyRepository, cfg config.Config, log logger.Logger) (PasskeyUseCase, error) {
+	rpID, origins := cfg.WebAuthnRPID, cfg.WebAuthnOrigins
+	if rpID == "" || len(origins) == 0 {
+		appURL, err := url.Parse(cfg.AppURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse app URL: %w", err)
+		}
+		if rpID == "" {
+			rpID = appURL.Hostname()
+		}
+		if len(origins) == 0 {
+			origins = []string{appURL.Scheme + "://" + appURL.Host}
+		}
+	}
+
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: passkeyCeremonyTTL, TimeoutUVD: passkeyCeremonyTTL}
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: cfg.WebAuthnRPName,
+		RPOrigins:     origins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationPreferred,
+		},
+		Timeouts: webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &passkeyUseCase{
+		userRepo:    userRepo,
+		passkeyRepo: passkeyRepo,
+		webAuthn:    webAuthn,
+		log:         log,
+	}, nil
+}
+
+func (puc *passkeyUseCase) BeginRegistration(userID uint, name string) pkg.PasskeyOptionsResponse {
+	user, resp := puc.webAuthnUser(userID)
+	if resp.Code != http.StatusOK {
+		return pkg.PasskeyOptionsResponse{Code: resp.Code, Message: resp.Message, Error: resp.Error}
+	}
+	if len(user.passkeys) >= maxPasskeys {
+		return pkg.PasskeyOptionsResponse{Code: http.StatusConflict, Message: fmt.Sprintf("Limit of passkeys: %d", maxPasskeys), Error: cerr.ErrLimitOfPasskeys}
+	}
+
+	name = truncateRunes(strings.TrimSpace(name), maxPasskeyNameLength)
+	if name == "" {
+		name = fmt.Sprintf("Passkey %d", len(user.passkeys)+1)
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, len(user.passkeys))
+	for i, passkey := range user.passkeys {
+		exclusions[i] = passkeyCredential(passkey).Descriptor()
+	}
+	creation, session, err := puc.webAuthn.BeginRegistration(user, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		puc.log.Error(context.Background(), "Begin passkey registration: failed to create options", map[string]any{"user_id": userID, "error": err})
+		return pkg.PasskeyOptionsResponse{Code: http.StatusInternalServerError, Message: "failed to start passkey registration"}
+	}
+
+	ceremonyToken, err := puc.createCeremony(domain.PasskeyCeremony{Kind: domain.PasskeyRegistration, UserID: userID, Name: name}, session)
+	if err != nil {
+		puc.log.Error(context.Background(), "Begin passkey registration: failed to store ceremony", map[string]any{"user_id": userID, "error": err})
+		return pkg.PasskeyOptionsResponse{Code: http.StatusInternalServerError, Message: "failed to start passkey registration"}
+	}
+	return pkg.PasskeyOptionsResponse{Code: http.StatusOK, Ceremony: ceremonyToken, Options: creation}
+}
+
+func (puc *passkeyUseCase) FinishRegistration(userID uint, ceremonyToken string, credential []byte) (domain.Passkey, pkg.Response) {
+	ceremony, session, resp := puc.takeCeremony(ceremonyToken, domain.PasskeyRegistration)
+	if resp.Code != http.StatusOK {
+		return domain.Passkey{}, resp
+	}
+	if ceremony.UserID != userID {
+		return domain.Passkey{}, invalidCeremonyResponse()
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(credential))
+	if err != nil {
+		puc.log.Info(context.Background(), "Finish passkey registration: invalid credential", map[string]any{"user_id": userID, "error": err})
+		return domain.Passkey{}, pkg.Response{Code: http.StatusBadRequest, Message: passkeyInvalidMessage, Error: cerr.ErrInvalidPasskey}
+	}
+	user, resp := puc.webAuthnUser(userID)
+	if resp.Code != http.StatusOK {
+		return domain.Passkey{}, resp
+	}
+	created, err := puc.webAuthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		puc.log.Info(context.Background(), "Finish passkey registration: credential rejected", map[string]any{"user_id": userID, "error": err})
+		return domain.Passkey{}, pkg.Response{Code: http.StatusBadRequest, Message: passkeyInvalidMessage, Error: cerr.ErrInvalidPasskey}
+	}
+
+	transports := make([]string, len(created.Transport))
+	for i, transport := range created.Transport {
+		transports[i] = string(transport)
+	}
+	passkey := domain.Passkey{
+		UserID:          userID,
+		Name:            ceremony.Name,
+		CredentialID:    created.ID,
+		PublicKey:       created.PublicKey,
+		AttestationType: created.AttestationType,
+		AAGUID:          created.Authenticator.AAGUID,
+		Transports:      strings.Join(transports, ","),
+		SignCount:       created.Authenticator.SignCount,
+		UserVerified:    created.Flags.UserVerified,
+		BackupEligible:  created.Flags.BackupEligible,
+		BackedUp:        created.Flags.BackupState,
+	}
+	err = puc.passkeyRepo.CreatePasskey(&passkey)
+	if errors.Is(err, repository.ErrDuplicatePasskey) {
+		return domain.Passkey{}, pkg.Response{Code: http.StatusConflict, Message: "this passkey is already registered", Error: cerr.ErrPasskeyExists}
+	}
+	if err != nil {
+		puc.log.Error(context.Background(), "Finish passkey registration: failed to create passkey", map[string]any{"user_id": userID, "error": err})
+		return domain.Passkey{}, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to add passkey"}
+	}
+
+	puc.log.Info(context.Background(), "Finish passkey registration: success", map[string]any{"user_id": userID, "passkeyID": passkey.ID})
+	return passkey, pkg.Response{Code: http.StatusCreated, Message: "Passkey added"}
+}
+
+func (puc *passkeyUseCase) ListPasskeys(userID uint) ([]domain.Passkey, pkg.Response) {
+	passkeys, err := puc.passkeyRepo.GetPasskeysByUser(userID)
+	if err != nil {
+		puc.log.Error(context.Background(), "List passkeys: failed to get passkeys", map[string]any{"user_id": userID, "error": err})
+		return nil, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get passkeys"}
+	}
+	return passkeys, pkg.Response{Code: http.StatusOK}
+}
+
+func (puc *passkeyUseCase) RenamePasskey(userID, passkeyID uint, name string) pkg.Response {
+	name = truncateRunes(strings.TrimSpace(name), maxPasskeyNameLength)
+	if name == "" {
+		return pkg.Response{Code: http.StatusBadRequest, Message: "name is required", Error: cerr.ErrInvalidBody}
+	}
+	err := puc.passkeyRepo.RenamePasskey(userID, passkeyID, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkg.Response{Code: http.StatusNotFound, Message: "passkey not found", Error: cerr.ErrPasskeyNotFound}
+	}
+	if err != nil {
+		puc.log.Error(context.Background(), "Rename passkey: failed to update passkey", map[string]any{"user_id": userID, "passkeyID": passkeyID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to rename passkey"}
+	}
+	return pkg.Response{Code: http.StatusOK, Message: "Passkey renamed"}
+}
+
+func (puc *passkeyUseCase) DeletePasskey(userID, passkeyID uint) pkg.Response {
+	err := puc.passkeyRepo.DeletePasskey(userID, passkeyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkg.Response{Code: http.StatusNotFound, Message: "passkey not found", Error: cerr.ErrPasskeyNotFound}
+	}
+	if err != nil {
+		puc.log.Error(context.Background(), "Delete passkey: failed to delete passkey", map[string]any{"user_id": userID, "passkeyID": passkeyID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to delete passkey"}
+	}
+
+	puc.log.Info(context.Background(), "Delete passkey: success", map[string]any{"user_id": userID, "passkeyID": passkeyID})
+	return pkg.Response{Code: http.StatusOK, Message: "Passkey removed"}
+}
+
+func (puc *passkeyUseCase) BeginLogin(remember bool) pkg.PasskeyOptionsResponse {
+	// Without a password the passkey is both factors, so the authenticator
+	// must verify the user with a PIN or biometrics.
+	assertion, session, err := puc.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		puc.log.Error(context.Background(), "Begin passkey login: failed to create options", map[string]any{"error": err})
+		return pkg.PasskeyOptionsResponse{Code: http.StatusInternalServerError, Message: "failed to start passkey login"}
+	}
+
+	ceremonyToken, err := puc.createCeremony(domain.PasskeyCeremony{Kind: domain.PasskeyLogin, Remember: remember}, session)
+	if err != nil {
+		puc.log.Error(context.Background(), "Begin passkey login: failed to store ceremony", map[string]any{"error": err})
+		return pkg.PasskeyOptionsResponse{Code: http.StatusInternalServerError, Message: "failed to start passkey login"}
+	}
+	return pkg.PasskeyOptionsResponse{Code: http.StatusOK, Ceremony: ceremonyToken, Options: assertion}
+}
+
+func (puc *passkeyUseCase) FinishLogin(ceremonyToken string, credential []byte) (*domain.User, bool, pkg.Response) {
+	ceremony, session, resp := puc.takeCeremony(ceremonyToken, domain.PasskeyLogin)
+	if resp.Code != http.StatusOK {
+		return nil, false, resp
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(credential))
+	if err != nil {
+		puc.log.Info(context.Background(), "Finish passkey login: invalid credential", map[string]any{"error": err})
+		return nil, false, pkg.Response{Code: http.StatusBadRequest, Message: passkeyInvalidMessage, Error: cerr.ErrInvalidPasskey}
+	}
+
+	var user *webAuthnUser
+	validated, err := puc.webAuthn.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
+		if len(userHandle) != webAuthnUserIDLength {
+			return nil, errors.New("unknown user handle")
+		}
+		found, resp := puc.webAuthnUser(uint(binary.BigEndian.Uint64(userHandle)))
+		if resp.Code != http.StatusOK {
+			return nil, errors.New(resp.Message)
+		}
+		user = found
+		return found, nil
+	}, session, parsed)
+	if err != nil {
+		puc.log.Info(context.Background(), "Finish passkey login: credential rejected", map[string]any{"error": err})
+		return nil, false, pkg.Response{Code: http.StatusUnauthorized, Message: passkeyInvalidMessage, Error: cerr.ErrInvalidPasskey}
+	}
+
+	if resp := puc.recordUse(user, validated); resp.Code != http.StatusOK {
+		return nil, false, resp
+	}
+	puc.log.Info(context.Background(), "Finish passkey login: success", map[string]any{"user_id": user.user.ID})
+	return &user.user, ceremony.Remember, pkg.Response{Code: http.StatusOK}
+}
+
+func (puc *passkeyUseCase) BeginAssertion(userID uint) (*protocol.CredentialAssertion, []byte, pkg.Response) {
+	user, resp := puc.webAuthnUser(userID)
+	if resp.Code != http.StatusOK {
+		return nil, nil, resp
+	}
+	if len(user.passkeys) == 0 {
+		return nil, nil, pkg.Response{Code: http.StatusBadRequest, Message: "no passkeys registered", Error: cerr.ErrPasskeyNotFound}
+	}
+
+	assertion, session, err := puc.webAuthn.BeginLogin(user)
+	if err == nil {
+		var data []byte
+		if data, err = json.Marshal(session); err == nil {
+			return assertion, data, pkg.Response{Code: http.StatusOK}
+		}
+	}
+	puc.log.Error(context.Background(), "Begin passkey assertion: failed to create options", map[string]any{"user_id": userID, "error": err})
+	return nil, nil, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to ask for a passkey"}
+}
+
+func (puc *passkeyUseCase) FinishAssertion(userID uint, session, credential []byte) pkg.Response {
+	var sessionData webauthn.SessionData
+	if err := json.Unmarshal(session, &sessionData); err != nil {
+		puc.log.Error(context.Background(), "Finish passkey assertion: failed to read session", map[string]any{"user_id": userID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to verify passkey"}
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(credential))
+	if err != nil {
+		puc.log.Info(context.Background(), "Finish passkey assertion: invalid credential", map[string]any{"user_id": userID, "error": err})
+		return pkg.Response{Code: http.StatusBadRequest, Message: passkeyInvalidMessage, Error: cerr.ErrInvalidPasskey}
+	}
+	user, resp := puc.webAuthnUser(userID)
+	if resp.Code != http.StatusOK {
+		return resp
+	}
+
+	validated, err := puc.webAuthn.ValidateLogin(user, sessionData, parsed)
+	if err != nil {
+		puc.log.Info(context.Background(), "Finish passkey assertion: credential rejected", map[string]any{"user_id": userID, "error": err})
+		return pkg.Response{Code: http.StatusUnauthorized, Message: passkeyInvalidMessage, Error: cerr.ErrInvalidPasskey}
+	}
+	return puc.recordUse(user, validated)
+}
+
+// recordUse stores the authenticator's state after the passkey was used.
+// A passkey whose signature counter went backwards is refused from then on.
+func (puc *passkeyUseCase) recordUse(user *webAuthnUser, validated *webauthn.Credential) pkg.Response {
+	for _, passkey := range user.passkeys {
+		if !bytes.Equal(passkey.CredentialID, validated.ID) {
+			continue
+		}
+		passkey.SignCount = validated.Authenticator.SignCount
+		passkey.CloneWarning = validated.Authenticator.CloneWarning
+		passkey.UserVerified = validated.Flags.UserVerified
+		passkey.BackedUp = validated.Flags.BackupState
+		if err := puc.passkeyRepo.RecordPasskeyUse(passkey, time.Now()); err != nil {
+			puc.log.Error(context.Background(), "Record passkey use: failed to update passkey", map[string]any{"user_id": user.user.ID, "passkeyID": passkey.ID, "error": err})
+			return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to verify passkey"}
+		}
+		if passkey.CloneWarning {
+			puc.log.Info(context.Background(), "Record passkey use: signature counter went backwards", map[string]any{"user_id": user.user.ID, "passkeyID": passkey.ID})
+			return pkg.Response{Code: http.StatusUnauthorized, Message: passkeyCloneWarning, Error: cerr.ErrInvalidPasskey}
+		}
+		return pkg.Response{Code: http.StatusOK}
+	}
+	return pkg.Response{Code: http.StatusUnauthorized, Message: passkeyInvalidMessage, Error: cerr.ErrInvalidPasskey}
+}
+
+// webAuthnUser loads the user with their passkeys.
+func (puc *passkeyUseCase) webAuthnUser(userID uint) (*webAuthnUser, pkg.Response) {
+	user, err := puc.userRepo.GetByID(userID)
+	if err != nil {
+		puc.log.Info(context.Background(), "Passkeys: user not found", map[string]any{"user_id": userID, "error": err})
+		return nil, pkg.Response{Code: http.StatusNotFound, Message: "user not found", Error: cerr.ErrInvalidUser}
+	}
+	passkeys, err := puc.passkeyRepo.GetPasskeysByUser(userID)
+	if err != nil {
+		puc.log.Error(context.Background(), "Passkeys: failed to get passkeys", map[string]any{"user_id": userID, "error": err})
+		return nil, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get passkeys"}
+	}
+	return &webAuthnUser{user: user, passkeys: passkeys}, pkg.Response{Code: http.StatusOK}
+}
+
+func (puc *passkeyUseCase) createCeremony(ceremony domain.PasskeyCeremony, session *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+	ceremonyToken, err := token.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	ceremony.ID = token.Hash(ceremonyToken)
+	ceremony.Session = data
+	ceremony.ExpiresAt = time.Now().Add(passkeyCeremonyTTL)
+	if err := puc.passkeyRepo.CreateCeremony(&ceremony); err != nil {
+		return "", err
+	}
+	return ceremonyToken, nil
+}
+
+// takeCeremony ends the unexpired ceremony of the token, returning it with
+// its WebAuthn state.
+func (puc *passkeyUseCase) takeCeremony(ceremonyToken, kind string) (domain.PasskeyCeremony, webauthn.SessionData, pkg.Response) {
+	var session webauthn.SessionData
+	ceremony, err := puc.passkeyRepo.TakeCeremony(token.Hash(ceremonyToken), kind, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ceremony, session, invalidCeremonyResponse()
+	}
+	if err == nil {
+		err = json.Unmarshal(ceremony.Session, &session)
+	}
+	if err != nil {
+		puc.log.Error(context.Background(), "Passkeys: failed to get ceremony", map[string]any{"error": err})
+		return ceremony, session, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to verify passkey"}
+	}
+	return ceremony, session, pkg.Response{Code: http.StatusOK}
+}
+
+func invalidCeremonyResponse() pkg.Response {
+	return pkg.Response{Code: http.StatusBadRequest, Message: "passkey request expired, try again", Error: cerr.ErrInvalidChallenge}
+}
+
+// webAuthnUser presents a user and their passkeys to the WebAuthn library.
+// The user handle stored in passkeys is the user's ID as 8 big-endian
+// bytes, which tells nothing about the user.
+type webAuthnUser struct {
+	user     domain.User
+	passkeys []domain.Passkey
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(u.user.ID))
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.passkeys))
+	for i, passkey := range u.passkeys {
+		credentials[i] = passkeyCredential(passkey)
+	}
+	return credentials
+}
+
+func passkeyCredential(passkey domain.Passkey) webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	for _, transport := range strings.Split(passkey.Transports, ",") {
+		if transport != "" {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+	}
+	return webauthn.Credential{
+		ID:              passkey.CredentialID,
+		PublicKey:       passkey.PublicKey,
+		AttestationType: passkey.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			UserPresent:    true,
+			UserVerified:   passkey.UserVerified,
+			BackupEligible: passkey.BackupEligible,
+			BackupState:    passkey.BackedUp,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:       passkey.AAGUID,
+			SignCount:    passkey.SignCount,
+			CloneWarning: passkey.CloneWarning,
+		},
+	}
+}
@@ -0,0 +1,619 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/config"
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"gorm.io/gorm"
+)
+
+const (
+	testRPID   = "theca.example"
+	testOrigin = "https://theca.example"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(context.Context, string, map[string]any) {}
+func (nopLogger) Info(context.Context, string, map[string]any)  {}
+func (nopLogger) Warn(context.Context, string, map[string]any)  {}
+func (nopLogger) Error(context.Context, string, map[string]any) {}
+
+// fakeUserRepository knows every user ID, with TOTP enabled unless
+// withoutTOTP is set.
+type fakeUserRepository struct {
+	repository.UserRepository
+	withoutTOTP bool
+}
+
+func (r fakeUserRepository) GetByID(id uint) (domain.User, error) {
+	return domain.User{ID: id, Username: fmt.Sprintf("user%d", id), TOTPEnabled: !r.withoutTOTP}, nil
+}
+
+type fakePasskeyRepository struct {
+	repository.PasskeyRepository
+	passkeys   []domain.Passkey
+	ceremonies map[string]domain.PasskeyCeremony
+}
+
+func newFakePasskeyRepository() *fakePasskeyRepository {
+	return &fakePasskeyRepository{ceremonies: map[string]domain.PasskeyCeremony{}}
+}
+
+func (r *fakePasskeyRepository) CreatePasskey(passkey *domain.Passkey) error {
+	for _, existing := range r.passkeys {
+		if bytes.Equal(existing.CredentialID, passkey.CredentialID) {
+			return repository.ErrDuplicatePasskey
+		}
+	}
+	passkey.ID = uint(len(r.passkeys) + 1)
+	r.passkeys = append(r.passkeys, *passkey)
+	return nil
+}
+
+func (r *fakePasskeyRepository) GetPasskeysByUser(userID uint) ([]domain.Passkey, error) {
+	var passkeys []domain.Passkey
+	for _, passkey := range r.passkeys {
+		if passkey.UserID == userID {
+			passkeys = append(passkeys, passkey)
+		}
+	}
+	return passkeys, nil
+}
+
+func (r *fakePasskeyRepository) CountPasskeys(userID uint) (int64, error) {
+	passkeys, err := r.GetPasskeysByUser(userID)
+	return int64(len(passkeys)), err
+}
+
+func (r *fakePasskeyRepository) RecordPasskeyUse(passkey domain.Passkey, usedAt time.Time) error {
+	for i := range r.passkeys {
+		if r.passkeys[i].ID == passkey.ID {
+			passkey.LastUsedAt = &usedAt
+			r.passkeys[i] = passkey
+		}
+	}
+	return nil
+}
+
+func (r *fakePasskeyRepository) CreateCeremony(ceremony *domain.PasskeyCeremony) error {
+	r.ceremonies[ceremony.ID] = *ceremony
+	return nil
+}
+
+func (r *fakePasskeyRepository) TakeCeremony(ceremonyID, kind string, now time.Time) (domain.PasskeyCeremony, error) {
+	ceremony, ok := r.ceremonies[ceremonyID]
+	delete(r.ceremonies, ceremonyID)
+	if !ok || ceremony.Kind != kind || !ceremony.ExpiresAt.After(now) {
+		return domain.PasskeyCeremony{}, gorm.ErrRecordNotFound
+	}
+	return ceremony, nil
+}
+
+type fakeTwoFactorRepository struct {
+	repository.TwoFactorRepository
+	challenges map[string]domain.LoginChallenge
+}
+
+func (r *fakeTwoFactorRepository) CreateChallenge(challenge *domain.LoginChallenge) error {
+	r.challenges[challenge.ID] = *challenge
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) GetChallenge(challengeID string, maxAttempts int, now time.Time) (domain.LoginChallenge, error) {
+	challenge, ok := r.challenges[challengeID]
+	if !ok || challenge.Attempts >= maxAttempts || !challenge.ExpiresAt.After(now) {
+		return domain.LoginChallenge{}, gorm.ErrRecordNotFound
+	}
+	return challenge, nil
+}
+
+func (r *fakeTwoFactorRepository) SetChallengePasskeySession(challengeID string, session []byte) error {
+	challenge := r.challenges[challengeID]
+	challenge.PasskeySession = session
+	r.challenges[challengeID] = challenge
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) UseChallengeAttempt(challengeID string, maxAttempts int, now time.Time) (domain.LoginChallenge, error) {
+	challenge, err := r.GetChallenge(challengeID, maxAttempts, now)
+	if err != nil {
+		return challenge, err
+	}
+	challenge.Attempts++
+	r.challenges[challengeID] = challenge
+	return challenge, nil
+}
+
+func (r *fakeTwoFactorRepository) DeleteChallenge(challengeID string) error {
+	delete(r.challenges, challengeID)
+	return nil
+}
+
+// softwareAuthenticator plays a browser with a platform authenticator
+// holding one ES256 passkey, attesting with "none".
+type softwareAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+	origin       string
+}
+
+func newSoftwareAuthenticator(t *testing.T, credentialID string) *softwareAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &softwareAuthenticator{key: key, credentialID: []byte(credentialID), origin: testOrigin}
+}
+
+var b64url = base64.RawURLEncoding
+
+func (a *softwareAuthenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *softwareAuthenticator) clientData(t *testing.T, kind string, challenge []byte) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{"type": kind, "challenge": b64url.EncodeToString(challenge), "origin": a.origin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func (a *softwareAuthenticator) credential(t *testing.T, response map[string]string) []byte {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{
+		"id":       b64url.EncodeToString(a.credentialID),
+		"rawId":    b64url.EncodeToString(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// create answers navigator.credentials.create() with the options.
+func (a *softwareAuthenticator) create(t *testing.T, options any) []byte {
+	t.Helper()
+	creation, ok := options.(*protocol.CredentialCreation)
+	if !ok {
+		t.Fatalf("options are %T, not creation options", options)
+	}
+	a.userHandle = creation.Response.User.ID.(protocol.URLEncodedBase64)
+
+	publicKey, err := cbor.Marshal(map[int]any{
+		1: 2, 3: -7, -1: 1, // EC2 key, ES256, P-256
+		-2: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attested := make([]byte, 16) // zero AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	// User present, user verified, attested credential data.
+	attestation, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(0x01|0x04|0x40, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    b64url.EncodeToString(a.clientData(t, "webauthn.create", creation.Response.Challenge)),
+		"attestationObject": b64url.EncodeToString(attestation),
+	})
+}
+
+// get answers navigator.credentials.get() with the options, counting one
+// more signature.
+func (a *softwareAuthenticator) get(t *testing.T, options any) []byte {
+	t.Helper()
+	assertion, ok := options.(*protocol.CredentialAssertion)
+	if !ok {
+		t.Fatalf("options are %T, not assertion options", options)
+	}
+	a.signCount++
+
+	clientData := a.clientData(t, "webauthn.get", assertion.Response.Challenge)
+	authData := a.authenticatorData(0x01|0x04, nil)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    b64url.EncodeToString(clientData),
+		"authenticatorData": b64url.EncodeToString(authData),
+		"signature":         b64url.EncodeToString(signature),
+		"userHandle":        b64url.EncodeToString(a.userHandle),
+	})
+}
+
+func newTestPasskeyUseCase(t *testing.T, passkeyRepo repository.PasskeyRepository) PasskeyUseCase {
+	t.Helper()
+	cfg := config.Config{AppURL: testOrigin + "/app", WebAuthnRPName: "Theca"}
+	passkeys, err := NewPasskeyUseCase(fakeUserRepository{}, passkeyRepo, cfg, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return passkeys
+}
+
+// register adds the authenticator's passkey to the user's account.
+func register(t *testing.T, passkeys PasskeyUseCase, userID uint, authenticator *softwareAuthenticator) domain.Passkey {
+	t.Helper()
+	begin := passkeys.BeginRegistration(userID, "")
+	if begin.Code != http.StatusOK {
+		t.Fatalf("BeginRegistration: %+v", begin)
+	}
+	passkey, resp := passkeys.FinishRegistration(userID, begin.Ceremony, authenticator.create(t, begin.Options))
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("FinishRegistration: %+v", resp)
+	}
+	return passkey
+}
+
+func TestPasskeyRegistration(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, passkeys PasskeyUseCase, authenticator *softwareAuthenticator)
+		finish   func(t *testing.T, passkeys PasskeyUseCase, ceremony string, credential []byte) (domain.Passkey, int, string)
+		origin   string
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:     "new passkey",
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "already registered",
+			setup: func(t *testing.T, passkeys PasskeyUseCase, authenticator *softwareAuthenticator) {
+				register(t, passkeys, 42, authenticator)
+			},
+			wantCode: http.StatusConflict,
+			wantErr:  cerr.ErrPasskeyExists,
+		},
+		{
+			name:     "other origin",
+			origin:   "https://evil.example",
+			wantCode: http.StatusBadRequest,
+			wantErr:  cerr.ErrInvalidPasskey,
+		},
+		{
+			name: "ceremony of another user",
+			finish: func(t *testing.T, passkeys PasskeyUseCase, ceremony string, credential []byte) (domain.Passkey, int, string) {
+				passkey, resp := passkeys.FinishRegistration(7, ceremony, credential)
+				return passkey, resp.Code, resp.Error
+			},
+			wantCode: http.StatusBadRequest,
+			wantErr:  cerr.ErrInvalidChallenge,
+		},
+		{
+			name: "ceremony replayed",
+			finish: func(t *testing.T, passkeys PasskeyUseCase, ceremony string, credential []byte) (domain.Passkey, int, string) {
+				if _, resp := passkeys.FinishRegistration(42, ceremony, credential); resp.Code != http.StatusCreated {
+					t.Fatalf("first FinishRegistration: %+v", resp)
+				}
+				passkey, resp := passkeys.FinishRegistration(42, ceremony, credential)
+				return passkey, resp.Code, resp.Error
+			},
+			wantCode: http.StatusBadRequest,
+			wantErr:  cerr.ErrInvalidChallenge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passkeyRepo := newFakePasskeyRepository()
+			passkeys := newTestPasskeyUseCase(t, passkeyRepo)
+			authenticator := newSoftwareAuthenticator(t, "credential-1")
+			if tt.setup != nil {
+				tt.setup(t, passkeys, authenticator)
+			}
+			if tt.origin != "" {
+				authenticator.origin = tt.origin
+			}
+
+			begin := passkeys.BeginRegistration(42, "Laptop")
+			if begin.Code != http.StatusOK {
+				t.Fatalf("BeginRegistration: %+v", begin)
+			}
+			credential := authenticator.create(t, begin.Options)
+
+			var passkey domain.Passkey
+			var code int
+			var errCode string
+			if tt.finish != nil {
+				passkey, code, errCode = tt.finish(t, passkeys, begin.Ceremony, credential)
+			} else {
+				var resp pkg.Response
+				passkey, resp = passkeys.FinishRegistration(42, begin.Ceremony, credential)
+				code, errCode = resp.Code, resp.Error
+			}
+			if code != tt.wantCode || errCode != tt.wantErr {
+				t.Fatalf("got %d %q, want %d %q", code, errCode, tt.wantCode, tt.wantErr)
+			}
+			if code == http.StatusCreated && (passkey.Name != "Laptop" || passkey.UserID != 42 || !bytes.Equal(passkey.CredentialID, authenticator.credentialID)) {
+				t.Fatalf("stored passkey %+v", passkey)
+			}
+		})
+	}
+}
+
+func TestPasskeyRegistrationDefaultName(t *testing.T) {
+	passkeyRepo := newFakePasskeyRepository()
+	passkeys := newTestPasskeyUseCase(t, passkeyRepo)
+
+	for i, want := range []string{"Passkey 1", "Passkey 2"} {
+		passkey := register(t, passkeys, 42, newSoftwareAuthenticator(t, fmt.Sprintf("credential-%d", i)))
+		if passkey.Name != want {
+			t.Errorf("name %q, want %q", passkey.Name, want)
+		}
+	}
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	tests := []struct {
+		name string
+		// answer signs the login, changing the authenticator first.
+		answer   func(t *testing.T, authenticator, other *softwareAuthenticator, options any) []byte
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name: "passkey of the user",
+			answer: func(t *testing.T, authenticator, _ *softwareAuthenticator, options any) []byte {
+				return authenticator.get(t, options)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "sign counter replayed",
+			answer: func(t *testing.T, authenticator, _ *softwareAuthenticator, options any) []byte {
+				authenticator.signCount--
+				return authenticator.get(t, options)
+			},
+			wantCode: http.StatusUnauthorized,
+			wantErr:  cerr.ErrInvalidPasskey,
+		},
+		{
+			name: "sign counter decreased",
+			answer: func(t *testing.T, authenticator, _ *softwareAuthenticator, options any) []byte {
+				authenticator.signCount = 0
+				return authenticator.get(t, options)
+			},
+			wantCode: http.StatusUnauthorized,
+			wantErr:  cerr.ErrInvalidPasskey,
+		},
+		{
+			name: "credential of another user claiming the user",
+			answer: func(t *testing.T, authenticator, other *softwareAuthenticator, options any) []byte {
+				other.userHandle = authenticator.userHandle
+				return other.get(t, options)
+			},
+			wantCode: http.StatusUnauthorized,
+			wantErr:  cerr.ErrInvalidPasskey,
+		},
+		{
+			name: "signed by another key",
+			answer: func(t *testing.T, authenticator, other *softwareAuthenticator, options any) []byte {
+				authenticator.key = other.key
+				return authenticator.get(t, options)
+			},
+			wantCode: http.StatusUnauthorized,
+			wantErr:  cerr.ErrInvalidPasskey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passkeyRepo := newFakePasskeyRepository()
+			passkeys := newTestPasskeyUseCase(t, passkeyRepo)
+			authenticator := newSoftwareAuthenticator(t, "credential-42")
+			other := newSoftwareAuthenticator(t, "credential-7")
+			register(t, passkeys, 42, authenticator)
+			register(t, passkeys, 7, other)
+
+			// A first login stores sign count 10.
+			authenticator.signCount = 9
+			login := passkeys.BeginLogin(false)
+			if _, _, resp := passkeys.FinishLogin(login.Ceremony, authenticator.get(t, login.Options)); resp.Code != http.StatusOK {
+				t.Fatalf("first FinishLogin: %+v", resp)
+			}
+
+			login = passkeys.BeginLogin(true)
+			if login.Code != http.StatusOK {
+				t.Fatalf("BeginLogin: %+v", login)
+			}
+			user, remember, resp := passkeys.FinishLogin(login.Ceremony, tt.answer(t, authenticator, other, login.Options))
+			if resp.Code != tt.wantCode || resp.Error != tt.wantErr {
+				t.Fatalf("got %+v, want %d %q", resp, tt.wantCode, tt.wantErr)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if user.ID != 42 || !remember {
+				t.Fatalf("logged in user %d, remember %v", user.ID, remember)
+			}
+			if stored := passkeyRepo.passkeys[0]; stored.SignCount != authenticator.signCount || stored.LastUsedAt == nil {
+				t.Fatalf("stored passkey %+v", stored)
+			}
+		})
+	}
+}
+
+func TestPasskeyLoginCeremonyReplayed(t *testing.T) {
+	passkeys := newTestPasskeyUseCase(t, newFakePasskeyRepository())
+	authenticator := newSoftwareAuthenticator(t, "credential-42")
+	register(t, passkeys, 42, authenticator)
+
+	login := passkeys.BeginLogin(false)
+	credential := authenticator.get(t, login.Options)
+	if _, _, resp := passkeys.FinishLogin(login.Ceremony, credential); resp.Code != http.StatusOK {
+		t.Fatalf("first FinishLogin: %+v", resp)
+	}
+	if _, _, resp := passkeys.FinishLogin(login.Ceremony, credential); resp.Code != http.StatusBadRequest || resp.Error != cerr.ErrInvalidChallenge {
+		t.Fatalf("replayed FinishLogin: %+v", resp)
+	}
+}
+
+func TestPasskeySecondFactor(t *testing.T) {
+	tests := []struct {
+		name string
+		// answer signs the assertion of the challenge for user 42.
+		answer   func(t *testing.T, authenticator, other *softwareAuthenticator, options any) []byte
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name: "passkey of the user",
+			answer: func(t *testing.T, authenticator, _ *softwareAuthenticator, options any) []byte {
+				return authenticator.get(t, options)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "passkey of another user",
+			answer: func(t *testing.T, _, other *softwareAuthenticator, options any) []byte {
+				return other.get(t, options)
+			},
+			wantCode: http.StatusUnauthorized,
+			wantErr:  cerr.ErrInvalidPasskey,
+		},
+		{
+			name: "sign counter decreased",
+			answer: func(t *testing.T, authenticator, _ *softwareAuthenticator, options any) []byte {
+				authenticator.signCount = 0
+				return authenticator.get(t, options)
+			},
+			wantCode: http.StatusUnauthorized,
+			wantErr:  cerr.ErrInvalidPasskey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passkeyRepo := newFakePasskeyRepository()
+			passkeys := newTestPasskeyUseCase(t, passkeyRepo)
+			authenticator := newSoftwareAuthenticator(t, "credential-42")
+			other := newSoftwareAuthenticator(t, "credential-7")
+			register(t, passkeys, 42, authenticator)
+			register(t, passkeys, 7, other)
+			// A first login stores sign count 10.
+			authenticator.signCount = 9
+			login := passkeys.BeginLogin(false)
+			if _, _, resp := passkeys.FinishLogin(login.Ceremony, authenticator.get(t, login.Options)); resp.Code != http.StatusOK {
+				t.Fatalf("first FinishLogin: %+v", resp)
+			}
+
+			twoFactorRepo := &fakeTwoFactorRepository{challenges: map[string]domain.LoginChallenge{}}
+			twoFactor := NewTwoFactorUseCase(fakeUserRepository{}, twoFactorRepo, passkeyRepo, passkeys, config.Config{}, nopLogger{})
+			challenge, methods, resp := twoFactor.CreateChallenge(42, false)
+			if resp.Code != http.StatusOK {
+				t.Fatalf("CreateChallenge: %+v", resp)
+			}
+			if len(methods) != 3 || methods[2] != "passkey" {
+				t.Fatalf("methods %v", methods)
+			}
+
+			begin := twoFactor.BeginPasskeyChallenge(challenge)
+			if begin.Code != http.StatusOK {
+				t.Fatalf("BeginPasskeyChallenge: %+v", begin)
+			}
+			user, _, resp := twoFactor.VerifyChallenge(challenge, "", tt.answer(t, authenticator, other, begin.Options))
+			if resp.Code != tt.wantCode || resp.Error != tt.wantErr {
+				t.Fatalf("got %+v, want %d %q", resp, tt.wantCode, tt.wantErr)
+			}
+			if tt.wantCode == http.StatusOK && user.ID != 42 {
+				t.Fatalf("logged in user %d", user.ID)
+			}
+		})
+	}
+}
+
+func TestPasskeySecondFactorWithoutAssertion(t *testing.T) {
+	passkeyRepo := newFakePasskeyRepository()
+	passkeys := newTestPasskeyUseCase(t, passkeyRepo)
+	authenticator := newSoftwareAuthenticator(t, "credential-42")
+	register(t, passkeys, 42, authenticator)
+
+	// A credential signed for a passkey login does not answer a challenge
+	// that never asked for a passkey.
+	twoFactorRepo := &fakeTwoFactorRepository{challenges: map[string]domain.LoginChallenge{}}
+	twoFactor := NewTwoFactorUseCase(fakeUserRepository{}, twoFactorRepo, passkeyRepo, passkeys, config.Config{}, nopLogger{})
+	challenge, _, _ := twoFactor.CreateChallenge(42, false)
+	login := passkeys.BeginLogin(false)
+	_, _, resp := twoFactor.VerifyChallenge(challenge, "", authenticator.get(t, login.Options))
+	if resp.Code != http.StatusBadRequest || resp.Error != cerr.ErrInvalidChallenge {
+		t.Fatalf("got %+v", resp)
+	}
+}
+
+func TestPasskeySecondFactorWithoutTOTP(t *testing.T) {
+	passkeyRepo := newFakePasskeyRepository()
+	passkeys := newTestPasskeyUseCase(t, passkeyRepo)
+	authenticator := newSoftwareAuthenticator(t, "credential-42")
+	register(t, passkeys, 42, authenticator)
+
+	twoFactorRepo := &fakeTwoFactorRepository{challenges: map[string]domain.LoginChallenge{}}
+	twoFactor := NewTwoFactorUseCase(fakeUserRepository{withoutTOTP: true}, twoFactorRepo, passkeyRepo, passkeys, config.Config{}, nopLogger{})
+
+	// Without TOTP or a passkey the password is enough.
+	if challenge, methods, resp := twoFactor.CreateChallenge(7, false); resp.Code != http.StatusOK || challenge != "" || len(methods) != 0 {
+		t.Fatalf("CreateChallenge without a second factor = %q, %v, %+v", challenge, methods, resp)
+	}
+
+	challenge, methods, resp := twoFactor.CreateChallenge(42, false)
+	if resp.Code != http.StatusOK || challenge == "" {
+		t.Fatalf("CreateChallenge: %+v", resp)
+	}
+	if len(methods) != 1 || methods[0] != "passkey" {
+		t.Fatalf("methods %v, want only passkey", methods)
+	}
+	if _, _, resp := twoFactor.VerifyChallenge(challenge, "123456", nil); resp.Code != http.StatusUnauthorized || resp.Error != cerr.ErrInvalidOTP {
+		t.Fatalf("code accepted without TOTP: %+v", resp)
+	}
+
+	begin := twoFactor.BeginPasskeyChallenge(challenge)
+	if begin.Code != http.StatusOK {
+		t.Fatalf("BeginPasskeyChallenge: %+v", begin)
+	}
+	user, _, resp := twoFactor.VerifyChallenge(challenge, "", authenticator.get(t, begin.Options))
+	if resp.Code != http.StatusOK || user.ID != 42 {
+		t.Fatalf("VerifyChallenge = %+v, want user 42 logged in", resp)
+	}
+}
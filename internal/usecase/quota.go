@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"gorm.io/gorm"
+)
+
+// Resource is something a plan limits the amount of.
+type Resource string
+
+const (
+	ResourceBookmarks Resource = "bookmarks"
+	ResourceFolders   Resource = "folders"
+	ResourceTags      Resource = "tags"
+)
+
+// QuotaExceededError is returned when a user's plan has no room left for a
+// new item of Resource.
+type QuotaExceededError struct {
+	Resource Resource
+	Limit    int
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("limit of %s: %d", e.Resource, e.Limit)
+}
+
+// QuotaService checks user actions against the limits of their plan.
+type QuotaService interface {
+	// Reserve checks that the user may add n items of resource. It locks
+	// the user row, so it has to run inside the transaction that adds
+	// them; concurrent requests of the same user then wait for each other.
+	Reserve(repos repository.Repositories, userID uint, resource Resource, n int) error
+	Usage(userID uint) (domain.QuotaUsage, error)
+}
+
+type quotaService struct {
+	repos repository.Repositories
+}
+
+// NewQuotaService returns a QuotaService that reads usage through repos
+// outside of transactions.
+func NewQuotaService(repos repository.Repositories) QuotaService {
+	return &quotaService{repos: repos}
+}
+
+func (qs *quotaService) Reserve(repos repository.Repositories, userID uint, resource Resource, n int) error {
+	user, err := repos.Users.GetByIDForUpdate(userID)
+	if err != nil {
+		return fmt.Errorf("get user: %w", err)
+	}
+	plan, err := planOf(repos, user)
+	if err != nil {
+		return err
+	}
+
+	limit := planLimit(plan, resource)
+	if limit == domain.Unlimited {
+		return nil
+	}
+	used, err := resourceUsed(repos, user, resource)
+	if err != nil {
+		return err
+	}
+	if used+int64(n) > int64(limit) {
+		return &QuotaExceededError{Resource: resource, Limit: limit}
+	}
+	return nil
+}
+
+func (qs *quotaService) Usage(userID uint) (domain.QuotaUsage, error) {
+	user, err := qs.repos.Users.GetByID(userID)
+	if err != nil {
+		return domain.QuotaUsage{}, fmt.Errorf("get user: %w", err)
+	}
+	plan, err := planOf(qs.repos, user)
+	if err != nil {
+		return domain.QuotaUsage{}, err
+	}
+
+	usage := domain.QuotaUsage{Plan: plan.Name}
+	for resource, target := range map[Resource]*domain.ResourceUsage{
+		ResourceBookmarks: &usage.Bookmarks,
+		ResourceFolders:   &usage.Folders,
+		ResourceTags:      &usage.Tags,
+	} {
+		used, err := resourceUsed(qs.repos, user, resource)
+		if err != nil {
+			return domain.QuotaUsage{}, err
+		}
+		*target = domain.ResourceUsage{Used: used, Limit: planLimit(plan, resource)}
+	}
+	return usage, nil
+}
+
+// planOf returns the user's plan. Users without one, or with a plan that
+// no longer exists, are on the free plan.
+func planOf(repos repository.Repositories, user domain.User) (domain.Plan, error) {
+	if user.PlanID != nil {
+		plan, err := repos.Plans.GetPlanByID(*user.PlanID)
+		if err == nil {
+			return plan, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.Plan{}, fmt.Errorf("get plan: %w", err)
+		}
+	}
+
+	plan, err := repos.Plans.GetPlanByName(domain.PlanFree)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.DefaultPlans[0], nil
+	}
+	if err != nil {
+		return domain.Plan{}, fmt.Errorf("get plan: %w", err)
+	}
+	return plan, nil
+}
+
+func planLimit(plan domain.Plan, resource Resource) int {
+	switch resource {
+	case ResourceBookmarks:
+		return plan.MaxBookmarks
+	case ResourceFolders:
+		return plan.MaxFolders
+	default:
+		return plan.MaxTags
+	}
+}
+
+func resourceUsed(repos repository.Repositories, user domain.User, resource Resource) (int64, error) {
+	switch resource {
+	case ResourceBookmarks:
+		return int64(user.AmountOfBookmarks), nil
+	case ResourceFolders:
+		return repos.Folders.CountFoldersByUser(user.ID)
+	default:
+		return repos.Tags.CountTagsByUser(user.ID)
+	}
+}
+
+// quotaExceededResponse is the response for a create request rejected by
+// the user's plan.
+func quotaExceededResponse(err *QuotaExceededError) pkg.Response {
+	code := cerr.ErrLimitOfBookmarks
+	switch err.Resource {
+	case ResourceFolders:
+		code = cerr.ErrLimitOfFolders
+	case ResourceTags:
+		code = cerr.ErrLimitOfTags
+	}
+	return pkg.Response{Code: http.StatusConflict, Message: fmt.Sprintf("Limit of %s: %d", err.Resource, err.Limit), Error: code}
+}
+
+// asQuotaExceeded reports whether err was caused by a plan limit.
+func asQuotaExceeded(err error) (*QuotaExceededError, bool) {
+	var exceeded *QuotaExceededError
+	ok := errors.As(err, &exceeded)
+	return exceeded, ok
+}
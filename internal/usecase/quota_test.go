@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"gorm.io/gorm"
+)
+
+type quotaUserRepository struct {
+	repository.UserRepository
+	user domain.User
+}
+
+func (r quotaUserRepository) GetByIDForUpdate(uint) (domain.User, error) { return r.user, nil }
+
+// quotaPlanRepository stores plans under their ID; the free plan may be
+// missing, as before plans were seeded.
+type quotaPlanRepository struct {
+	repository.PlanRepository
+	plans map[uint]domain.Plan
+}
+
+func (r quotaPlanRepository) GetPlanByID(id uint) (domain.Plan, error) {
+	plan, ok := r.plans[id]
+	if !ok {
+		return domain.Plan{}, gorm.ErrRecordNotFound
+	}
+	return plan, nil
+}
+
+func (r quotaPlanRepository) GetPlanByName(name string) (domain.Plan, error) {
+	for _, plan := range r.plans {
+		if plan.Name == name {
+			return plan, nil
+		}
+	}
+	return domain.Plan{}, gorm.ErrRecordNotFound
+}
+
+type quotaFolderRepository struct {
+	repository.FolderRepository
+	count int64
+}
+
+func (r quotaFolderRepository) CountFoldersByUser(uint) (int64, error) { return r.count, nil }
+
+type quotaTagRepository struct {
+	repository.TagRepository
+	count int64
+}
+
+func (r quotaTagRepository) CountTagsByUser(uint) (int64, error) { return r.count, nil }
+
+func TestQuotaReserve(t *testing.T) {
+	seeded := map[uint]domain.Plan{
+		1: {ID: 1, Name: domain.PlanFree, MaxBookmarks: 3, MaxFolders: 2, MaxTags: 1},
+		2: {ID: 2, Name: domain.PlanPro, MaxBookmarks: 10, MaxFolders: 10, MaxTags: 10},
+		3: {ID: 3, Name: domain.PlanUnlimited, MaxBookmarks: domain.Unlimited, MaxFolders: domain.Unlimited, MaxTags: domain.Unlimited},
+	}
+	planID := func(id uint) *uint { return &id }
+
+	tests := []struct {
+		name      string
+		user      domain.User
+		plans     map[uint]domain.Plan
+		used      int64
+		resource  Resource
+		n         int
+		wantLimit int
+	}{
+		{name: "room left", user: domain.User{AmountOfBookmarks: 2}, plans: seeded, resource: ResourceBookmarks, n: 1},
+		{name: "limit reached", user: domain.User{AmountOfBookmarks: 3}, plans: seeded, resource: ResourceBookmarks, n: 1, wantLimit: 3},
+		{name: "several at once", user: domain.User{AmountOfBookmarks: 2}, plans: seeded, resource: ResourceBookmarks, n: 2, wantLimit: 3},
+		{name: "folders", plans: seeded, used: 2, resource: ResourceFolders, n: 1, wantLimit: 2},
+		{name: "tags", plans: seeded, used: 0, resource: ResourceTags, n: 1},
+		{name: "tags limit", plans: seeded, used: 1, resource: ResourceTags, n: 1, wantLimit: 1},
+		{name: "pro plan", user: domain.User{PlanID: planID(2), AmountOfBookmarks: 3}, plans: seeded, resource: ResourceBookmarks, n: 1},
+		{name: "unlimited plan", user: domain.User{PlanID: planID(3), AmountOfBookmarks: 1 << 20}, plans: seeded, resource: ResourceBookmarks, n: 1},
+		{name: "deleted plan counts as free", user: domain.User{PlanID: planID(9), AmountOfBookmarks: 3}, plans: seeded, resource: ResourceBookmarks, n: 1, wantLimit: 3},
+		{
+			name:      "default free plan before seeding",
+			user:      domain.User{AmountOfBookmarks: uint(domain.DefaultPlans[0].MaxBookmarks)},
+			plans:     map[uint]domain.Plan{},
+			resource:  ResourceBookmarks,
+			n:         1,
+			wantLimit: domain.DefaultPlans[0].MaxBookmarks,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := repository.Repositories{
+				Users:   quotaUserRepository{user: tt.user},
+				Plans:   quotaPlanRepository{plans: tt.plans},
+				Folders: quotaFolderRepository{count: tt.used},
+				Tags:    quotaTagRepository{count: tt.used},
+			}
+			err := NewQuotaService(repos).Reserve(repos, 1, tt.resource, tt.n)
+
+			exceeded, ok := asQuotaExceeded(err)
+			if tt.wantLimit == 0 {
+				if err != nil {
+					t.Fatalf("Reserve = %v, want room", err)
+				}
+				return
+			}
+			if !ok || exceeded.Resource != tt.resource || exceeded.Limit != tt.wantLimit {
+				t.Fatalf("Reserve = %v, want the %s limit of %d", err, tt.resource, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestQuotaExceededResponse(t *testing.T) {
+	tests := []struct {
+		resource Resource
+		want     string
+	}{
+		{ResourceBookmarks, cerr.ErrLimitOfBookmarks},
+		{ResourceFolders, cerr.ErrLimitOfFolders},
+		{ResourceTags, cerr.ErrLimitOfTags},
+	}
+	for _, tt := range tests {
+		wrapped := errors.Join(errors.New("create"), &QuotaExceededError{Resource: tt.resource, Limit: 5})
+		exceeded, ok := asQuotaExceeded(wrapped)
+		if !ok {
+			t.Fatalf("asQuotaExceeded(%v) = false", wrapped)
+		}
+		if resp := quotaExceededResponse(exceeded); resp.Code != http.StatusConflict || resp.Error != tt.want {
+			t.Errorf("%s: response %d %q, want 409 %q", tt.resource, resp.Code, resp.Error, tt.want)
+		}
+	}
+}
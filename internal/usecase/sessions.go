@@ -3,48 +3,180 @@ package usecase
 import (
 	"context"
 	"errors"
+	"net/http"
 	"time"
 
+	"github.com/OxytocinGroup/theca-backend/internal/config"
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
 	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/internal/utils/token"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
 	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+const (
+	// sessionRotationInterval is how long a session token is used before
+	// it is replaced. After a rotation the old token is still accepted for
+	// sessionRotationGrace, for requests that were already in flight.
+	sessionRotationInterval = 24 * time.Hour
+	sessionRotationGrace    = time.Minute
+
+	maxUserAgentLength = 512
+)
+
+// Sessions are identified by a random token kept in the session cookie.
+// Only its hash is stored, as the session's ID, so the sessions table
+// cannot be used to log in.
 type SessionUseCase interface {
-	CreateSession(sessionID string, userID uint, expiresAt time.Time) error
-	ValidateSession(sessionID string) (uint, error)
+	// CreateSession opens a session from the device with the user agent
+	// and IP address and returns its token and expiry. Remembered sessions
+	// follow the long timeouts.
+	CreateSession(userID uint, remember bool, userAgent, ip string) (string, time.Time, error)
+	// ValidateSession returns the unexpired session of the token.
+	ValidateSession(sessionToken string) (domain.Session, error)
+	// RefreshSession records use of the session of sessionToken, extending
+	// its expiry, and rotates its token once it is old enough. session is
+	// updated to match. It returns the token the session cookie should be
+	// set to with the new expiry, or "" when the cookie is still right.
+	RefreshSession(session *domain.Session, sessionToken string) (string, error)
+	// DeleteSession ends the session with the ID, the hash of its token.
 	DeleteSession(sessionID string) error
 	DeleteAllSessions(userID uint) error
+	// ListSessions returns the user's active sessions, marking the one of
+	// currentSessionID.
+	ListSessions(userID uint, currentSessionID string) ([]domain.Session, pkg.Response)
+	// RevokeSession ends the user's session with the handle.
+	RevokeSession(userID uint, handle string) pkg.Response
 }
 
 type sessionUseCase struct {
 	sessionRepo repository.SessionRepository
+	cfg         config.Config
 	log         logger.Logger
 }
 
-func NewSessionUseCase(repo repository.SessionRepository, log logger.Logger) SessionUseCase {
+func NewSessionUseCase(repo repository.SessionRepository, cfg config.Config, log logger.Logger) SessionUseCase {
 	return &sessionUseCase{
 		sessionRepo: repo,
+		cfg:         cfg,
 		log:         log,
 	}
 }
 
-func (suc *sessionUseCase) CreateSession(sessionID string, userID uint, expiresAt time.Time) error {
-	return suc.sessionRepo.CreateSession(sessionID, userID, expiresAt)
+// timeouts returns the idle timeout and the maximum lifetime of sessions
+// with or without remember me.
+func (suc *sessionUseCase) timeouts(remember bool) (time.Duration, time.Duration) {
+	if remember {
+		return suc.cfg.SessionRememberIdleTimeout, suc.cfg.SessionRememberMaxAge
+	}
+	return suc.cfg.SessionIdleTimeout, suc.cfg.SessionMaxAge
 }
 
-func (suc *sessionUseCase) ValidateSession(sessionID string) (uint, error) {
+func (suc *sessionUseCase) CreateSession(userID uint, remember bool, userAgent, ip string) (string, time.Time, error) {
+	sessionToken, err := token.GenerateToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	idleTimeout, maxAge := suc.timeouts(remember)
+	session := domain.Session{
+		ID:           token.Hash(sessionToken),
+		UserID:       userID,
+		MaxExpiresAt: now.Add(maxAge),
+		Remember:     remember,
+		Handle:       uuid.New().String(),
+		UserAgent:    truncateRunes(userAgent, maxUserAgentLength),
+		IP:           ip,
+		LastSeenAt:   now,
+		RotatedAt:    now,
+	}
+	session.ExpiresAt = minTime(now.Add(idleTimeout), session.MaxExpiresAt)
+	if err := suc.sessionRepo.CreateSession(&session); err != nil {
+		return "", time.Time{}, err
+	}
+	return sessionToken, session.ExpiresAt, nil
+}
+
+func (suc *sessionUseCase) ValidateSession(sessionToken string) (domain.Session, error) {
+	sessionID := token.Hash(sessionToken)
 	session, err := suc.sessionRepo.GetSessionByID(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		session, err = suc.sessionRepo.GetSessionByPreviousID(sessionID, time.Now().Add(-sessionRotationGrace))
+	}
 	if err != nil {
-		suc.log.Info(context.Background(), "failed to get session by id", map[string]any{
-			"sessionID": sessionID,
-		})
-		return 0, err
+		suc.log.Info(context.Background(), "Validate session: failed to get session", map[string]any{"error": err})
+		return domain.Session{}, err
 	}
 
 	if session.ExpiresAt.Before(time.Now()) {
-		return 0, errors.New("session expired")
+		return domain.Session{}, errors.New("session expired")
+	}
+	return session, nil
+}
+
+func (suc *sessionUseCase) RefreshSession(session *domain.Session, sessionToken string) (string, error) {
+	cookieToken, err := suc.rotate(session)
+	if err != nil {
+		return "", err
+	}
+
+	// Extending at most every SessionRefreshInterval keeps requests from
+	// each writing to the sessions table, at the cost of sessions expiring
+	// up to that much early.
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) < suc.cfg.SessionRefreshInterval {
+		return cookieToken, nil
+	}
+	idleTimeout, _ := suc.timeouts(session.Remember)
+	expiresAt := minTime(now.Add(idleTimeout), session.MaxExpiresAt)
+	if err := suc.sessionRepo.TouchSession(session.ID, now, expiresAt); err != nil {
+		return cookieToken, err
+	}
+	session.LastSeenAt, session.ExpiresAt = now, expiresAt
+
+	// A request still sending the token replaced by a rotation must not
+	// set the cookie back to it.
+	if cookieToken == "" && token.Hash(sessionToken) == session.ID {
+		cookieToken = sessionToken
+	}
+	return cookieToken, nil
+}
+
+// rotate gives the session a new token once its token is old enough and
+// returns it, or "" when the token is kept.
+func (suc *sessionUseCase) rotate(session *domain.Session) (string, error) {
+	now := time.Now()
+	if now.Sub(session.RotatedAt) < sessionRotationInterval {
+		return "", nil
+	}
+
+	sessionToken, err := token.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	newID := token.Hash(sessionToken)
+	err = suc.sessionRepo.RotateSession(session.ID, newID, now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// A concurrent request rotated the session first; its response
+		// carries the new token.
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	session.PreviousID, session.ID, session.RotatedAt = session.ID, newID, now
+	return sessionToken, nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
 	}
-	return session.UserID, nil
+	return a
 }
 
 func (suc *sessionUseCase) DeleteSession(sessionID string) error {
@@ -54,3 +186,29 @@ func (suc *sessionUseCase) DeleteSession(sessionID string) error {
 func (suc *sessionUseCase) DeleteAllSessions(userID uint) error {
 	return suc.sessionRepo.DeleteAllSessions(userID)
 }
+
+func (suc *sessionUseCase) ListSessions(userID uint, currentSessionID string) ([]domain.Session, pkg.Response) {
+	sessions, err := suc.sessionRepo.GetSessionsByUser(userID)
+	if err != nil {
+		suc.log.Error(context.Background(), "List sessions: failed to get sessions", map[string]any{"user_id": userID, "error": err})
+		return nil, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get sessions"}
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, pkg.Response{Code: http.StatusOK}
+}
+
+func (suc *sessionUseCase) RevokeSession(userID uint, handle string) pkg.Response {
+	err := suc.sessionRepo.DeleteUserSession(userID, handle)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkg.Response{Code: http.StatusNotFound, Message: "session not found", Error: cerr.ErrSessionNotFound}
+	}
+	if err != nil {
+		suc.log.Error(context.Background(), "Revoke session: failed to delete session", map[string]any{"user_id": userID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to revoke session"}
+	}
+
+	suc.log.Info(context.Background(), "Revoke session: success", map[string]any{"user_id": userID})
+	return pkg.Response{Code: http.StatusOK, Message: "Session revoked"}
+}
@@ -0,0 +1,278 @@
+package usecase
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/config"
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/internal/utils/token"
+	"gorm.io/gorm"
+)
+
+// memorySessionRepository keeps sessions in a map under their ID.
+type memorySessionRepository struct {
+	repository.SessionRepository
+	sessions map[string]domain.Session
+}
+
+func newMemorySessionRepository() *memorySessionRepository {
+	return &memorySessionRepository{sessions: map[string]domain.Session{}}
+}
+
+func (r *memorySessionRepository) CreateSession(session *domain.Session) error {
+	r.sessions[session.ID] = *session
+	return nil
+}
+
+func (r *memorySessionRepository) GetSessionByID(sessionID string) (domain.Session, error) {
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return domain.Session{}, gorm.ErrRecordNotFound
+	}
+	return session, nil
+}
+
+func (r *memorySessionRepository) GetSessionsByUser(userID uint) ([]domain.Session, error) {
+	var sessions []domain.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *memorySessionRepository) DeleteUserSession(userID uint, handle string) error {
+	for id, session := range r.sessions {
+		if session.UserID == userID && session.Handle == handle {
+			delete(r.sessions, id)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *memorySessionRepository) GetSessionByPreviousID(previousID string, rotatedAfter time.Time) (domain.Session, error) {
+	for _, session := range r.sessions {
+		if session.PreviousID == previousID && session.RotatedAt.After(rotatedAfter) {
+			return session, nil
+		}
+	}
+	return domain.Session{}, gorm.ErrRecordNotFound
+}
+
+func (r *memorySessionRepository) RotateSession(oldID, newID string, rotatedAt time.Time) error {
+	session, ok := r.sessions[oldID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.sessions, oldID)
+	session.PreviousID, session.ID, session.RotatedAt = oldID, newID, rotatedAt
+	r.sessions[newID] = session
+	return nil
+}
+
+func (r *memorySessionRepository) TouchSession(sessionID string, seenAt, expiresAt time.Time) error {
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	session.LastSeenAt, session.ExpiresAt = seenAt, expiresAt
+	r.sessions[sessionID] = session
+	return nil
+}
+
+func TestListSessionsMarksCurrent(t *testing.T) {
+	repo := newMemorySessionRepository()
+	repo.sessions["a"] = domain.Session{ID: "a", UserID: 1}
+	repo.sessions["b"] = domain.Session{ID: "b", UserID: 1}
+	repo.sessions["c"] = domain.Session{ID: "c", UserID: 2}
+	suc := NewSessionUseCase(repo, config.Config{}, nopLogger{})
+
+	sessions, resp := suc.ListSessions(1, "b")
+	if resp.Code != http.StatusOK || len(sessions) != 2 {
+		t.Fatalf("ListSessions = %d sessions, %d, want 2 sessions", len(sessions), resp.Code)
+	}
+	for _, session := range sessions {
+		if session.Current != (session.ID == "b") {
+			t.Errorf("session %s current = %v", session.ID, session.Current)
+		}
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	repo := newMemorySessionRepository()
+	repo.sessions["a"] = domain.Session{ID: "a", UserID: 1, Handle: "laptop"}
+	repo.sessions["b"] = domain.Session{ID: "b", UserID: 2, Handle: "phone"}
+	suc := NewSessionUseCase(repo, config.Config{}, nopLogger{})
+
+	tests := []struct {
+		name   string
+		handle string
+		want   int
+	}{
+		{name: "another user's session", handle: "phone", want: http.StatusNotFound},
+		{name: "own session", handle: "laptop", want: http.StatusOK},
+		{name: "already revoked", handle: "laptop", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if resp := suc.RevokeSession(1, tt.handle); resp.Code != tt.want {
+			t.Errorf("%s: RevokeSession = %d, want %d", tt.name, resp.Code, tt.want)
+		}
+	}
+	if _, ok := repo.sessions["b"]; !ok {
+		t.Error("another user's session was revoked")
+	}
+}
+
+func TestSessionTokenStoredHashed(t *testing.T) {
+	repo := newMemorySessionRepository()
+	cfg := config.Config{SessionIdleTimeout: time.Hour, SessionMaxAge: 24 * time.Hour}
+	suc := NewSessionUseCase(repo, cfg, nopLogger{})
+
+	sessionToken, _, err := suc.CreateSession(1, false, "Firefox", "192.0.2.1")
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if _, ok := repo.sessions[sessionToken]; ok {
+		t.Fatal("session stored under its token")
+	}
+	if _, ok := repo.sessions[token.Hash(sessionToken)]; !ok {
+		t.Fatal("session not stored under the hash of its token")
+	}
+	if session, err := suc.ValidateSession(sessionToken); err != nil || session.UserID != 1 {
+		t.Fatalf("ValidateSession = %+v, %v", session, err)
+	}
+	if _, err := suc.ValidateSession(token.Hash(sessionToken)); err == nil {
+		t.Fatal("the stored hash is accepted as a token")
+	}
+}
+
+func TestRefreshSessionRotatesToken(t *testing.T) {
+	repo := newMemorySessionRepository()
+	cfg := config.Config{SessionIdleTimeout: time.Hour, SessionMaxAge: 24 * time.Hour, SessionRefreshInterval: time.Hour}
+	suc := NewSessionUseCase(repo, cfg, nopLogger{})
+	now := time.Now()
+
+	const oldToken = "old-token"
+	repo.sessions[token.Hash(oldToken)] = domain.Session{
+		ID:           token.Hash(oldToken),
+		UserID:       1,
+		ExpiresAt:    now.Add(time.Hour),
+		MaxExpiresAt: now.Add(time.Hour),
+		LastSeenAt:   now,
+		RotatedAt:    now.Add(-sessionRotationInterval),
+	}
+
+	session, err := suc.ValidateSession(oldToken)
+	if err != nil {
+		t.Fatalf("ValidateSession: %v", err)
+	}
+	newToken, err := suc.RefreshSession(&session, oldToken)
+	if err != nil || newToken == "" {
+		t.Fatalf("RefreshSession = %q, %v, want a new token", newToken, err)
+	}
+	if session.ID != token.Hash(newToken) || session.PreviousID != token.Hash(oldToken) {
+		t.Fatal("session not updated to the new token")
+	}
+	if _, err := suc.ValidateSession(newToken); err != nil {
+		t.Fatalf("new token rejected: %v", err)
+	}
+	// Requests sent before the rotation still carry the old token.
+	if _, err := suc.ValidateSession(oldToken); err != nil {
+		t.Fatalf("old token rejected within the grace period: %v", err)
+	}
+
+	// A fresh token is kept.
+	if again, err := suc.RefreshSession(&session, newToken); err != nil || again != "" {
+		t.Fatalf("RefreshSession of a fresh token = %q, %v, want it kept", again, err)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	cfg := config.Config{
+		SessionIdleTimeout:         time.Hour,
+		SessionMaxAge:              8 * time.Hour,
+		SessionRememberIdleTimeout: 7 * 24 * time.Hour,
+		SessionRememberMaxAge:      30 * 24 * time.Hour,
+		SessionRefreshInterval:     time.Minute,
+	}
+	tests := []struct {
+		name     string
+		remember bool
+		want     time.Duration
+	}{
+		{name: "idle timeout", want: time.Hour},
+		{name: "remember me", remember: true, want: 7 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemorySessionRepository()
+			suc := NewSessionUseCase(repo, cfg, nopLogger{})
+			before := time.Now()
+			sessionToken, expiresAt, err := suc.CreateSession(1, tt.remember, "", "")
+			if err != nil {
+				t.Fatalf("CreateSession: %v", err)
+			}
+			if expiresAt.Before(before.Add(tt.want)) || expiresAt.After(time.Now().Add(tt.want)) {
+				t.Fatalf("session expires in %v, want %v", time.Until(expiresAt), tt.want)
+			}
+			if stored := repo.sessions[token.Hash(sessionToken)]; stored.Remember != tt.remember || !stored.ExpiresAt.Equal(expiresAt) {
+				t.Fatalf("stored session %+v", stored)
+			}
+		})
+	}
+}
+
+func TestRefreshSessionExtendsExpiry(t *testing.T) {
+	cfg := config.Config{SessionIdleTimeout: time.Hour, SessionMaxAge: 8 * time.Hour, SessionRefreshInterval: time.Minute}
+	now := time.Now()
+	tests := []struct {
+		name          string
+		lastSeen      time.Duration
+		maxExpires    time.Duration
+		wantExpiresIn time.Duration
+	}{
+		{name: "used recently", lastSeen: 10 * time.Second, maxExpires: 8 * time.Hour, wantExpiresIn: 30 * time.Minute},
+		{name: "extended by the idle timeout", lastSeen: 30 * time.Minute, maxExpires: 8 * time.Hour, wantExpiresIn: time.Hour},
+		{name: "capped by the maximum age", lastSeen: 30 * time.Minute, maxExpires: 10 * time.Minute, wantExpiresIn: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemorySessionRepository()
+			suc := NewSessionUseCase(repo, cfg, nopLogger{})
+			const sessionToken = "token"
+			session := domain.Session{
+				ID:           token.Hash(sessionToken),
+				ExpiresAt:    now.Add(30 * time.Minute),
+				MaxExpiresAt: now.Add(tt.maxExpires),
+				LastSeenAt:   now.Add(-tt.lastSeen),
+				RotatedAt:    now,
+			}
+			repo.sessions[session.ID] = session
+
+			if _, err := suc.RefreshSession(&session, sessionToken); err != nil {
+				t.Fatalf("RefreshSession: %v", err)
+			}
+			want := now.Add(tt.wantExpiresIn)
+			if d := session.ExpiresAt.Sub(want); d < 0 || d > time.Second {
+				t.Fatalf("session expires at %v, want %v", session.ExpiresAt, want)
+			}
+			if !repo.sessions[session.ID].ExpiresAt.Equal(session.ExpiresAt) {
+				t.Fatal("new expiry not stored")
+			}
+		})
+	}
+}
+
+func TestValidateSessionRejectsExpired(t *testing.T) {
+	repo := newMemorySessionRepository()
+	repo.sessions[token.Hash("token")] = domain.Session{ID: token.Hash("token"), ExpiresAt: time.Now().Add(-time.Second)}
+	suc := NewSessionUseCase(repo, config.Config{}, nopLogger{})
+	if _, err := suc.ValidateSession("token"); err == nil {
+		t.Fatal("expired session accepted")
+	}
+}
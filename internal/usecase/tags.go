@@ -0,0 +1,293 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// maxTagLength is the longest tag name, in characters.
+const maxTagLength = 64
+
+type TagUseCase interface {
+	CreateTag(userID uint, name string) pkg.Response
+	GetTagsByUser(userID uint) ([]domain.Tag, pkg.Response)
+	RenameTag(userID, tagID uint, name string) pkg.Response
+	MergeTags(userID uint, sourceIDs []uint, targetID uint) pkg.Response
+	DeleteTag(userID, tagID uint) pkg.Response
+}
+
+type tagUseCase struct {
+	tagRepo repository.TagRepository
+	uow     repository.UnitOfWork
+	quota   QuotaService
+	log     logger.Logger
+}
+
+func NewTagUseCase(tagRepo repository.TagRepository, uow repository.UnitOfWork, quota QuotaService, log logger.Logger) TagUseCase {
+	return &tagUseCase{
+		tagRepo: tagRepo,
+		uow:     uow,
+		quota:   quota,
+		log:     log,
+	}
+}
+
+func (tuc *tagUseCase) CreateTag(userID uint, name string) pkg.Response {
+	name = strings.TrimSpace(name)
+	if resp := checkTagName(name); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	if resp := tuc.checkNameFree(userID, name); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	err := createTag(tuc.uow, tuc.quota, &domain.Tag{UserID: userID, Name: name})
+	if exceeded, ok := asQuotaExceeded(err); ok {
+		tuc.log.Info(context.Background(), "Create tag: limit of tags for user", map[string]any{"user_id": userID})
+		return quotaExceededResponse(exceeded)
+	}
+	if err != nil {
+		tuc.log.Error(context.Background(), "Create tag: failed to create tag", map[string]any{"error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "Failed to create tag"}
+	}
+
+	tuc.log.Info(context.Background(), "Create tag: created successfully", map[string]any{})
+	return pkg.Response{Code: http.StatusCreated, Message: "tag created successfully"}
+}
+
+func (tuc *tagUseCase) GetTagsByUser(userID uint) ([]domain.Tag, pkg.Response) {
+	tags, err := tuc.tagRepo.GetTagsByUser(userID)
+	if err != nil {
+		tuc.log.Error(context.Background(), "Get tags by user: failed to get tags", map[string]any{"user_id": userID, "error": err})
+		return nil, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get tags"}
+	}
+	return tags, pkg.Response{Code: http.StatusOK}
+}
+
+func (tuc *tagUseCase) RenameTag(userID, tagID uint, name string) pkg.Response {
+	name = strings.TrimSpace(name)
+	if resp := checkTagName(name); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	tag, resp := tuc.getOwnedTag(userID, tagID)
+	if resp.Code != http.StatusOK {
+		return resp
+	}
+	if tag.Name == name {
+		return pkg.Response{Code: http.StatusOK, Message: "Tag renamed"}
+	}
+
+	if resp := tuc.checkNameFree(userID, name); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	tag.Name = name
+	if err := tuc.tagRepo.UpdateTag(&tag); err != nil {
+		tuc.log.Error(context.Background(), "Rename tag: failed to update tag", map[string]any{"tagID": tagID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to rename tag"}
+	}
+
+	return pkg.Response{Code: http.StatusOK, Message: "Tag renamed"}
+}
+
+func (tuc *tagUseCase) MergeTags(userID uint, sourceIDs []uint, targetID uint) pkg.Response {
+	if _, resp := tuc.getOwnedTag(userID, targetID); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	var sources []uint
+	for _, id := range sourceIDs {
+		if id != targetID {
+			sources = append(sources, id)
+		}
+	}
+	if len(sources) == 0 {
+		return pkg.Response{Code: http.StatusBadRequest, Message: "nothing to merge", Error: cerr.ErrInvalidBody}
+	}
+
+	tags, err := tuc.tagRepo.GetTagsByIDs(sources)
+	if err != nil {
+		tuc.log.Error(context.Background(), "Merge tags: failed to get tags", map[string]any{"error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get tags"}
+	}
+	if resp := checkTagsOwner(tuc.log, userID, sources, tags); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	if err := tuc.tagRepo.MergeTags(sources, targetID); err != nil {
+		tuc.log.Error(context.Background(), "Merge tags: failed to merge tags", map[string]any{"targetID": targetID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to merge tags"}
+	}
+
+	tuc.log.Info(context.Background(), "Merge tags: success", map[string]any{})
+	return pkg.Response{Code: http.StatusOK, Message: "Tags merged"}
+}
+
+func (tuc *tagUseCase) DeleteTag(userID, tagID uint) pkg.Response {
+	if _, resp := tuc.getOwnedTag(userID, tagID); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	if err := tuc.tagRepo.DeleteTagByID(tagID); err != nil {
+		tuc.log.Error(context.Background(), "Delete tag: failed to delete tag", map[string]any{"tagID": tagID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to delete tag"}
+	}
+
+	tuc.log.Info(context.Background(), "Delete tag: success", map[string]any{})
+	return pkg.Response{Code: http.StatusOK, Message: "Tag deleted"}
+}
+
+func (tuc *tagUseCase) getOwnedTag(userID, tagID uint) (domain.Tag, pkg.Response) {
+	tag, err := tuc.tagRepo.GetTagByID(tagID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tag, pkg.Response{Code: http.StatusNotFound, Message: "tag not found", Error: cerr.ErrTagNotFound}
+	}
+	if err != nil {
+		tuc.log.Error(context.Background(), "failed to get tag", map[string]any{"tagID": tagID, "error": err})
+		return tag, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get tag"}
+	}
+	if tag.UserID != userID {
+		tuc.log.Info(context.Background(), "tag belongs to another user", map[string]any{"userID": userID, "tagID": tagID})
+		return tag, pkg.Response{Code: http.StatusForbidden, Message: "tag belongs to another user", Error: cerr.BelongsToAnotherUser}
+	}
+	return tag, pkg.Response{Code: http.StatusOK}
+}
+
+func (tuc *tagUseCase) checkNameFree(userID uint, name string) pkg.Response {
+	_, err := tuc.tagRepo.GetTagByName(userID, name)
+	if err == nil {
+		return pkg.Response{Code: http.StatusConflict, Message: "tag with this name already exists", Error: cerr.ErrTagExists}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		tuc.log.Error(context.Background(), "failed to get tag by name", map[string]any{"error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get tag"}
+	}
+	return pkg.Response{Code: http.StatusOK}
+}
+
+// checkTagName rejects a trimmed tag name that is empty or longer than
+// maxTagLength.
+func checkTagName(name string) pkg.Response {
+	if name == "" {
+		return pkg.Response{Code: http.StatusBadRequest, Message: "tag name is empty", Error: cerr.ErrInvalidBody}
+	}
+	if utf8.RuneCountInString(name) > maxTagLength {
+		return pkg.Response{Code: http.StatusBadRequest, Message: fmt.Sprintf("tag names are at most %d characters", maxTagLength), Error: cerr.ErrInvalidBody}
+	}
+	return pkg.Response{Code: http.StatusOK}
+}
+
+// checkTagsOwner makes sure every id in ids was found in tags and belongs
+// to the user.
+func checkTagsOwner(log logger.Logger, userID uint, ids []uint, tags []domain.Tag) pkg.Response {
+	owned := make(map[uint]bool, len(tags))
+	for _, tag := range tags {
+		if tag.UserID != userID {
+			log.Info(context.Background(), "tag belongs to another user", map[string]any{"userID": userID, "tagID": tag.ID})
+			return pkg.Response{Code: http.StatusForbidden, Message: "tag belongs to another user", Error: cerr.BelongsToAnotherUser}
+		}
+		owned[tag.ID] = true
+	}
+	for _, id := range ids {
+		if !owned[id] {
+			return pkg.Response{Code: http.StatusNotFound, Message: "tag not found", Error: cerr.ErrTagNotFound}
+		}
+	}
+	return pkg.Response{Code: http.StatusOK}
+}
+
+// createTag saves a new tag if the user's plan allows another one.
+func createTag(uow repository.UnitOfWork, quota QuotaService, tag *domain.Tag) error {
+	return uow.Do(func(repos repository.Repositories) error {
+		return createTagWithinQuota(repos, quota, tag)
+	})
+}
+
+func createTagWithinQuota(repos repository.Repositories, quota QuotaService, tag *domain.Tag) error {
+	if err := quota.Reserve(repos, tag.UserID, ResourceTags, 1); err != nil {
+		return err
+	}
+	return repos.Tags.CreateTag(tag)
+}
+
+// tagRefs are the tags sent with a bookmark: tags of the user referenced
+// by ID and the names of tags to find or create.
+type tagRefs struct {
+	ids   []uint
+	names []string
+}
+
+// checkTags checks the tags sent with a bookmark before it is saved: tags
+// referenced by ID must be the user's and names must fit a tag.
+func checkTags(tagRepo repository.TagRepository, log logger.Logger, userID uint, tags []domain.Tag) (tagRefs, pkg.Response) {
+	var refs tagRefs
+	for _, tag := range tags {
+		if tag.ID != 0 {
+			refs.ids = append(refs.ids, tag.ID)
+			continue
+		}
+		name := strings.TrimSpace(tag.Name)
+		if name == "" {
+			continue
+		}
+		if resp := checkTagName(name); resp.Code != http.StatusOK {
+			return refs, resp
+		}
+		refs.names = append(refs.names, name)
+	}
+
+	if len(refs.ids) > 0 {
+		found, err := tagRepo.GetTagsByIDs(refs.ids)
+		if err != nil {
+			log.Error(context.Background(), "failed to get tags by ids", map[string]any{"error": err})
+			return refs, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to save tags"}
+		}
+		if resp := checkTagsOwner(log, userID, refs.ids, found); resp.Code != http.StatusOK {
+			return refs, resp
+		}
+	}
+	return refs, pkg.Response{Code: http.StatusOK}
+}
+
+// resolveTags turns checked tags into tag IDs within the transaction of
+// repos, creating the unknown names for the user. It returns a
+// *QuotaExceededError when the plan has no room for them.
+func resolveTags(repos repository.Repositories, quota QuotaService, userID uint, refs tagRefs) ([]uint, error) {
+	ids := []uint{}
+	seen := make(map[uint]bool)
+	for _, id := range refs.ids {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	for _, name := range refs.names {
+		tag, err := repos.Tags.GetTagByName(userID, name)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			tag = domain.Tag{UserID: userID, Name: name}
+			err = createTagWithinQuota(repos, quota, &tag)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("resolve tag %q: %w", name, err)
+		}
+		if !seen[tag.ID] {
+			seen[tag.ID] = true
+			ids = append(ids, tag.ID)
+		}
+	}
+	return ids, nil
+}
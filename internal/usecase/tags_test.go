@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckTagName(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want int
+	}{
+		{name: "short", tag: "go", want: http.StatusOK},
+		{name: "longest", tag: strings.Repeat("a", maxTagLength), want: http.StatusOK},
+		{name: "longest in multibyte characters", tag: strings.Repeat("ё", maxTagLength), want: http.StatusOK},
+		{name: "too long", tag: strings.Repeat("a", maxTagLength+1), want: http.StatusBadRequest},
+		{name: "empty", tag: "", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkTagName(tt.tag); got.Code != tt.want {
+				t.Fatalf("checkTagName(%q) = %d, want %d", tt.tag, got.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"gorm.io/gorm"
+)
+
+func (buc *bookmarkUseCase) GetTrash(userID uint) ([]domain.Bookmark, pkg.Response) {
+	bookmarks, err := buc.bookmarkRepo.GetDeletedBookmarks(userID)
+	if err != nil {
+		buc.log.Error(context.Background(), "Get trash: failed to get deleted bookmarks", map[string]any{"user_id": userID, "error": err})
+		return nil, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get trash"}
+	}
+	return bookmarks, pkg.Response{Code: http.StatusOK}
+}
+
+// RestoreBookmark takes a bookmark out of the trash. It returns to its
+// folder, or to the root level when that folder no longer exists.
+func (buc *bookmarkUseCase) RestoreBookmark(userID, bookmarkID uint) pkg.Response {
+	bookmark, err := buc.bookmarkRepo.GetDeletedBookmarkByID(bookmarkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkg.Response{Code: http.StatusNotFound, Message: "bookmark not found in trash", Error: cerr.ErrBookmarkNotFound}
+	}
+	if err != nil {
+		buc.log.Error(context.Background(), "Restore bookmark: failed to get bookmark", map[string]any{"bookmarkID": bookmarkID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get bookmark"}
+	}
+	if bookmark.UserID != userID {
+		buc.log.Info(context.Background(), "Restore bookmark: bookmark belongs to another user", map[string]any{"userID": userID, "bookmarkID": bookmarkID})
+		return pkg.Response{Code: http.StatusForbidden, Message: "bookmark belongs to another user", Error: cerr.BelongsToAnotherUser}
+	}
+
+	if resp := buc.checkDuplicate(userID, bookmarkID, bookmark.NormalizedURL); resp.Code != http.StatusOK {
+		return resp
+	}
+
+	folderID := bookmark.FolderID
+	if folderID != nil {
+		folder, err := buc.folderRepo.GetFolderByID(*folderID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && folder.UserID != userID) {
+			folderID = nil
+		} else if err != nil {
+			buc.log.Error(context.Background(), "Restore bookmark: failed to get folder", map[string]any{"folderID": *folderID, "error": err})
+			return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to get folder"}
+		}
+	}
+
+	err = buc.uow.Do(func(repos repository.Repositories) error {
+		if err := buc.quota.Reserve(repos, userID, ResourceBookmarks, 1); err != nil {
+			return err
+		}
+		if err := repos.Bookmarks.RestoreBookmark(bookmarkID, folderID); err != nil {
+			return err
+		}
+		return repos.Users.AdjustBookmarkCount(userID, 1)
+	})
+	if exceeded, ok := asQuotaExceeded(err); ok {
+		buc.log.Info(context.Background(), "Restore bookmark: limit of bookmarks for user", map[string]any{"user_id": userID})
+		return quotaExceededResponse(exceeded)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkg.Response{Code: http.StatusNotFound, Message: "bookmark not found in trash", Error: cerr.ErrBookmarkNotFound}
+	}
+	if errors.Is(err, repository.ErrDuplicateURL) {
+		return duplicateBookmarkResponse()
+	}
+	if err != nil {
+		buc.log.Error(context.Background(), "Restore bookmark: failed to restore bookmark", map[string]any{"bookmarkID": bookmarkID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to restore bookmark"}
+	}
+
+	buc.log.Info(context.Background(), "Restore bookmark: success", map[string]any{})
+	return pkg.Response{Code: http.StatusOK, Message: "Bookmark restored"}
+}
+
+// EmptyTrash permanently deletes every bookmark in the user's trash. The
+// bookmark counter is not touched, trashed bookmarks are no longer counted.
+func (buc *bookmarkUseCase) EmptyTrash(userID uint) pkg.Response {
+	purged, err := buc.bookmarkRepo.EmptyTrash(userID)
+	if err != nil {
+		buc.log.Error(context.Background(), "Empty trash: failed to delete bookmarks", map[string]any{"user_id": userID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to empty trash"}
+	}
+
+	buc.log.Info(context.Background(), "Empty trash: success", map[string]any{"user_id": userID, "purged": purged})
+	return pkg.Response{Code: http.StatusOK, Message: "Trash emptied"}
+}
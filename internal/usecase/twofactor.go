@@ -0,0 +1,367 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"image/png"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/config"
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/internal/utils/token"
+	"github.com/OxytocinGroup/theca-backend/pkg"
+	"github.com/OxytocinGroup/theca-backend/pkg/cerr"
+	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// Codes are the usual six digits every 30 seconds with SHA-1, which
+	// all authenticator apps support.
+	totpPeriod = 30
+	totpDigits = otp.DigitsSix
+
+	totpQRSize         = 256
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10 // random bytes, 16 characters
+
+	// A login challenge must be answered within loginChallengeTTL, in at
+	// most loginChallengeAttempts tries.
+	loginChallengeTTL      = 5 * time.Minute
+	loginChallengeAttempts = 5
+)
+
+type TwoFactorUseCase interface {
+	// EnrollTOTP gives the user a new secret for an authenticator app.
+	// It is not used until confirmed with ConfirmTOTP.
+	EnrollTOTP(userID uint) pkg.TOTPEnrollmentResponse
+	// ConfirmTOTP turns TOTP on when code is valid for the enrolled secret
+	// and returns new recovery codes.
+	ConfirmTOTP(userID uint, code string) pkg.RecoveryCodesResponse
+	// DisableTOTP turns TOTP off once password is checked.
+	DisableTOTP(userID uint, password string) pkg.Response
+	// CreateChallenge starts the second step of the user's login when
+	// they have TOTP enabled or a passkey, and returns the challenge's
+	// token with the methods that can answer it. The token is "" when the
+	// user has no second factor.
+	CreateChallenge(userID uint, remember bool) (string, []string, pkg.Response)
+	// BeginPasskeyChallenge asks for one of the user's passkeys to answer
+	// the challenge with.
+	BeginPasskeyChallenge(challengeToken string) pkg.PasskeyOptionsResponse
+	// VerifyChallenge completes the login of the challenge with a code of
+	// the authenticator app, a recovery code or, when credential is given,
+	// a passkey. Codes are only accepted from users with TOTP enabled. It
+	// returns the user and whether the session is remembered.
+	VerifyChallenge(challengeToken, code string, credential []byte) (*domain.User, bool, pkg.Response)
+}
+
+type twoFactorUseCase struct {
+	userRepo      repository.UserRepository
+	twoFactorRepo repository.TwoFactorRepository
+	passkeyRepo   repository.PasskeyRepository
+	passkeys      PasskeyUseCase
+	cfg           config.Config
+	log           logger.Logger
+}
+
+func NewTwoFactorUseCase(userRepo repository.UserRepository, twoFactorRepo repository.TwoFactorRepository, passkeyRepo repository.PasskeyRepository, passkeys PasskeyUseCase, cfg config.Config, log logger.Logger) TwoFactorUseCase {
+	return &twoFactorUseCase{
+		userRepo:      userRepo,
+		twoFactorRepo: twoFactorRepo,
+		passkeyRepo:   passkeyRepo,
+		passkeys:      passkeys,
+		cfg:           cfg,
+		log:           log,
+	}
+}
+
+func (tuc *twoFactorUseCase) EnrollTOTP(userID uint) pkg.TOTPEnrollmentResponse {
+	user, err := tuc.userRepo.GetByID(userID)
+	if err != nil {
+		tuc.log.Info(context.Background(), "Enroll TOTP: user not found", map[string]any{"user_id": userID, "error": err})
+		return pkg.TOTPEnrollmentResponse{Code: http.StatusNotFound, Message: "user not found", Error: cerr.ErrInvalidUser}
+	}
+	if user.TOTPEnabled {
+		return pkg.TOTPEnrollmentResponse{Code: http.StatusConflict, Message: "two-factor authentication is already enabled", Error: cerr.ErrTwoFactorEnabled}
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      tuc.cfg.TOTPIssuer,
+		AccountName: user.Username,
+		Period:      totpPeriod,
+		Digits:      totpDigits,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		tuc.log.Error(context.Background(), "Enroll TOTP: failed to generate secret", map[string]any{"user_id": userID, "error": err})
+		return pkg.TOTPEnrollmentResponse{Code: http.StatusInternalServerError, Message: "failed to generate secret"}
+	}
+	qrCode, err := key.Image(totpQRSize, totpQRSize)
+	if err != nil {
+		tuc.log.Error(context.Background(), "Enroll TOTP: failed to draw QR code", map[string]any{"user_id": userID, "error": err})
+		return pkg.TOTPEnrollmentResponse{Code: http.StatusInternalServerError, Message: "failed to generate QR code"}
+	}
+	var qrPNG bytes.Buffer
+	if err := png.Encode(&qrPNG, qrCode); err != nil {
+		tuc.log.Error(context.Background(), "Enroll TOTP: failed to encode QR code", map[string]any{"user_id": userID, "error": err})
+		return pkg.TOTPEnrollmentResponse{Code: http.StatusInternalServerError, Message: "failed to generate QR code"}
+	}
+
+	if err := tuc.twoFactorRepo.SetTOTPSecret(userID, key.Secret()); err != nil {
+		tuc.log.Error(context.Background(), "Enroll TOTP: failed to store secret", map[string]any{"user_id": userID, "error": err})
+		return pkg.TOTPEnrollmentResponse{Code: http.StatusInternalServerError, Message: "failed to store secret"}
+	}
+
+	tuc.log.Info(context.Background(), "Enroll TOTP: success", map[string]any{"user_id": userID})
+	return pkg.TOTPEnrollmentResponse{
+		Code:    http.StatusOK,
+		Message: "Scan the QR code and confirm with a code from the app",
+		Secret:  key.Secret(),
+		URI:     key.URL(),
+		QRCode:  qrPNG.Bytes(),
+	}
+}
+
+func (tuc *twoFactorUseCase) ConfirmTOTP(userID uint, code string) pkg.RecoveryCodesResponse {
+	user, err := tuc.userRepo.GetByID(userID)
+	if err != nil {
+		tuc.log.Info(context.Background(), "Confirm TOTP: user not found", map[string]any{"user_id": userID, "error": err})
+		return pkg.RecoveryCodesResponse{Code: http.StatusNotFound, Message: "user not found", Error: cerr.ErrInvalidUser}
+	}
+	if user.TOTPEnabled {
+		return pkg.RecoveryCodesResponse{Code: http.StatusConflict, Message: "two-factor authentication is already enabled", Error: cerr.ErrTwoFactorEnabled}
+	}
+	if user.TOTPSecret == "" {
+		return pkg.RecoveryCodesResponse{Code: http.StatusBadRequest, Message: "no authenticator app is being enrolled", Error: cerr.ErrTwoFactorDisabled}
+	}
+
+	step, ok := totpStep(user.TOTPSecret, code, time.Now())
+	if !ok {
+		tuc.log.Info(context.Background(), "Confirm TOTP: invalid code", map[string]any{"user_id": userID})
+		return pkg.RecoveryCodesResponse{Code: http.StatusUnauthorized, Message: "invalid code", Error: cerr.ErrInvalidOTP}
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	recoveryCodes := make([]domain.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = newRecoveryCode(); err != nil {
+			tuc.log.Error(context.Background(), "Confirm TOTP: failed to generate recovery code", map[string]any{"user_id": userID, "error": err})
+			return pkg.RecoveryCodesResponse{Code: http.StatusInternalServerError, Message: "failed to generate recovery codes"}
+		}
+		recoveryCodes[i] = domain.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(codes[i])}
+	}
+	if err := tuc.twoFactorRepo.EnableTOTP(userID, step, recoveryCodes); err != nil {
+		tuc.log.Error(context.Background(), "Confirm TOTP: failed to enable TOTP", map[string]any{"user_id": userID, "error": err})
+		return pkg.RecoveryCodesResponse{Code: http.StatusInternalServerError, Message: "failed to enable two-factor authentication"}
+	}
+
+	tuc.log.Info(context.Background(), "Confirm TOTP: two-factor authentication enabled", map[string]any{"user_id": userID})
+	return pkg.RecoveryCodesResponse{
+		Code:          http.StatusOK,
+		Message:       "Two-factor authentication enabled. Keep the recovery codes somewhere safe, they are not shown again",
+		RecoveryCodes: codes,
+	}
+}
+
+func (tuc *twoFactorUseCase) DisableTOTP(userID uint, password string) pkg.Response {
+	user, err := tuc.userRepo.GetByID(userID)
+	if err != nil {
+		tuc.log.Info(context.Background(), "Disable TOTP: user not found", map[string]any{"user_id": userID, "error": err})
+		return pkg.Response{Code: http.StatusNotFound, Message: "user not found", Error: cerr.ErrInvalidUser}
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		tuc.log.Info(context.Background(), "Disable TOTP: wrong password", map[string]any{"user_id": userID})
+		return pkg.Response{Code: http.StatusUnauthorized, Message: "invalid password", Error: cerr.InvalidPass}
+	}
+	if !user.TOTPEnabled && user.TOTPSecret == "" {
+		return pkg.Response{Code: http.StatusConflict, Message: "two-factor authentication is not enabled", Error: cerr.ErrTwoFactorDisabled}
+	}
+
+	if err := tuc.twoFactorRepo.DisableTOTP(userID); err != nil {
+		tuc.log.Error(context.Background(), "Disable TOTP: failed to disable TOTP", map[string]any{"user_id": userID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to disable two-factor authentication"}
+	}
+
+	tuc.log.Info(context.Background(), "Disable TOTP: two-factor authentication disabled", map[string]any{"user_id": userID})
+	return pkg.Response{Code: http.StatusOK, Message: "Two-factor authentication disabled"}
+}
+
+func (tuc *twoFactorUseCase) CreateChallenge(userID uint, remember bool) (string, []string, pkg.Response) {
+	user, err := tuc.userRepo.GetByID(userID)
+	if err != nil {
+		tuc.log.Info(context.Background(), "Create challenge: user not found", map[string]any{"user_id": userID, "error": err})
+		return "", nil, pkg.Response{Code: http.StatusNotFound, Message: "user not found", Error: cerr.ErrInvalidUser}
+	}
+	var methods []string
+	if user.TOTPEnabled {
+		methods = append(methods, "totp", "recovery_code")
+	}
+	passkeys, err := tuc.passkeyRepo.CountPasskeys(userID)
+	if err != nil {
+		tuc.log.Error(context.Background(), "Create challenge: failed to count passkeys", map[string]any{"user_id": userID, "error": err})
+		return "", nil, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to start two-factor authentication"}
+	}
+	if passkeys > 0 {
+		methods = append(methods, "passkey")
+	}
+	if len(methods) == 0 {
+		return "", nil, pkg.Response{Code: http.StatusOK}
+	}
+
+	challengeToken, err := token.GenerateToken()
+	if err != nil {
+		tuc.log.Error(context.Background(), "Create challenge: failed to generate token", map[string]any{"user_id": userID, "error": err})
+		return "", nil, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to start two-factor authentication"}
+	}
+	err = tuc.twoFactorRepo.CreateChallenge(&domain.LoginChallenge{
+		ID:        token.Hash(challengeToken),
+		UserID:    userID,
+		Remember:  remember,
+		ExpiresAt: time.Now().Add(loginChallengeTTL),
+	})
+	if err != nil {
+		tuc.log.Error(context.Background(), "Create challenge: failed to store challenge", map[string]any{"user_id": userID, "error": err})
+		return "", nil, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to start two-factor authentication"}
+	}
+	return challengeToken, methods, pkg.Response{Code: http.StatusOK}
+}
+
+func (tuc *twoFactorUseCase) BeginPasskeyChallenge(challengeToken string) pkg.PasskeyOptionsResponse {
+	challengeID := token.Hash(challengeToken)
+	challenge, err := tuc.twoFactorRepo.GetChallenge(challengeID, loginChallengeAttempts, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkg.PasskeyOptionsResponse{Code: http.StatusUnauthorized, Message: "login expired, log in again", Error: cerr.ErrInvalidChallenge}
+	}
+	if err != nil {
+		tuc.log.Error(context.Background(), "Begin passkey challenge: failed to get challenge", map[string]any{"error": err})
+		return pkg.PasskeyOptionsResponse{Code: http.StatusInternalServerError, Message: "failed to ask for a passkey"}
+	}
+
+	assertion, session, resp := tuc.passkeys.BeginAssertion(challenge.UserID)
+	if resp.Code != http.StatusOK {
+		return pkg.PasskeyOptionsResponse{Code: resp.Code, Message: resp.Message, Error: resp.Error}
+	}
+	if err := tuc.twoFactorRepo.SetChallengePasskeySession(challengeID, session); err != nil {
+		tuc.log.Error(context.Background(), "Begin passkey challenge: failed to store session", map[string]any{"user_id": challenge.UserID, "error": err})
+		return pkg.PasskeyOptionsResponse{Code: http.StatusInternalServerError, Message: "failed to ask for a passkey"}
+	}
+	return pkg.PasskeyOptionsResponse{Code: http.StatusOK, Options: assertion}
+}
+
+func (tuc *twoFactorUseCase) VerifyChallenge(challengeToken, code string, credential []byte) (*domain.User, bool, pkg.Response) {
+	challengeID := token.Hash(challengeToken)
+	challenge, err := tuc.twoFactorRepo.UseChallengeAttempt(challengeID, loginChallengeAttempts, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, pkg.Response{Code: http.StatusUnauthorized, Message: "login expired, log in again", Error: cerr.ErrInvalidChallenge}
+	}
+	if err != nil {
+		tuc.log.Error(context.Background(), "Verify challenge: failed to get challenge", map[string]any{"error": err})
+		return nil, false, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to verify code"}
+	}
+
+	user, err := tuc.userRepo.GetByID(challenge.UserID)
+	if err != nil {
+		tuc.log.Info(context.Background(), "Verify challenge: user not found", map[string]any{"user_id": challenge.UserID, "error": err})
+		return nil, false, pkg.Response{Code: http.StatusNotFound, Message: "user not found", Error: cerr.ErrInvalidUser}
+	}
+
+	if len(credential) > 0 {
+		if len(challenge.PasskeySession) == 0 {
+			return nil, false, pkg.Response{Code: http.StatusBadRequest, Message: "no passkey was asked for", Error: cerr.ErrInvalidChallenge}
+		}
+		if resp := tuc.passkeys.FinishAssertion(user.ID, challenge.PasskeySession, credential); resp.Code != http.StatusOK {
+			return nil, false, resp
+		}
+	} else if resp := tuc.checkCode(user, code); resp.Code != http.StatusOK {
+		return nil, false, resp
+	}
+	if err := tuc.twoFactorRepo.DeleteChallenge(challengeID); err != nil {
+		tuc.log.Error(context.Background(), "Verify challenge: failed to delete challenge", map[string]any{"user_id": user.ID, "error": err})
+		return nil, false, pkg.Response{Code: http.StatusInternalServerError, Message: "failed to verify code"}
+	}
+
+	tuc.log.Info(context.Background(), "Verify challenge: success", map[string]any{"user_id": user.ID})
+	return &user, challenge.Remember, pkg.Response{Code: http.StatusOK}
+}
+
+// checkCode accepts a current code of the user's authenticator app that
+// was not used before, or an unused recovery code, which is used up.
+func (tuc *twoFactorUseCase) checkCode(user domain.User, code string) pkg.Response {
+	invalid := pkg.Response{Code: http.StatusUnauthorized, Message: "invalid code", Error: cerr.ErrInvalidOTP}
+	if !user.TOTPEnabled {
+		return invalid
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits.Length() {
+		step, ok := totpStep(user.TOTPSecret, code, time.Now())
+		if !ok {
+			tuc.log.Info(context.Background(), "Check code: invalid code", map[string]any{"user_id": user.ID})
+			return invalid
+		}
+		err := tuc.twoFactorRepo.UseTOTPStep(user.ID, step)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			tuc.log.Info(context.Background(), "Check code: code reused", map[string]any{"user_id": user.ID})
+			return invalid
+		}
+		if err != nil {
+			tuc.log.Error(context.Background(), "Check code: failed to record code use", map[string]any{"user_id": user.ID, "error": err})
+			return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to verify code"}
+		}
+		return pkg.Response{Code: http.StatusOK}
+	}
+
+	err := tuc.twoFactorRepo.UseRecoveryCode(user.ID, hashRecoveryCode(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		tuc.log.Info(context.Background(), "Check code: invalid recovery code", map[string]any{"user_id": user.ID})
+		return invalid
+	}
+	if err != nil {
+		tuc.log.Error(context.Background(), "Check code: failed to use recovery code", map[string]any{"user_id": user.ID, "error": err})
+		return pkg.Response{Code: http.StatusInternalServerError, Message: "failed to verify code"}
+	}
+	tuc.log.Info(context.Background(), "Check code: recovery code used", map[string]any{"user_id": user.ID})
+	return pkg.Response{Code: http.StatusOK}
+}
+
+// totpStep returns the time step of code when it is valid for the secret
+// at now, allowing for a step of clock drift either way.
+func totpStep(secret, code string, now time.Time) (int64, bool) {
+	opts := totp.ValidateOpts{Period: totpPeriod, Digits: totpDigits, Algorithm: otp.AlgorithmSHA1}
+	for _, skew := range []int64{0, -1, 1} {
+		step := now.Unix()/totpPeriod + skew
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), opts)
+		if err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCode returns a random code written as four groups of four
+// characters, such as "abcd-efgh-ijkl-mnop".
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+	return code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:], nil
+}
+
+// hashRecoveryCode hashes a recovery code regardless of case, dashes and
+// spaces, so it can be typed as the user likes.
+func hashRecoveryCode(code string) string {
+	code = strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return token.Hash(code)
+}
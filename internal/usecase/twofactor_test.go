@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestTOTPStep(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+	now := time.Unix(1_700_000_000, 0)
+	step := now.Unix() / totpPeriod
+	codeAt := func(t *testing.T, at time.Time) string {
+		code, err := totp.GenerateCodeCustom(secret, at, totp.ValidateOpts{Period: totpPeriod, Digits: totpDigits, Algorithm: otp.AlgorithmSHA1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current", at: now, wantStep: step, wantOK: true},
+		{name: "previous step", at: now.Add(-totpPeriod * time.Second), wantStep: step - 1, wantOK: true},
+		{name: "next step", at: now.Add(totpPeriod * time.Second), wantStep: step + 1, wantOK: true},
+		{name: "too old", at: now.Add(-3 * totpPeriod * time.Second)},
+		{name: "too new", at: now.Add(3 * totpPeriod * time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := totpStep(secret, codeAt(t, tt.at), now)
+			if got != tt.wantStep || ok != tt.wantOK {
+				t.Fatalf("totpStep = %d, %v, want %d, %v", got, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+	if _, ok := totpStep(secret, "", now); ok {
+		t.Error("empty code accepted")
+	}
+	if _, ok := totpStep("not base32!", codeAt(t, now), now); ok {
+		t.Error("code accepted for an invalid secret")
+	}
+}
+
+func TestNewRecoveryCode(t *testing.T) {
+	format := regexp.MustCompile(`^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !format.MatchString(code) {
+			t.Fatalf("recovery code %q not four groups of four", code)
+		}
+		if seen[code] {
+			t.Fatalf("recovery code %q repeated", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := hashRecoveryCode("abcd-efgh-ijkl-mnop")
+	for _, typed := range []string{"abcdefghijklmnop", "ABCD-EFGH-IJKL-MNOP", "abcd efgh ijkl mnop", " abcd-efgh-ijkl-mnop "} {
+		if got := hashRecoveryCode(typed); got != want {
+			t.Errorf("hashRecoveryCode(%q) differs from the printed code", typed)
+		}
+	}
+	if hashRecoveryCode("abcd-efgh-ijkl-mnoq") == want {
+		t.Error("different codes hash alike")
+	}
+}
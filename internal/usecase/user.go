@@ -25,9 +25,13 @@ type UserUseCase interface {
 	Register(email, password, username string) pkg.Response
 	VerifyEmail(code string) pkg.Response
 	Auth(username, password string) (*domain.User, pkg.Response)
-	ChangePass(userID uint, newPassword string) pkg.Response
+	// ChangePass sets a new password after checking the current one and
+	// ends every session of the user.
+	ChangePass(userID uint, currentPassword, newPassword string) pkg.Response
 	CheckVerificationStatus(username string) (bool, pkg.Response)
 	GetResetPassword(email string) pkg.Response
+	// ResetPassword sets a new password by reset token and ends every
+	// session of the user.
 	ResetPassword(token, password string) pkg.Response
 	ResendVerificationToken(username string) pkg.Response
 	GetUserInfo(userID uint) pkg.UserInfoResponse
@@ -36,14 +40,16 @@ type UserUseCase interface {
 type userUseCase struct {
 	userRepo    repository.UserRepository
 	sessionRepo repository.SessionRepository
+	quota       QuotaService
 	log         logger.Logger
 	cfg         config.Config
 }
 
-func NewUserUseCase(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, cfg config.Config, log logger.Logger) UserUseCase {
+func NewUserUseCase(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, quota QuotaService, cfg config.Config, log logger.Logger) UserUseCase {
 	return &userUseCase{
 		userRepo:    userRepo,
 		sessionRepo: sessionRepo,
+		quota:       quota,
 		log:         log,
 		cfg:         cfg,
 	}
@@ -206,7 +212,7 @@ func (uuc *userUseCase) Auth(username, password string) (*domain.User, pkg.Respo
 	}
 }
 
-func (uuc *userUseCase) ChangePass(userID uint, newPassword string) pkg.Response {
+func (uuc *userUseCase) ChangePass(userID uint, currentPassword, newPassword string) pkg.Response {
 	user, err := uuc.userRepo.GetByID(userID)
 	if err != nil {
 		uuc.log.Info(context.Background(), "Change pass: failed to get user by id", map[string]any{
@@ -218,6 +224,14 @@ func (uuc *userUseCase) ChangePass(userID uint, newPassword string) pkg.Response
 			Message: "Not found user by username",
 		}
 	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		uuc.log.Info(context.Background(), "Change pass: wrong current password", map[string]any{"user_id": user.ID})
+		return pkg.Response{
+			Code:    http.StatusUnauthorized,
+			Message: "invalid password",
+			Error:   cerr.InvalidPass,
+		}
+	}
 
 	hashPass, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
@@ -359,6 +373,11 @@ func (uuc *userUseCase) ResetPassword(token, password string) pkg.Response {
 		return pkg.Response{Code: 500, Message: "failed to update user"}
 	}
 
+	if err := uuc.sessionRepo.DeleteAllSessions(user.ID); err != nil {
+		uuc.log.Error(context.Background(), "Reset pass: failed to delete sessions", map[string]any{"user_id": user.ID, "error": err})
+		return pkg.Response{Code: 500, Message: "failed to delete sessions"}
+	}
+
 	uuc.log.Info(context.Background(), "Reset pass: reset successfully", map[string]any{})
 	return pkg.Response{
 		Code:    200,
@@ -411,5 +430,11 @@ func (uuc *userUseCase) GetUserInfo(userID uint) pkg.UserInfoResponse {
 		return pkg.UserInfoResponse{Code: 404, Message: "User not found"}
 	}
 
-	return pkg.UserInfoResponse{Code: 200, Message: "Successful", Email: user.Email, Username: user.Username}
+	usage, err := uuc.quota.Usage(userID)
+	if err != nil {
+		uuc.log.Error(context.Background(), "Get user info: failed to get usage", map[string]any{"error": err, "user_id": userID})
+		return pkg.UserInfoResponse{Code: http.StatusInternalServerError, Message: "Failed to get usage"}
+	}
+
+	return pkg.UserInfoResponse{Code: 200, Message: "Successful", Email: user.Email, Username: user.Username, Usage: &usage, TwoFactorEnabled: user.TOTPEnabled}
 }
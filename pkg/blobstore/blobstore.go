@@ -0,0 +1,46 @@
+// Package blobstore keeps binary objects, such as favicons, outside the
+// database. Objects are addressed by a key chosen by the caller.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/OxytocinGroup/theca-backend/internal/config"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+type Store interface {
+	// Put stores data under key, replacing what was there.
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Get opens the object stored under key and returns its size, or
+	// returns ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// validateKey rejects keys that could escape the store, such as absolute
+// paths or ".." segments.
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
+
+// FromConfig opens the store selected by cfg.BlobStore.
+func FromConfig(cfg config.Config) (Store, error) {
+	if cfg.BlobStore == "s3" {
+		return NewS3Store(cfg.S3Endpoint, cfg.S3Bucket, cfg.S3Region, cfg.S3AccessKey, cfg.S3SecretKey)
+	}
+	return NewLocalStore(cfg.BlobDir)
+}
@@ -14,5 +14,26 @@ const (
 	ErrMissingCookie     = "MISSING_SESSION"
 	ErrLimitOfBookmarks  = "BOOKMARKS_LIMIT"
 	ErrInvalidSession    = "INVALID_SESSION"
-	ErrInvalidUser = "INVALID_USER"
+	ErrInvalidUser       = "INVALID_USER"
+	ErrFolderNotFound    = "FOLDER_NOT_FOUND"
+	ErrInvalidParent     = "INVALID_PARENT_FOLDER"
+	ErrBookmarkNotFound  = "BOOKMARK_NOT_FOUND"
+	ErrTagNotFound       = "TAG_NOT_FOUND"
+	ErrTagExists         = "TAG_EXISTS"
+	ErrInvalidImportFile = "INVALID_IMPORT_FILE"
+	ErrLimitOfFolders    = "FOLDERS_LIMIT"
+	ErrLimitOfTags       = "TAGS_LIMIT"
+	ErrIconNotFound      = "ICON_NOT_FOUND"
+	ErrInvalidURL        = "INVALID_URL"
+	ErrPageUnavailable   = "PAGE_UNAVAILABLE"
+	ErrDuplicateBookmark = "DUPLICATE_BOOKMARK"
+	ErrSessionNotFound   = "SESSION_NOT_FOUND"
+	ErrTwoFactorEnabled  = "TWO_FACTOR_ALREADY_ENABLED"
+	ErrTwoFactorDisabled = "TWO_FACTOR_NOT_ENABLED"
+	ErrInvalidOTP        = "INVALID_TWO_FACTOR_CODE"
+	ErrInvalidChallenge  = "INVALID_LOGIN_CHALLENGE"
+	ErrInvalidPasskey    = "INVALID_PASSKEY"
+	ErrPasskeyExists     = "PASSKEY_EXISTS"
+	ErrPasskeyNotFound   = "PASSKEY_NOT_FOUND"
+	ErrLimitOfPasskeys   = "PASSKEYS_LIMIT"
 )
@@ -7,14 +7,20 @@ import (
 
 	"github.com/OxytocinGroup/theca-backend/internal/config"
 	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/internal/usecase"
 	"github.com/OxytocinGroup/theca-backend/pkg/logger"
 	"github.com/go-co-op/gocron"
 )
 
 var (
-	conf  *config.Config
-	repos repository.SessionRepository
-	logs  logger.Logger
+	conf         *config.Config
+	repos        repository.SessionRepository
+	twoFactor    repository.TwoFactorRepository
+	passkeys     repository.PasskeyRepository
+	bookmarkRepo repository.BookmarkRepository
+	jobRepo      repository.JobRepository
+	jobQueue     usecase.JobQueue
+	logs         logger.Logger
 )
 
 func clearDB() {
@@ -34,11 +40,23 @@ func clearDB() {
 		}
 	}
 
+	if _, err := twoFactor.DeleteExpiredChallenges(time.Now()); err != nil {
+		logs.Error(context.Background(), "cron (clear session db): error while deleting login challenges", map[string]any{"error": err})
+	}
+	if _, err := passkeys.DeleteExpiredCeremonies(time.Now()); err != nil {
+		logs.Error(context.Background(), "cron (clear session db): error while deleting passkey ceremonies", map[string]any{"error": err})
+	}
+
 }
 
-func InitScheduler(cfg *config.Config, log logger.Logger, repo repository.SessionRepository) {
+func InitScheduler(cfg *config.Config, log logger.Logger, repo repository.SessionRepository, challenges repository.TwoFactorRepository, ceremonies repository.PasskeyRepository, bookmarks repository.BookmarkRepository, jobs repository.JobRepository, queue usecase.JobQueue) {
 	conf = cfg
 	repos = repo
+	twoFactor = challenges
+	passkeys = ceremonies
+	bookmarkRepo = bookmarks
+	jobRepo = jobs
+	jobQueue = queue
 	logs = log
 	location, err := time.LoadLocation("Europe/Moscow")
 	if err != nil {
@@ -48,6 +66,9 @@ func InitScheduler(cfg *config.Config, log logger.Logger, repo repository.Sessio
 	scheduler := gocron.NewScheduler(location)
 
 	scheduler.Every(1).Day().At(cfg.ClearTime).Do(clearDB)
+	scheduler.Every(1).Day().At(cfg.ClearTime).Do(purgeTrash)
+	scheduler.Every(1).Day().At(cfg.ClearTime).Do(purgeJobs)
+	scheduler.Every(1).Hour().Do(queueLinkChecks)
 
 	scheduler.StartAsync()
 }
@@ -0,0 +1,21 @@
+package cron
+
+import (
+	"context"
+	"time"
+)
+
+// jobRetention is how long finished jobs are kept. Dead jobs are kept
+// until they are retried.
+const jobRetention = 7 * 24 * time.Hour
+
+// purgeJobs deletes background jobs that finished long ago.
+func purgeJobs() {
+	purged, err := jobRepo.DeleteDoneJobs(time.Now().Add(-jobRetention))
+	if err != nil {
+		logs.Error(context.Background(), "cron (purge jobs): error while deleting jobs", map[string]any{"error": err})
+		return
+	}
+
+	logs.Info(context.Background(), "cron (purge jobs): done", map[string]any{"purged": purged})
+}
@@ -0,0 +1,38 @@
+package cron
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+)
+
+// queueLinkChecks queues checks of the bookmark URLs due for one. The
+// checks run on the job queue, which limits how many run at once and how
+// often each site is requested.
+func queueLinkChecks() {
+	if conf.LinkCheckBatch <= 0 {
+		return
+	}
+
+	urls, err := bookmarkRepo.GetURLsToCheck(time.Now().Add(-conf.LinkCheckInterval), conf.LinkCheckBatch)
+	if err != nil {
+		logs.Error(context.Background(), "cron (check links): error while getting URLs", map[string]any{"error": err})
+		return
+	}
+
+	for _, resourceURL := range urls {
+		host := ""
+		if parsed, err := url.Parse(resourceURL); err == nil {
+			host = strings.ToLower(parsed.Hostname())
+		}
+		if err := jobQueue.Enqueue(domain.JobCheckLink, host, domain.CheckLinkPayload{URL: resourceURL}); err != nil {
+			logs.Error(context.Background(), "cron (check links): error while queueing check", map[string]any{"error": err})
+			return
+		}
+	}
+
+	logs.Info(context.Background(), "cron (check links): done", map[string]any{"queued": len(urls)})
+}
@@ -0,0 +1,23 @@
+package cron
+
+import (
+	"context"
+	"time"
+)
+
+// purgeTrash permanently deletes bookmarks that stayed in the trash longer
+// than the configured retention. A retention of 0 keeps them forever.
+func purgeTrash() {
+	if conf.TrashRetentionDays <= 0 {
+		return
+	}
+
+	deletedBefore := time.Now().AddDate(0, 0, -conf.TrashRetentionDays)
+	purged, err := bookmarkRepo.PurgeDeletedBookmarks(deletedBefore)
+	if err != nil {
+		logs.Error(context.Background(), "cron (purge trash): error while deleting bookmarks", map[string]any{"error": err})
+		return
+	}
+
+	logs.Info(context.Background(), "cron (purge trash): done", map[string]any{"purged": purged})
+}
@@ -0,0 +1,44 @@
+package exporters
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+)
+
+var csvHeader = []string{"title", "url", "folder", "tags", "icon_url", "show_text", "created"}
+
+// writeCSV writes one row per bookmark. Nested folders are joined with "/"
+// and tags with ",", the layout most bookmark managers import.
+func writeCSV(w io.Writer, tree domain.BookmarkTree) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	err := walk(tree, func(path []string, bookmark domain.Bookmark) error {
+		created := ""
+		if !bookmark.CreatedAt.IsZero() {
+			created = bookmark.CreatedAt.UTC().Format(time.RFC3339)
+		}
+		return cw.Write([]string{
+			bookmark.Title,
+			bookmark.URL,
+			strings.Join(path, "/"),
+			strings.Join(tagNames(bookmark.Tags), ","),
+			bookmark.IconURL,
+			strconv.FormatBool(bookmark.ShowText),
+			created,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
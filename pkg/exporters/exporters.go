@@ -0,0 +1,94 @@
+package exporters
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Format describes one of the supported export formats.
+type Format struct {
+	ContentType string
+	Extension   string
+	write       func(w io.Writer, tree domain.BookmarkTree) error
+}
+
+var formats = map[string]Format{
+	"html": {ContentType: "text/html; charset=utf-8", Extension: "html", write: writeNetscape},
+	"json": {ContentType: "application/json; charset=utf-8", Extension: "json", write: writeJSON},
+	"csv":  {ContentType: "text/csv; charset=utf-8", Extension: "csv", write: writeCSV},
+	"md":   {ContentType: "text/markdown; charset=utf-8", Extension: "md", write: writeMarkdown},
+}
+
+// Lookup returns the export format registered under name.
+func Lookup(name string) (Format, error) {
+	format, ok := formats[strings.ToLower(name)]
+	if !ok {
+		return Format{}, ErrUnknownFormat
+	}
+	return format, nil
+}
+
+// Write encodes the bookmark tree to w. Bookmarks with an icon stored by
+// Theca link to it under iconBaseURL, the address the API is served at.
+func (f Format) Write(w io.Writer, tree domain.BookmarkTree, iconBaseURL string) error {
+	setIconURLs(tree.Bookmarks, tree.Folders, strings.TrimSuffix(iconBaseURL, "/"))
+	return f.write(w, tree)
+}
+
+// setIconURLs fills IconURL from IconHash, which is where icons are kept
+// since they are stored by Theca.
+func setIconURLs(bookmarks []domain.Bookmark, folders []domain.FolderNode, iconBaseURL string) {
+	for i := range bookmarks {
+		if bookmarks[i].IconHash != "" {
+			bookmarks[i].IconURL = iconBaseURL + domain.IconPath(bookmarks[i].IconHash)
+		}
+	}
+	for _, folder := range folders {
+		setIconURLs(folder.Bookmarks, folder.Folders, iconBaseURL)
+	}
+}
+
+// walk calls fn for every bookmark in the tree in display order, passing the
+// names of the folders it is nested in.
+func walk(tree domain.BookmarkTree, fn func(path []string, bookmark domain.Bookmark) error) error {
+	for _, bookmark := range tree.Bookmarks {
+		if err := fn(nil, bookmark); err != nil {
+			return err
+		}
+	}
+
+	var visit func(path []string, node domain.FolderNode) error
+	visit = func(path []string, node domain.FolderNode) error {
+		path = append(path[:len(path):len(path)], node.Name)
+		for _, bookmark := range node.Bookmarks {
+			if err := fn(path, bookmark); err != nil {
+				return err
+			}
+		}
+		for _, child := range node.Folders {
+			if err := visit(path, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, node := range tree.Folders {
+		if err := visit(nil, node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tagNames(tags []domain.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
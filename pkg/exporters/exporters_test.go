@@ -0,0 +1,123 @@
+package exporters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+)
+
+// testTree holds a root bookmark with an icon stored by Theca and a folder
+// with a subfolder, with titles that need escaping in every format.
+func testTree() domain.BookmarkTree {
+	created := time.Unix(1700000000, 0)
+	return domain.BookmarkTree{
+		Bookmarks: []domain.Bookmark{
+			{Title: "Go <dev>", URL: "https://go.dev/?a=1&b=2", IconHash: "abc", CreatedAt: created, Tags: []domain.Tag{{Name: "go"}, {Name: "open source"}}},
+		},
+		Folders: []domain.FolderNode{{
+			Folder:    domain.Folder{Name: "Work & Play"},
+			Bookmarks: []domain.Bookmark{{Title: "Docs [draft]", URL: "https://example.com/docs", ShowText: true}},
+			Folders: []domain.FolderNode{{
+				Folder:    domain.Folder{Name: "Deep"},
+				Bookmarks: []domain.Bookmark{{URL: "https://example.com/deep", IconURL: "https://example.com/icon.png"}},
+			}},
+		}},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "html",
+			want: netscapeHeader + `<DL><p>
+    <DT><A HREF="https://go.dev/?a=1&amp;b=2" ADD_DATE="1700000000" ICON_URI="https://theca.example/icons/abc" TAGS="go,open source">Go &lt;dev&gt;</A>
+    <DT><H3>Work &amp; Play</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/docs" SHOW_TEXT="true">Docs [draft]</A>
+        <DT><H3>Deep</H3>
+        <DL><p>
+            <DT><A HREF="https://example.com/deep" ICON_URI="https://example.com/icon.png"></A>
+        </DL><p>
+    </DL><p>
+</DL><p>
+`,
+		},
+		{
+			format: "csv",
+			want: `title,url,folder,tags,icon_url,show_text,created
+Go <dev>,https://go.dev/?a=1&b=2,,"go,open source",https://theca.example/icons/abc,false,2023-11-14T22:13:20Z
+Docs [draft],https://example.com/docs,Work & Play,,,true,
+,https://example.com/deep,Work & Play/Deep,,https://example.com/icon.png,false,
+`,
+		},
+		{
+			format: "md",
+			want: `# Bookmarks
+
+- [Go <dev>](<https://go.dev/?a=1&b=2>) #go #open-source
+
+## Work & Play
+
+- [Docs \[draft\]](<https://example.com/docs>)
+
+### Deep
+
+- [https://example.com/deep](<https://example.com/deep>)
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			format, err := Lookup(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := format.Write(&out, testTree(), "https://theca.example/"); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	format, err := Lookup("JSON")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := format.Write(&out, testTree(), "https://theca.example"); err != nil {
+		t.Fatal(err)
+	}
+
+	var export jsonExport
+	if err := json.Unmarshal(out.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+	if export.Version != 1 || export.ExportedAt.IsZero() {
+		t.Errorf("version %d exported at %v, want version 1 and a time", export.Version, export.ExportedAt)
+	}
+	if len(export.Bookmarks) != 1 || export.Bookmarks[0].IconURL != "https://theca.example/icons/abc" {
+		t.Errorf("root bookmarks = %+v, want one linking to its stored icon", export.Bookmarks)
+	}
+	if len(export.Folders) != 1 || export.Folders[0].Name != "Work & Play" || len(export.Folders[0].Folders) != 1 ||
+		export.Folders[0].Folders[0].Bookmarks[0].URL != "https://example.com/deep" {
+		t.Errorf("folders = %+v, want the nested folders of the tree", export.Folders)
+	}
+}
+
+func TestLookupUnknownFormat(t *testing.T) {
+	if _, err := Lookup("xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("Lookup(xml) error = %v, want ErrUnknownFormat", err)
+	}
+}
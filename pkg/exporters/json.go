@@ -0,0 +1,27 @@
+package exporters
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+)
+
+type jsonExport struct {
+	Version    int                 `json:"version"`
+	ExportedAt time.Time           `json:"exported_at"`
+	Folders    []domain.FolderNode `json:"folders"`
+	Bookmarks  []domain.Bookmark   `json:"bookmarks"`
+}
+
+func writeJSON(w io.Writer, tree domain.BookmarkTree) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(jsonExport{
+		Version:    1,
+		ExportedAt: time.Now().UTC(),
+		Folders:    tree.Folders,
+		Bookmarks:  tree.Bookmarks,
+	})
+}
@@ -0,0 +1,59 @@
+package exporters
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+)
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`)
+
+// writeMarkdown writes folders as headings and bookmarks as link lists.
+func writeMarkdown(w io.Writer, tree domain.BookmarkTree) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("# Bookmarks\n")
+	writeMarkdownLinks(bw, tree.Bookmarks)
+	for _, folder := range tree.Folders {
+		writeMarkdownFolder(bw, 2, folder)
+	}
+	return bw.Flush()
+}
+
+func writeMarkdownFolder(bw *bufio.Writer, level int, folder domain.FolderNode) {
+	if level > 6 {
+		level = 6
+	}
+	fmt.Fprintf(bw, "\n%s %s\n", strings.Repeat("#", level), folder.Name)
+	writeMarkdownLinks(bw, folder.Bookmarks)
+	for _, child := range folder.Folders {
+		writeMarkdownFolder(bw, level+1, child)
+	}
+}
+
+func writeMarkdownLinks(bw *bufio.Writer, bookmarks []domain.Bookmark) {
+	if len(bookmarks) == 0 {
+		return
+	}
+	bw.WriteString("\n")
+	for _, bookmark := range bookmarks {
+		title := bookmark.Title
+		if title == "" {
+			title = bookmark.URL
+		}
+		// Angle brackets keep URLs with spaces or parentheses intact.
+		fmt.Fprintf(bw, "- [%s](<%s>)", markdownEscaper.Replace(title), bookmark.URL)
+		if len(bookmark.Tags) > 0 {
+			bw.WriteString(" ")
+			for i, name := range tagNames(bookmark.Tags) {
+				if i > 0 {
+					bw.WriteString(" ")
+				}
+				fmt.Fprintf(bw, "#%s", strings.ReplaceAll(name, " ", "-"))
+			}
+		}
+		bw.WriteString("\n")
+	}
+}
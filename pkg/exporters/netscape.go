@@ -0,0 +1,57 @@
+package exporters
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+)
+
+const netscapeHeader = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+`
+
+// writeNetscape writes the Netscape bookmark file format understood by every
+// browser. TAGS and SHOW_TEXT are extensions that browsers ignore.
+func writeNetscape(w io.Writer, tree domain.BookmarkTree) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(netscapeHeader)
+	bw.WriteString("<DL><p>\n")
+	writeNetscapeLevel(bw, 1, tree.Bookmarks, tree.Folders)
+	bw.WriteString("</DL><p>\n")
+	return bw.Flush()
+}
+
+func writeNetscapeLevel(bw *bufio.Writer, depth int, bookmarks []domain.Bookmark, folders []domain.FolderNode) {
+	indent := strings.Repeat("    ", depth)
+	for _, bookmark := range bookmarks {
+		fmt.Fprintf(bw, `%s<DT><A HREF="%s"`, indent, html.EscapeString(bookmark.URL))
+		if !bookmark.CreatedAt.IsZero() {
+			fmt.Fprintf(bw, ` ADD_DATE="%d"`, bookmark.CreatedAt.Unix())
+		}
+		if bookmark.IconURL != "" {
+			fmt.Fprintf(bw, ` ICON_URI="%s"`, html.EscapeString(bookmark.IconURL))
+		}
+		if len(bookmark.Tags) > 0 {
+			fmt.Fprintf(bw, ` TAGS="%s"`, html.EscapeString(strings.Join(tagNames(bookmark.Tags), ",")))
+		}
+		if bookmark.ShowText {
+			bw.WriteString(` SHOW_TEXT="true"`)
+		}
+		fmt.Fprintf(bw, ">%s</A>\n", html.EscapeString(bookmark.Title))
+	}
+	for _, folder := range folders {
+		fmt.Fprintf(bw, "%s<DT><H3>%s</H3>\n", indent, html.EscapeString(folder.Name))
+		fmt.Fprintf(bw, "%s<DL><p>\n", indent)
+		writeNetscapeLevel(bw, depth+1, folder.Bookmarks, folder.Folders)
+		fmt.Fprintf(bw, "%s</DL><p>\n", indent)
+	}
+}
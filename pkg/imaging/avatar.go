@@ -0,0 +1,152 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// avatarFallbackLetter is drawn when none of the texts has a letter the
+// font can draw.
+const avatarFallbackLetter = '#'
+
+var avatarFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(gobold.TTF)
+})
+
+// AvatarLetter returns the first letter or digit of the first text that
+// has one the avatar font can draw, upper-cased.
+func AvatarLetter(texts ...string) rune {
+	f, err := avatarFont()
+	if err != nil {
+		return avatarFallbackLetter
+	}
+	var buf sfnt.Buffer
+	for _, text := range texts {
+		for _, r := range text {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				continue
+			}
+			r = unicode.ToUpper(r)
+			if index, err := f.GlyphIndex(&buf, r); err == nil && index != 0 {
+				return r
+			}
+			// Only the first letter of a text represents it.
+			break
+		}
+	}
+	return avatarFallbackLetter
+}
+
+// AvatarColor derives a background color from seed, so the same site
+// always gets the same color. Saturation and lightness are fixed to keep
+// white letters readable on every hue.
+func AvatarColor(seed string) color.NRGBA {
+	h := fnv.New32a()
+	h.Write([]byte(seed))
+	return hslToRGB(float64(h.Sum32()%360), 0.55, 0.45)
+}
+
+// LetterAvatar draws letter in white on background, as an SVG with PNG
+// variants. The output only depends on its arguments, so equal avatars
+// are stored once.
+func LetterAvatar(letter rune, background color.NRGBA) (*Icon, error) {
+	f, err := avatarFont()
+	if err != nil {
+		return nil, fmt.Errorf("parse avatar font: %w", err)
+	}
+
+	icon := &Icon{ContentType: contentTypes[FormatSVG], Original: avatarSVG(letter, background), Variants: make(map[int][]byte, len(VariantSizes))}
+	for _, size := range VariantSizes {
+		img, err := drawAvatar(f, letter, background, size)
+		if err != nil {
+			return nil, err
+		}
+		if icon.Variants[size], err = encodePNG(img); err != nil {
+			return nil, err
+		}
+		if size == colorSampleSize {
+			icon.setColors(img)
+		}
+	}
+	return icon, nil
+}
+
+func avatarSVG(letter rune, background color.NRGBA) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 128 128">`+
+		`<rect width="128" height="128" fill="#%02x%02x%02x"/>`+
+		`<text x="64" y="64" dy="0.35em" text-anchor="middle" font-family="Go, Helvetica, Arial, sans-serif" font-size="70" font-weight="bold" fill="#fff">`,
+		background.R, background.G, background.B)
+	xml.EscapeText(&buf, []byte(string(letter)))
+	buf.WriteString(`</text></svg>`)
+	return buf.Bytes()
+}
+
+// drawAvatar centers the glyph on its drawn bounds rather than on the
+// font's line metrics, so letters with and without descenders sit alike.
+func drawAvatar(f *opentype.Font, letter rune, background color.NRGBA, size int) (image.Image, error) {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: float64(size) * 0.55, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("load avatar font: %w", err)
+	}
+	defer face.Close()
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	bounds, _ := font.BoundString(face, string(letter))
+	width, height := bounds.Max.X-bounds.Min.X, bounds.Max.Y-bounds.Min.Y
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.White,
+		Face: face,
+		Dot: fixed.Point26_6{
+			X: (fixed.I(size)-width)/2 - bounds.Min.X,
+			Y: (fixed.I(size)-height)/2 - bounds.Min.Y,
+		},
+	}
+	drawer.DrawString(string(letter))
+	return img, nil
+}
+
+func hslToRGB(hue, saturation, lightness float64) color.NRGBA {
+	chroma := (1 - math.Abs(2*lightness-1)) * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	m := lightness - chroma/2
+
+	var r, g, b float64
+	switch {
+	case hue < 60:
+		r, g, b = chroma, x, 0
+	case hue < 120:
+		r, g, b = x, chroma, 0
+	case hue < 180:
+		r, g, b = 0, chroma, x
+	case hue < 240:
+		r, g, b = 0, x, chroma
+	case hue < 300:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+	return color.NRGBA{
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
+		A: 0xff,
+	}
+}
@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestAvatarLetter(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  rune
+	}{
+		{name: "first letter", texts: []string{"github"}, want: 'G'},
+		{name: "skips punctuation", texts: []string{"  «Hacker News»"}, want: 'H'},
+		{name: "digit", texts: []string{"9gag.com"}, want: '9'},
+		{name: "non-latin letter", texts: []string{"яндекс"}, want: 'Я'},
+		{name: "falls back to the next text", texts: []string{"", "---", "example.com"}, want: 'E'},
+		{name: "letter the font cannot draw", texts: []string{"日本", "nippon"}, want: 'N'},
+		{name: "nothing to draw", texts: []string{"", "!!!"}, want: avatarFallbackLetter},
+		{name: "no texts", want: avatarFallbackLetter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AvatarLetter(tt.texts...); got != tt.want {
+				t.Fatalf("AvatarLetter(%q) = %q, want %q", tt.texts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAvatarColor(t *testing.T) {
+	a, b := AvatarColor("github.com"), AvatarColor("github.com")
+	if a != b {
+		t.Fatalf("same seed gave %v and %v", a, b)
+	}
+	if a.A != 0xff {
+		t.Fatalf("color %v is not opaque", a)
+	}
+	if AvatarColor("gitlab.com") == a && AvatarColor("example.com") == a {
+		t.Fatal("different seeds all gave the same color")
+	}
+}
+
+func TestHSLToRGB(t *testing.T) {
+	tests := []struct {
+		hue, saturation, lightness float64
+		want                       color.NRGBA
+	}{
+		{0, 1, 0.5, color.NRGBA{R: 0xff, A: 0xff}},
+		{120, 1, 0.5, color.NRGBA{G: 0xff, A: 0xff}},
+		{240, 1, 0.5, color.NRGBA{B: 0xff, A: 0xff}},
+		{60, 1, 0.5, color.NRGBA{R: 0xff, G: 0xff, A: 0xff}},
+		{300, 1, 0.25, color.NRGBA{R: 0x80, B: 0x80, A: 0xff}},
+		{0, 0, 1, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+	}
+	for _, tt := range tests {
+		if got := hslToRGB(tt.hue, tt.saturation, tt.lightness); got != tt.want {
+			t.Errorf("hslToRGB(%v, %v, %v) = %v, want %v", tt.hue, tt.saturation, tt.lightness, got, tt.want)
+		}
+	}
+}
+
+func TestLetterAvatar(t *testing.T) {
+	background := color.NRGBA{R: 0x20, G: 0x60, B: 0xa0, A: 0xff}
+	icon, err := LetterAvatar('<', background)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if icon.ContentType != "image/svg+xml" {
+		t.Errorf("content type %q", icon.ContentType)
+	}
+	svg := string(icon.Original)
+	if !strings.Contains(svg, `fill="#2060a0"`) || !strings.Contains(svg, ">&lt;</text>") {
+		t.Errorf("SVG lacks the background or the escaped letter: %s", svg)
+	}
+	if icon.DominantColor != "#2060a0" {
+		t.Errorf("dominant color %q, want the background", icon.DominantColor)
+	}
+	for _, size := range VariantSizes {
+		img, err := png.Decode(bytes.NewReader(icon.Variants[size]))
+		if err != nil {
+			t.Fatalf("variant %d: %v", size, err)
+		}
+		if bounds := img.Bounds(); bounds.Dx() != size || bounds.Dy() != size {
+			t.Errorf("variant %d is %v", size, bounds)
+		}
+		if _, accent, _ := ExtractColors(img); accent == icon.DominantColor {
+			t.Errorf("variant %d has no letter drawn on it", size)
+		}
+	}
+
+	again, err := LetterAvatar('<', background)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again.Original, icon.Original) || !bytes.Equal(again.Variants[64], icon.Variants[64]) {
+		t.Error("equal avatars are drawn differently")
+	}
+}
@@ -0,0 +1,101 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// colorSampleSize is the edge of the image colors are taken from; larger
+// images are scaled down first.
+const colorSampleSize = 64
+
+type colorBucket struct {
+	count   int
+	r, g, b int
+}
+
+func (cb colorBucket) color() color.NRGBA {
+	return color.NRGBA{R: uint8(cb.r / cb.count), G: uint8(cb.g / cb.count), B: uint8(cb.b / cb.count), A: 0xff}
+}
+
+// ExtractColors returns the dominant color of an image, the one covering
+// most of it, and its accent color, the most vivid color that clearly
+// differs from the dominant one. Both are "#rrggbb". Transparent pixels
+// are ignored; ok is false when there are no others. The accent falls
+// back to the dominant color for single-colored images.
+func ExtractColors(img image.Image) (dominant, accent string, ok bool) {
+	if bounds := img.Bounds(); bounds.Dx() > colorSampleSize || bounds.Dy() > colorSampleSize {
+		img = fit(img, colorSampleSize)
+	}
+
+	// Colors are grouped by their 4 high bits per channel, so near
+	// identical shades from anti-aliasing count as one color.
+	buckets := make(map[int]*colorBucket)
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < 0x80 {
+				continue
+			}
+			key := int(c.R>>4)<<8 | int(c.G>>4)<<4 | int(c.B>>4)
+			bucket := buckets[key]
+			if bucket == nil {
+				bucket = &colorBucket{}
+				buckets[key] = bucket
+			}
+			bucket.count++
+			bucket.r += int(c.R)
+			bucket.g += int(c.G)
+			bucket.b += int(c.B)
+		}
+	}
+	if len(buckets) == 0 {
+		return "", "", false
+	}
+
+	var top *colorBucket
+	for _, bucket := range buckets {
+		if top == nil || bucket.count > top.count {
+			top = bucket
+		}
+	}
+	dominantColor := top.color()
+
+	accentColor, bestScore := dominantColor, 0.0
+	for _, bucket := range buckets {
+		c := bucket.color()
+		if colorDistance(c, dominantColor) < 100 {
+			continue
+		}
+		// Grey pixels still count a little, so a white logo on a colored
+		// background gets white as its accent.
+		score := float64(bucket.count) * (0.2 + saturation(c))
+		if score > bestScore {
+			accentColor, bestScore = c, score
+		}
+	}
+	return hexColor(dominantColor), hexColor(accentColor), true
+}
+
+func colorDistance(a, b color.NRGBA) float64 {
+	dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+// saturation is the HSL saturation of c, from 0 for greys to 1.
+func saturation(c color.NRGBA) float64 {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	high, low := max(r, g, b), min(r, g, b)
+	if high == low {
+		return 0
+	}
+	lightness := (high + low) / 2
+	return (high - low) / (1 - math.Abs(2*lightness-1))
+}
+
+func hexColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,76 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// stripes draws an image of colors, each covering as many rows as its
+// count.
+func stripes(width int, colors []color.NRGBA, counts []int) image.Image {
+	height := 0
+	for _, count := range counts {
+		height += count
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	y := 0
+	for i, c := range colors {
+		for end := y + counts[i]; y < end; y++ {
+			for x := 0; x < width; x++ {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img
+}
+
+func TestExtractColors(t *testing.T) {
+	var (
+		white       = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+		nearWhite   = color.NRGBA{R: 0xf8, G: 0xf8, B: 0xf8, A: 0xff}
+		grey        = color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+		red         = color.NRGBA{R: 0xff, A: 0xff}
+		blue        = color.NRGBA{B: 0xff, A: 0xff}
+		transparent = color.NRGBA{R: 0xff, G: 0xff}
+	)
+	tests := []struct {
+		name         string
+		img          image.Image
+		wantDominant string
+		wantAccent   string
+		wantOK       bool
+	}{
+		{name: "single color", img: stripes(4, []color.NRGBA{red}, []int{4}), wantDominant: "#ff0000", wantAccent: "#ff0000", wantOK: true},
+		{
+			name:         "vivid accent wins over a larger grey",
+			img:          stripes(4, []color.NRGBA{white, grey, blue}, []int{10, 4, 3}),
+			wantDominant: "#ffffff", wantAccent: "#0000ff", wantOK: true,
+		},
+		{
+			name:         "white logo on a colored background",
+			img:          stripes(4, []color.NRGBA{blue, white}, []int{10, 3}),
+			wantDominant: "#0000ff", wantAccent: "#ffffff", wantOK: true,
+		},
+		{
+			name:         "near identical shades are one color",
+			img:          stripes(4, []color.NRGBA{white, nearWhite, red}, []int{3, 3, 4}),
+			wantDominant: "#fbfbfb", wantAccent: "#ff0000", wantOK: true,
+		},
+		{
+			name:         "transparent pixels are ignored",
+			img:          stripes(4, []color.NRGBA{transparent, red}, []int{10, 1}),
+			wantDominant: "#ff0000", wantAccent: "#ff0000", wantOK: true,
+		},
+		{name: "fully transparent", img: stripes(4, []color.NRGBA{transparent}, []int{4})},
+		{name: "large image", img: stripes(500, []color.NRGBA{blue}, []int{500}), wantDominant: "#0000ff", wantAccent: "#0000ff", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dominant, accent, ok := ExtractColors(tt.img)
+			if dominant != tt.wantDominant || accent != tt.wantAccent || ok != tt.wantOK {
+				t.Fatalf("ExtractColors = %q, %q, %v, want %q, %q, %v", dominant, accent, ok, tt.wantDominant, tt.wantAccent, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+var errInvalidICO = errors.New("invalid ICO file")
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+type icoEntry struct {
+	width, height int
+	bitCount      int
+	size, offset  int
+}
+
+// decodeICO decodes the best image of an ICO file: the largest one, and of
+// equally large ones the one with the most colors. Frames may be PNG or
+// BMP data without the file header.
+func decodeICO(data []byte) (image.Image, error) {
+	if len(data) < 6 || binary.LittleEndian.Uint16(data[0:2]) != 0 {
+		return nil, errInvalidICO
+	}
+	if kind := binary.LittleEndian.Uint16(data[2:4]); kind != 1 && kind != 2 {
+		return nil, errInvalidICO
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 || len(data) < 6+16*count {
+		return nil, errInvalidICO
+	}
+
+	entries := make([]icoEntry, 0, count)
+	for i := 0; i < count; i++ {
+		raw := data[6+16*i : 6+16*(i+1)]
+		entry := icoEntry{
+			width:    int(raw[0]),
+			height:   int(raw[1]),
+			bitCount: int(binary.LittleEndian.Uint16(raw[6:8])),
+			size:     int(binary.LittleEndian.Uint32(raw[8:12])),
+			offset:   int(binary.LittleEndian.Uint32(raw[12:16])),
+		}
+		// 0 stands for 256 pixels.
+		if entry.width == 0 {
+			entry.width = 256
+		}
+		if entry.height == 0 {
+			entry.height = 256
+		}
+		if entry.offset < 0 || entry.size <= 0 || entry.offset+entry.size > len(data) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	// Try frames from best to worst; a broken frame should not cost the
+	// whole icon.
+	var lastErr error = errInvalidICO
+	for len(entries) > 0 {
+		best := 0
+		for i, entry := range entries {
+			if betterICOEntry(entry, entries[best]) {
+				best = i
+			}
+		}
+		entry := entries[best]
+		entries = append(entries[:best], entries[best+1:]...)
+
+		frame := data[entry.offset : entry.offset+entry.size]
+		var img image.Image
+		var err error
+		if bytes.HasPrefix(frame, pngSignature) {
+			img, err = decodeChecked(frame, png.DecodeConfig, png.Decode)
+		} else {
+			img, err = decodeDIB(frame)
+		}
+		if err == nil {
+			return img, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func betterICOEntry(a, b icoEntry) bool {
+	if edgeA, edgeB := max(a.width, a.height), max(b.width, b.height); edgeA != edgeB {
+		return edgeA > edgeB
+	}
+	return a.bitCount > b.bitCount
+}
+
+// decodeDIB decodes a BMP frame of an ICO file. Its height counts both the
+// color image and the 1-bit transparency mask that follows it.
+func decodeDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 || binary.LittleEndian.Uint32(data[0:4]) < 40 {
+		return nil, fmt.Errorf("%w: bad bitmap header", errInvalidICO)
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12]))) / 2
+	bitCount := int(binary.LittleEndian.Uint16(data[14:16]))
+	compression := binary.LittleEndian.Uint32(data[16:20])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:36]))
+
+	if width <= 0 || height <= 0 || width > 1024 || height > 1024 {
+		return nil, fmt.Errorf("%w: bad bitmap size %dx%d", errInvalidICO, width, height)
+	}
+	// 3 is BI_BITFIELDS, used by some 32-bit icons with the usual masks.
+	if compression != 0 && !(compression == 3 && bitCount == 32) {
+		return nil, fmt.Errorf("%w: unsupported bitmap compression %d", errInvalidICO, compression)
+	}
+
+	offset := headerSize
+	var palette []color.NRGBA
+	switch bitCount {
+	case 1, 4, 8:
+		if colorsUsed == 0 || colorsUsed > 1<<bitCount {
+			colorsUsed = 1 << bitCount
+		}
+		if len(data) < offset+4*colorsUsed {
+			return nil, fmt.Errorf("%w: truncated palette", errInvalidICO)
+		}
+		palette = make([]color.NRGBA, colorsUsed)
+		for i := range palette {
+			entry := data[offset+4*i:]
+			palette[i] = color.NRGBA{R: entry[2], G: entry[1], B: entry[0], A: 0xff}
+		}
+		offset += 4 * colorsUsed
+	case 24, 32:
+		if compression == 3 {
+			offset += 12
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported bit count %d", errInvalidICO, bitCount)
+	}
+
+	stride := (width*bitCount + 31) / 32 * 4
+	maskStride := (width + 31) / 32 * 4
+	if len(data) < offset+stride*height {
+		return nil, fmt.Errorf("%w: truncated bitmap", errInvalidICO)
+	}
+	pixels := data[offset : offset+stride*height]
+	var mask []byte
+	if len(data) >= offset+stride*height+maskStride*height {
+		mask = data[offset+stride*height : offset+stride*height+maskStride*height]
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		// Rows are stored bottom-up.
+		row := pixels[(height-1-y)*stride:]
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch bitCount {
+			case 32:
+				c = color.NRGBA{R: row[4*x+2], G: row[4*x+1], B: row[4*x], A: row[4*x+3]}
+				hasAlpha = hasAlpha || c.A != 0
+			case 24:
+				c = color.NRGBA{R: row[3*x+2], G: row[3*x+1], B: row[3*x], A: 0xff}
+			default:
+				perByte := 8 / bitCount
+				shift := uint(8 - bitCount*(x%perByte+1))
+				index := int(row[x/perByte]>>shift) & (1<<bitCount - 1)
+				if index < len(palette) {
+					c = palette[index]
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// 32-bit frames carry their own alpha; the mask only matters for the
+	// others, and for 32-bit frames written with an empty alpha channel.
+	if mask != nil && (bitCount != 32 || !hasAlpha) {
+		for y := 0; y < height; y++ {
+			row := mask[(height-1-y)*maskStride:]
+			for x := 0; x < width; x++ {
+				transparent := row[x/8]>>(7-uint(x%8))&1 == 1
+				c := img.NRGBAAt(x, y)
+				if transparent {
+					c.A = 0
+				} else {
+					c.A = 0xff
+				}
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img, nil
+}
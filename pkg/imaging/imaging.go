@@ -0,0 +1,209 @@
+// Package imaging turns icons found on the web into images that display
+// the same everywhere: multi-image ICO files are reduced to their best
+// frame, SVGs are sanitized and every icon gets square PNG variants.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+
+	// Formats decoded by image.Decode.
+	_ "image/gif"
+	_ "image/jpeg"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+var ErrUnsupported = errors.New("unsupported image format")
+
+// ErrTooLarge is returned for images whose header claims more than
+// maxEdge pixels on a side; decoding them could take gigabytes.
+var ErrTooLarge = errors.New("image too large")
+
+const maxEdge = 2048
+
+// VariantSizes are the edges in pixels of the PNG variants made of every
+// icon.
+var VariantSizes = []int{32, 64, 128}
+
+const (
+	FormatPNG  = "png"
+	FormatICO  = "ico"
+	FormatGIF  = "gif"
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+	FormatBMP  = "bmp"
+	FormatSVG  = "svg"
+)
+
+var contentTypes = map[string]string{
+	FormatPNG:  "image/png",
+	FormatICO:  "image/x-icon",
+	FormatGIF:  "image/gif",
+	FormatJPEG: "image/jpeg",
+	FormatWebP: "image/webp",
+	FormatBMP:  "image/bmp",
+	FormatSVG:  "image/svg+xml",
+}
+
+// DetectFormat recognises a supported image by its content, regardless of
+// the content type it was served with. It returns "" for anything else.
+func DetectFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		return FormatPNG
+	case bytes.HasPrefix(data, []byte{0, 0, 1, 0}), bytes.HasPrefix(data, []byte{0, 0, 2, 0}):
+		return FormatICO
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return FormatGIF
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8, 0xff}):
+		return FormatJPEG
+	case len(data) >= 12 && bytes.Equal(data[0:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return FormatWebP
+	case bytes.HasPrefix(data, []byte("BM")):
+		return FormatBMP
+	}
+
+	// HTML pages may start with an inline <svg> too.
+	head := bytes.ToLower(data[:min(len(data), 1024)])
+	if bytes.Contains(head, []byte("<svg")) && !bytes.Contains(head, []byte("<html")) {
+		return FormatSVG
+	}
+	return ""
+}
+
+// Icon is an icon ready to be stored. Original is the icon in its own
+// format, sanitized for SVGs. Variants holds PNGs by edge size and is
+// empty when an SVG could not be rasterized; its colors are unknown then.
+type Icon struct {
+	ContentType   string
+	Original      []byte
+	Variants      map[int][]byte
+	DominantColor string
+	AccentColor   string
+}
+
+func (icon *Icon) setColors(img image.Image) {
+	icon.DominantColor, icon.AccentColor, _ = ExtractColors(img)
+}
+
+func Normalize(data []byte) (*Icon, error) {
+	format := DetectFormat(data)
+	if format == "" {
+		return nil, ErrUnsupported
+	}
+
+	if format == FormatSVG {
+		return normalizeSVG(data)
+	}
+
+	var img image.Image
+	var err error
+	if format == FormatICO {
+		img, err = decodeICO(data)
+	} else {
+		img, err = decodeChecked(data, func(r io.Reader) (image.Config, error) {
+			config, _, err := image.DecodeConfig(r)
+			return config, err
+		}, func(r io.Reader) (image.Image, error) {
+			img, _, err := image.Decode(r)
+			return img, err
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", format, err)
+	}
+	if img.Bounds().Empty() {
+		return nil, fmt.Errorf("decode %s: empty image", format)
+	}
+
+	icon := &Icon{ContentType: contentTypes[format], Original: data, Variants: make(map[int][]byte, len(VariantSizes))}
+	icon.setColors(img)
+	for _, size := range VariantSizes {
+		variant, err := encodePNG(fit(img, size))
+		if err != nil {
+			return nil, err
+		}
+		icon.Variants[size] = variant
+	}
+	return icon, nil
+}
+
+// decodeChecked reads the image's size from its header and decodes it
+// only when it is within maxEdge.
+func decodeChecked(data []byte, decodeConfig func(io.Reader) (image.Config, error), decode func(io.Reader) (image.Image, error)) (image.Image, error) {
+	config, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width > maxEdge || config.Height > maxEdge {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, config.Width, config.Height)
+	}
+	return decode(bytes.NewReader(data))
+}
+
+// normalizeSVG keeps the sanitized SVG and, when it can be drawn, renders
+// each variant at its own size rather than scaling one rendering.
+func normalizeSVG(data []byte) (*Icon, error) {
+	sanitized, err := sanitizeSVG(data)
+	if err != nil {
+		return nil, err
+	}
+
+	icon := &Icon{ContentType: contentTypes[FormatSVG], Original: sanitized}
+	variants := make(map[int][]byte, len(VariantSizes))
+	for _, size := range VariantSizes {
+		img, err := rasterizeSVG(sanitized, size)
+		if err != nil {
+			return icon, nil
+		}
+		if variants[size], err = encodePNG(img); err != nil {
+			return nil, err
+		}
+		if size == colorSampleSize {
+			icon.setColors(img)
+		}
+	}
+	icon.Variants = variants
+	return icon, nil
+}
+
+// fit scales img to fit a size x size square, centered on a transparent
+// background. Small icons scaled up by 2x or more use nearest neighbour so
+// pixel art stays sharp instead of turning blurry.
+func fit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := size, size
+	if bounds.Dx() > bounds.Dy() {
+		height = max(1, size*bounds.Dy()/bounds.Dx())
+	} else {
+		width = max(1, size*bounds.Dx()/bounds.Dy())
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	target := image.Rect((size-width)/2, (size-height)/2, (size-width)/2+width, (size-height)/2+height)
+
+	var scaler xdraw.Scaler = xdraw.CatmullRom
+	if width >= 2*bounds.Dx() {
+		scaler = xdraw.NearestNeighbor
+	}
+	scaler.Scale(dst, target, img, bounds, draw.Src, nil)
+	return dst
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
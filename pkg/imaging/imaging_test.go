@@ -0,0 +1,249 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodeTestPNG(t *testing.T, width, height int, c color.NRGBA) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngHeader is the start of a PNG claiming width x height pixels, enough
+// for its size to be read but not for it to be decoded.
+func pngHeader(width, height uint32) []byte {
+	chunk := []byte("IHDR")
+	chunk = binary.BigEndian.AppendUint32(chunk, width)
+	chunk = binary.BigEndian.AppendUint32(chunk, height)
+	chunk = append(chunk, 8, 6, 0, 0, 0) // 8-bit RGBA
+
+	data := append([]byte(nil), pngSignature...)
+	data = binary.BigEndian.AppendUint32(data, 13)
+	data = append(data, chunk...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk))
+}
+
+type icoFrame struct {
+	width, bitCount int
+	data            []byte
+}
+
+func encodeTestICO(frames ...icoFrame) []byte {
+	data := []byte{0, 0, 1, 0}
+	data = binary.LittleEndian.AppendUint16(data, uint16(len(frames)))
+	offset := 6 + 16*len(frames)
+	for _, frame := range frames {
+		edge := byte(frame.width) // 256 wraps to 0, as in real files
+		data = append(data, edge, edge, 0, 0, 1, 0)
+		data = binary.LittleEndian.AppendUint16(data, uint16(frame.bitCount))
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(frame.data)))
+		data = binary.LittleEndian.AppendUint32(data, uint32(offset))
+		offset += len(frame.data)
+	}
+	for _, frame := range frames {
+		data = append(data, frame.data...)
+	}
+	return data
+}
+
+// encodeTestDIB writes a 2x2 BMP frame of an ICO file: the top row red,
+// the bottom row blue, with the top left pixel transparent in the mask.
+func encodeTestDIB(bitCount int) []byte {
+	header := make([]byte, 40)
+	binary.LittleEndian.PutUint32(header[0:4], 40)
+	binary.LittleEndian.PutUint32(header[4:8], 2)
+	binary.LittleEndian.PutUint32(header[8:12], 4) // image and mask
+	binary.LittleEndian.PutUint16(header[12:14], 1)
+	binary.LittleEndian.PutUint16(header[14:16], uint16(bitCount))
+
+	pixel := func(b, g, r byte) []byte {
+		if bitCount == 32 {
+			return []byte{b, g, r, 0} // empty alpha, the mask applies
+		}
+		return []byte{b, g, r}
+	}
+	row := func(b, g, r byte) []byte {
+		row := append(pixel(b, g, r), pixel(b, g, r)...)
+		for len(row)%4 != 0 {
+			row = append(row, 0)
+		}
+		return row
+	}
+	data := append(header, row(0xff, 0, 0)...)     // bottom row, blue
+	data = append(data, row(0, 0, 0xff)...)        // top row, red
+	data = append(data, 0, 0, 0, 0, 0x80, 0, 0, 0) // mask, bottom row first
+	return data
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "png", data: pngHeader(16, 16), want: FormatPNG},
+		{name: "ico", data: []byte{0, 0, 1, 0, 1, 0}, want: FormatICO},
+		{name: "cur", data: []byte{0, 0, 2, 0, 1, 0}, want: FormatICO},
+		{name: "gif", data: []byte("GIF89a..."), want: FormatGIF},
+		{name: "jpeg", data: []byte{0xff, 0xd8, 0xff, 0xe0}, want: FormatJPEG},
+		{name: "webp", data: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), want: FormatWebP},
+		{name: "bmp", data: []byte("BM\x00\x00"), want: FormatBMP},
+		{name: "svg", data: []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"/>`), want: FormatSVG},
+		{name: "html with inline svg", data: []byte(`<!DOCTYPE html><html><body><svg></svg></body></html>`), want: ""},
+		{name: "text", data: []byte("Not Found"), want: ""},
+		{name: "empty", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat(tt.data); got != tt.want {
+				t.Fatalf("DetectFormat = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	tests := []struct {
+		name            string
+		data            []byte
+		wantContentType string
+		wantColor       string
+	}{
+		{name: "png", data: encodeTestPNG(t, 16, 16, red), wantContentType: "image/png", wantColor: "#ff0000"},
+		{name: "wide png", data: encodeTestPNG(t, 300, 100, red), wantContentType: "image/png", wantColor: "#ff0000"},
+		{
+			name:            "ico with png frames",
+			data:            encodeTestICO(icoFrame{16, 32, encodeTestPNG(t, 16, 16, red)}, icoFrame{256, 32, encodeTestPNG(t, 256, 256, color.NRGBA{G: 0xff, A: 0xff})}),
+			wantContentType: "image/x-icon",
+			wantColor:       "#00ff00",
+		},
+		{name: "ico with a 32-bit bitmap", data: encodeTestICO(icoFrame{2, 32, encodeTestDIB(32)}), wantContentType: "image/x-icon", wantColor: "#0000ff"},
+		{name: "ico with a 24-bit bitmap", data: encodeTestICO(icoFrame{2, 24, encodeTestDIB(24)}), wantContentType: "image/x-icon", wantColor: "#0000ff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			icon, err := Normalize(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if icon.ContentType != tt.wantContentType || !bytes.Equal(icon.Original, tt.data) {
+				t.Errorf("content type %q, want %q with the original data", icon.ContentType, tt.wantContentType)
+			}
+			if icon.DominantColor != tt.wantColor {
+				t.Errorf("dominant color %q, want %q", icon.DominantColor, tt.wantColor)
+			}
+			for _, size := range VariantSizes {
+				config, err := png.DecodeConfig(bytes.NewReader(icon.Variants[size]))
+				if err != nil {
+					t.Fatalf("variant %d: %v", size, err)
+				}
+				if config.Width != size || config.Height != size {
+					t.Errorf("variant %d is %dx%d", size, config.Width, config.Height)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeICOMask(t *testing.T) {
+	img, err := decodeICO(encodeTestICO(icoFrame{2, 24, encodeTestDIB(24)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{0, 0, color.NRGBA{R: 0xff}},
+		{1, 0, color.NRGBA{R: 0xff, A: 0xff}},
+		{0, 1, color.NRGBA{B: 0xff, A: 0xff}},
+		{1, 1, color.NRGBA{B: 0xff, A: 0xff}},
+	}
+	for _, tt := range tests {
+		if got := color.NRGBAModel.Convert(img.At(tt.x, tt.y)); got != tt.want {
+			t.Errorf("pixel (%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	broken := encodeTestPNG(t, 16, 16, color.NRGBA{A: 0xff})
+	broken = broken[:len(broken)/2]
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "unknown format", data: []byte("<html>Not Found</html>"), wantErr: ErrUnsupported},
+		{name: "huge png", data: pngHeader(10000, 10000), wantErr: ErrTooLarge},
+		{name: "tall png", data: pngHeader(16, maxEdge+1), wantErr: ErrTooLarge},
+		{name: "huge png in an ico", data: encodeTestICO(icoFrame{256, 32, pngHeader(10000, 10000)}), wantErr: ErrTooLarge},
+		{name: "ico without frames", data: []byte{0, 0, 1, 0, 0, 0}, wantErr: errInvalidICO},
+		{name: "ico frame out of bounds", data: encodeTestICO(icoFrame{16, 32, nil})[:6+16], wantErr: errInvalidICO},
+		{name: "truncated png", data: broken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			icon, err := Normalize(tt.data)
+			if err == nil {
+				t.Fatalf("accepted as %q", icon.ContentType)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeSanitizesSVG(t *testing.T) {
+	data := []byte(`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 10 10" onload="alert(1)">` +
+		`<script>alert(2)</script>` +
+		`<a xlink:href="javascript:alert(3)"><rect width="10" height="10" fill="#ff0000"/></a>` +
+		`<image href="https://tracker.example/pixel.png"/>` +
+		`<rect style="fill: url(https://tracker.example/x)"/>` +
+		`<use href="#shape"/>` +
+		`</svg>`)
+	icon, err := Normalize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if icon.ContentType != "image/svg+xml" {
+		t.Errorf("content type %q", icon.ContentType)
+	}
+	sanitized := string(icon.Original)
+	for _, banned := range []string{"onload", "script", "alert", "javascript", "tracker.example"} {
+		if strings.Contains(sanitized, banned) {
+			t.Errorf("sanitized SVG still contains %q: %s", banned, sanitized)
+		}
+	}
+	if !strings.Contains(sanitized, `href="#shape"`) || !strings.Contains(sanitized, `fill="#ff0000"`) {
+		t.Errorf("sanitized SVG lost safe content: %s", sanitized)
+	}
+	if len(icon.Variants) != len(VariantSizes) || icon.DominantColor != "#ff0000" {
+		t.Errorf("got %d variants and dominant color %q, want %d and #ff0000", len(icon.Variants), icon.DominantColor, len(VariantSizes))
+	}
+}
+
+func TestSanitizeSVGRejectsOtherXML(t *testing.T) {
+	if _, err := sanitizeSVG([]byte(`<html><svg/></html>`)); err == nil {
+		t.Fatal("a document whose root is not <svg> was accepted")
+	}
+}
@@ -0,0 +1,149 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// unsafeSVGElements are dropped with everything inside them. They run
+// scripts or embed other documents.
+var unsafeSVGElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"object":        true,
+	"embed":         true,
+	"handler":       true,
+	"listener":      true,
+	"set":           true,
+	"animate":       true,
+}
+
+// sanitizeSVG rewrites an SVG keeping only what is needed to draw it:
+// scripts, event handlers, DOCTYPEs (and with them entity expansion) and
+// links to anything but fragments of the document itself or embedded
+// raster images are removed.
+func sanitizeSVG(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	var out bytes.Buffer
+	skipDepth := 0
+	sawRoot := false
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse SVG: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 || unsafeSVGElements[strings.ToLower(t.Name.Local)] {
+				skipDepth++
+				continue
+			}
+			if !sawRoot {
+				if strings.ToLower(t.Name.Local) != "svg" {
+					return nil, errors.New("parse SVG: root element is not <svg>")
+				}
+				sawRoot = true
+			}
+			out.WriteString("<" + rawName(t.Name))
+			for _, attr := range t.Attr {
+				if !safeSVGAttr(attr) {
+					continue
+				}
+				out.WriteString(" " + rawName(attr.Name) + `="`)
+				xml.EscapeText(&out, []byte(attr.Value))
+				out.WriteString(`"`)
+			}
+			out.WriteString(">")
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			out.WriteString("</" + rawName(t.Name) + ">")
+		case xml.CharData:
+			if skipDepth == 0 && sawRoot {
+				xml.EscapeText(&out, t)
+			}
+		}
+	}
+	if !sawRoot {
+		return nil, errors.New("parse SVG: no <svg> element")
+	}
+	return out.Bytes(), nil
+}
+
+func rawName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+func safeSVGAttr(attr xml.Attr) bool {
+	local := strings.ToLower(attr.Name.Local)
+	if strings.HasPrefix(local, "on") {
+		return false
+	}
+	value := strings.ToLower(strings.TrimSpace(attr.Value))
+	switch local {
+	case "href":
+		return strings.HasPrefix(value, "#") || strings.HasPrefix(value, "data:image/png") ||
+			strings.HasPrefix(value, "data:image/jpeg") || strings.HasPrefix(value, "data:image/gif")
+	}
+	return !strings.Contains(value, "javascript:") && !containsExternalURL(value)
+}
+
+// containsExternalURL reports whether a style or presentation attribute
+// refers to anything outside the document, such as url(https://...).
+func containsExternalURL(style string) bool {
+	for rest := style; ; {
+		i := strings.Index(rest, "url(")
+		if i < 0 {
+			return false
+		}
+		rest = strings.TrimLeft(rest[i+len("url("):], ` '"`)
+		if !strings.HasPrefix(rest, "#") {
+			return true
+		}
+	}
+}
+
+// rasterizeSVG draws the SVG into a size x size image, keeping its aspect
+// ratio.
+func rasterizeSVG(data []byte, size int) (image.Image, error) {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(data), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, fmt.Errorf("rasterize SVG: %w", err)
+	}
+	if icon.ViewBox.W <= 0 || icon.ViewBox.H <= 0 {
+		return nil, errors.New("rasterize SVG: missing view box")
+	}
+
+	width, height := float64(size), float64(size)
+	if icon.ViewBox.W > icon.ViewBox.H {
+		height = width * icon.ViewBox.H / icon.ViewBox.W
+	} else {
+		width = height * icon.ViewBox.W / icon.ViewBox.H
+	}
+	icon.SetTarget((float64(size)-width)/2, (float64(size)-height)/2, width, height)
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	scanner := rasterx.NewScannerGV(size, size, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(size, size, scanner), 1)
+	return img, nil
+}
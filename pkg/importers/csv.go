@@ -0,0 +1,117 @@
+package importers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// pocketCSV reads the CSV export Pocket switched to in 2023:
+// title,url,time_added,tags,status with tags separated by "|".
+type pocketCSV struct{}
+
+func (pocketCSV) Name() string { return "pocket" }
+
+func (pocketCSV) Detect(_ string, head []byte) bool {
+	columns := csvHeader(head)
+	return columns["url"] && columns["time_added"]
+}
+
+func (pocketCSV) Parse(r io.Reader) ([]Bookmark, error) {
+	return readCSV(r, func(row csvRow) Bookmark {
+		return Bookmark{
+			Title:   row.get("title"),
+			URL:     row.get("url"),
+			AddDate: parseUnixTime(row.get("time_added")),
+			Tags:    splitTags(row.get("tags"), "|"),
+			RawTags: row.get("tags"),
+		}
+	})
+}
+
+// raindropCSV reads the CSV export of Raindrop.io. The folder column holds
+// the collection path separated by "/" and tags are separated by ",".
+type raindropCSV struct{}
+
+func (raindropCSV) Name() string { return "raindrop" }
+
+func (raindropCSV) Detect(_ string, head []byte) bool {
+	columns := csvHeader(head)
+	return columns["url"] && columns["folder"] && columns["created"]
+}
+
+func (raindropCSV) Parse(r io.Reader) ([]Bookmark, error) {
+	return readCSV(r, func(row csvRow) Bookmark {
+		return Bookmark{
+			Title:      row.get("title"),
+			URL:        row.get("url"),
+			IconURL:    row.get("cover"),
+			AddDate:    parseTimestamp(row.get("created")),
+			FolderPath: splitTags(row.get("folder"), "/"),
+			Tags:       splitTags(row.get("tags"), ","),
+			RawTags:    row.get("tags"),
+		}
+	})
+}
+
+type csvRow struct {
+	columns map[string]int
+	record  []string
+}
+
+func (r csvRow) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+func readCSV(r io.Reader, convert func(row csvRow) Bookmark) ([]Bookmark, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[normalizeColumn(name)] = i
+	}
+
+	var bookmarks []Bookmark
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return bookmarks, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if bookmark := convert(csvRow{columns: columns, record: record}); bookmark.URL != "" {
+			bookmarks = append(bookmarks, bookmark)
+		}
+	}
+}
+
+// csvHeader returns the column names of the first line of a CSV file.
+func csvHeader(head []byte) map[string]bool {
+	line, _, _ := bytes.Cut(head, []byte("\n"))
+	record, err := csv.NewReader(bytes.NewReader(line)).Read()
+	if err != nil {
+		return nil
+	}
+	columns := make(map[string]bool, len(record))
+	for _, name := range record {
+		columns[normalizeColumn(name)] = true
+	}
+	return columns
+}
+
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\xef\xbb\xbf")))
+}
@@ -0,0 +1,112 @@
+package importers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/OxytocinGroup/theca-backend/pkg/parsers"
+	"golang.org/x/net/html"
+)
+
+// netscapeHTML reads browser exports and files written by our own exporter.
+type netscapeHTML struct{}
+
+func (netscapeHTML) Name() string { return "netscape" }
+
+func (netscapeHTML) Detect(_ string, head []byte) bool {
+	return bytes.Contains(bytes.ToUpper(head), []byte("NETSCAPE-BOOKMARK-FILE"))
+}
+
+func (netscapeHTML) Parse(r io.Reader) ([]Bookmark, error) {
+	entries, err := parsers.ParseNetscapeBookmarks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarks := make([]Bookmark, 0, len(entries))
+	for _, entry := range entries {
+		bookmarks = append(bookmarks, Bookmark{
+			Title:      entry.Title,
+			URL:        entry.URL,
+			IconURL:    entry.IconURL,
+			AddDate:    entry.AddDate,
+			FolderPath: entry.FolderPath,
+			Tags:       entry.Tags,
+			RawTags:    strings.Join(entry.Tags, ","),
+			ShowText:   entry.ShowText,
+		})
+	}
+	return bookmarks, nil
+}
+
+// pocketHTML reads the ril_export.html file of Pocket: <h1> sections
+// ("Unread", "Read Archive") each followed by a list of links carrying
+// time_added and comma separated tags attributes.
+type pocketHTML struct{}
+
+func (pocketHTML) Name() string { return "pocket" }
+
+func (pocketHTML) Detect(_ string, head []byte) bool {
+	return bytes.Contains(head, []byte("Pocket Export")) || bytes.Contains(head, []byte("time_added="))
+}
+
+func (pocketHTML) Parse(r io.Reader) ([]Bookmark, error) {
+	tokenizer := html.NewTokenizer(r)
+
+	var (
+		bookmarks []Bookmark
+		current   *Bookmark
+		text      strings.Builder
+	)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				return bookmarks, nil
+			}
+			return nil, fmt.Errorf("failed to read Pocket export: %w", tokenizer.Err())
+
+		case html.StartTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if string(name) != "a" {
+				continue
+			}
+			attrs := readAttrs(tokenizer, hasAttr)
+			current = &Bookmark{
+				URL:     strings.TrimSpace(attrs["href"]),
+				AddDate: parseUnixTime(attrs["time_added"]),
+				Tags:    splitTags(attrs["tags"], ","),
+				RawTags: attrs["tags"],
+			}
+			text.Reset()
+
+		case html.TextToken:
+			if current != nil {
+				text.Write(tokenizer.Text())
+			}
+
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if string(name) == "a" && current != nil {
+				current.Title = strings.TrimSpace(text.String())
+				if current.URL != "" {
+					bookmarks = append(bookmarks, *current)
+				}
+				current = nil
+			}
+		}
+	}
+}
+
+func readAttrs(tokenizer *html.Tokenizer, hasAttr bool) map[string]string {
+	attrs := make(map[string]string)
+	for hasAttr {
+		var key, val []byte
+		key, val, hasAttr = tokenizer.TagAttr()
+		attrs[strings.ToLower(string(key))] = string(val)
+	}
+	return attrs
+}
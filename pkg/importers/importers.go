@@ -0,0 +1,75 @@
+package importers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrUnknownFormat = errors.New("unknown import file format")
+
+// Bookmark is an entry read from an import file.
+type Bookmark struct {
+	Title      string
+	URL        string
+	IconURL    string
+	AddDate    time.Time
+	FolderPath []string
+	Tags       []string
+	// RawTags keeps the tags exactly as the source service wrote them.
+	RawTags  string
+	ShowText bool
+}
+
+// Parser reads the export file of one bookmark service.
+type Parser interface {
+	// Name identifies the source, it is stored on imported bookmarks.
+	Name() string
+	// Detect reports whether the file looks like this parser's format.
+	// head holds the beginning of the file.
+	Detect(filename string, head []byte) bool
+	Parse(r io.Reader) ([]Bookmark, error)
+}
+
+// detectSize is how much of a file is handed to Detect.
+const detectSize = 4096
+
+var registry []Parser
+
+// Register adds a parser. Parsers are tried in registration order, so more
+// specific formats must be registered first.
+func Register(p Parser) {
+	registry = append(registry, p)
+}
+
+func init() {
+	Register(pinboardJSON{})
+	Register(netscapeHTML{})
+	Register(pocketHTML{})
+	Register(pocketCSV{})
+	Register(raindropCSV{})
+}
+
+// Parse detects the format of an import file and reads its bookmarks. It
+// returns the name of the parser that was used.
+func Parse(filename string, r io.Reader) (string, []Bookmark, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	head := data
+	if len(head) > detectSize {
+		head = head[:detectSize]
+	}
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+
+	for _, p := range registry {
+		if p.Detect(filename, head) {
+			bookmarks, err := p.Parse(bytes.NewReader(data))
+			return p.Name(), bookmarks, err
+		}
+	}
+	return "", nil, ErrUnknownFormat
+}
@@ -0,0 +1,161 @@
+package importers
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	netscapeExport = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+    <DT><H3>Dev</H3>
+    <DL><p>
+        <DT><A HREF="https://go.dev/" ADD_DATE="1700000000" TAGS="go,lang">Go</A>
+    </DL><p>
+</DL><p>
+`
+	pocketHTMLExport = `<!DOCTYPE html>
+<html><head><title>Pocket Export</title></head><body>
+<h1>Unread</h1>
+<ul>
+<li><a href="https://go.dev/" time_added="1700000000" tags="go,lang">Go</a></li>
+<li><a href="">Empty</a></li>
+</ul>
+<h1>Read Archive</h1>
+<ul>
+<li><a href="https://example.com/" time_added="1700000000" tags="">Example</a></li>
+</ul>
+</body></html>
+`
+	pocketCSVExport = "\xef\xbb\xbftitle,url,time_added,tags,status\n" +
+		"Go,https://go.dev/,1700000000,go|lang,unread\n" +
+		"No URL,,1700000000,,unread\n"
+	raindropCSVExport = "id,title,note,excerpt,url,folder,tags,created,cover,highlights,favorite\n" +
+		`1,Go,,,https://go.dev/,Dev/Languages,"go, lang",2023-11-14T22:13:20.000Z,https://go.dev/cover.png,,false` + "\n"
+	pinboardExport = `[
+  {"href": "https://go.dev/", "description": " Go ", "time": "2023-11-14T22:13:20Z", "tags": "go  lang"},
+  {"href": " ", "description": "blank"}
+]`
+)
+
+func TestParseDetectsFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		filename   string
+		content    string
+		wantParser string
+		wantErr    error
+	}{
+		{name: "netscape", filename: "bookmarks.html", content: netscapeExport, wantParser: "netscape"},
+		{name: "pocket html", filename: "ril_export.html", content: pocketHTMLExport, wantParser: "pocket"},
+		{name: "pocket csv", filename: "part_000000.csv", content: pocketCSVExport, wantParser: "pocket"},
+		{name: "raindrop csv", filename: "export.csv", content: raindropCSVExport, wantParser: "raindrop"},
+		{name: "pinboard json", filename: "pinboard.json", content: pinboardExport, wantParser: "pinboard"},
+		{name: "unrelated csv", filename: "data.csv", content: "name,age\nbob,3\n", wantErr: ErrUnknownFormat},
+		{name: "unrelated json", filename: "data.json", content: `{"href": "https://go.dev/"}`, wantErr: ErrUnknownFormat},
+		{name: "empty", filename: "empty.html", wantErr: ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, _, err := Parse(tt.filename, strings.NewReader(tt.content))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if parser != tt.wantParser {
+				t.Fatalf("parser = %q, want %q", parser, tt.wantParser)
+			}
+		})
+	}
+}
+
+func TestParseReadsBookmarks(t *testing.T) {
+	added := time.Unix(1700000000, 0)
+	goBookmark := Bookmark{Title: "Go", URL: "https://go.dev/", AddDate: added, Tags: []string{"go", "lang"}}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []Bookmark
+	}{
+		{
+			name:    "netscape",
+			content: netscapeExport,
+			want:    []Bookmark{{Title: "Go", URL: "https://go.dev/", AddDate: added, FolderPath: []string{"Dev"}, Tags: []string{"go", "lang"}, RawTags: "go,lang"}},
+		},
+		{
+			name:    "pocket html",
+			content: pocketHTMLExport,
+			want: []Bookmark{
+				withRawTags(goBookmark, "go,lang"),
+				{Title: "Example", URL: "https://example.com/", AddDate: added},
+			},
+		},
+		{
+			name:    "pocket csv",
+			content: pocketCSVExport,
+			want:    []Bookmark{withRawTags(goBookmark, "go|lang")},
+		},
+		{
+			name:    "raindrop csv",
+			content: raindropCSVExport,
+			want: []Bookmark{{
+				Title: "Go", URL: "https://go.dev/", IconURL: "https://go.dev/cover.png", AddDate: added,
+				FolderPath: []string{"Dev", "Languages"}, Tags: []string{"go", "lang"}, RawTags: "go, lang",
+			}},
+		},
+		{
+			name:    "pinboard json",
+			content: pinboardExport,
+			want:    []Bookmark{withRawTags(goBookmark, "go  lang")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := Parse("", strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d bookmarks, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if !got[i].AddDate.Equal(tt.want[i].AddDate) {
+					t.Errorf("bookmark %d added %v, want %v", i, got[i].AddDate, tt.want[i].AddDate)
+				}
+				got[i].AddDate, tt.want[i].AddDate = time.Time{}, time.Time{}
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("bookmark %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func withRawTags(bookmark Bookmark, rawTags string) Bookmark {
+	bookmark.RawTags = rawTags
+	return bookmark
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2023-11-14T22:13:20Z", time.Unix(1700000000, 0)},
+		{"2023-11-14T22:13:20.000Z", time.Unix(1700000000, 0)},
+		{"2023-11-14T22:13:20", time.Unix(1700000000, 0)},
+		{"2023-11-14 22:13:20", time.Unix(1700000000, 0)},
+		{"2023-11-14", time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC)},
+		{"1700000000", time.Unix(1700000000, 0)},
+		{"", time.Time{}},
+		{"last week", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseTimestamp(tt.value); !got.Equal(tt.want) {
+			t.Errorf("parseTimestamp(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package importers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// pinboardJSON reads the JSON export of Pinboard. Tags are separated by
+// spaces and description holds the title.
+type pinboardJSON struct{}
+
+type pinboardPost struct {
+	Href        string `json:"href"`
+	Description string `json:"description"`
+	Time        string `json:"time"`
+	Tags        string `json:"tags"`
+}
+
+func (pinboardJSON) Name() string { return "pinboard" }
+
+func (pinboardJSON) Detect(_ string, head []byte) bool {
+	head = bytes.TrimSpace(head)
+	return bytes.HasPrefix(head, []byte("[")) && bytes.Contains(head, []byte(`"href"`))
+}
+
+func (pinboardJSON) Parse(r io.Reader) ([]Bookmark, error) {
+	var posts []pinboardPost
+	if err := json.NewDecoder(r).Decode(&posts); err != nil {
+		return nil, fmt.Errorf("failed to read Pinboard export: %w", err)
+	}
+
+	bookmarks := make([]Bookmark, 0, len(posts))
+	for _, post := range posts {
+		if strings.TrimSpace(post.Href) == "" {
+			continue
+		}
+		bookmarks = append(bookmarks, Bookmark{
+			Title:   strings.TrimSpace(post.Description),
+			URL:     strings.TrimSpace(post.Href),
+			AddDate: parseTimestamp(post.Time),
+			Tags:    strings.Fields(post.Tags),
+			RawTags: post.Tags,
+		})
+	}
+	return bookmarks, nil
+}
@@ -0,0 +1,37 @@
+package importers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+func splitTags(value, sep string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, sep) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// parseUnixTime reads a timestamp in seconds since the epoch.
+func parseUnixTime(value string) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(n, 0)
+}
+
+// parseTimestamp reads ISO 8601 dates, falling back to a unix timestamp.
+func parseTimestamp(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return parseUnixTime(value)
+}
@@ -1,87 +1,278 @@
 package parsers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
-	"sync"
 
+	"github.com/OxytocinGroup/theca-backend/pkg/imaging"
 	"golang.org/x/net/html"
 )
 
-func FetchFavicon(resourceURL string) (string, error) {
-	resp, err := http.Get(resourceURL)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch URL: %w", err)
+var ErrFaviconNotFound = errors.New("favicon not found")
+
+const (
+	maxManifestSize = 512 << 10
+	MaxIconSize     = 1 << 20
+
+	// A page is not worth more than maxIconCandidates downloads, its best
+	// ones, nor more than maxManifests manifests.
+	maxIconCandidates = 8
+	maxManifests      = 2
+)
+
+// Candidate kinds, in the order they are tried when their sizes are equal.
+const (
+	kindIcon = iota
+	kindAppleTouchIcon
+	kindManifestIcon
+	kindFaviconICO
+	kindMaskIcon
+	kindOpenGraph
+)
+
+// iconCandidate is a possible favicon of a page. size is the largest edge
+// in pixels, 0 when unknown.
+type iconCandidate struct {
+	url  string
+	kind int
+	size int
+}
+
+// Favicon is an icon downloaded from a site, decoded and normalized.
+type Favicon struct {
+	URL  string
+	Icon *imaging.Icon
+}
+
+// FaviconResolver finds the best icon of a web page. It collects every
+// icon the page declares, then checks the best candidates in order and
+// returns the first one that is an image it can decode.
+type FaviconResolver struct {
+	Fetcher *Fetcher
+}
+
+func NewFaviconResolver(fetcher *Fetcher) *FaviconResolver {
+	return &FaviconResolver{Fetcher: fetcher}
+}
+
+var defaultFaviconResolver = NewFaviconResolver(DefaultFetcher)
+
+// FetchFavicon downloads the best icon of the page at resourceURL.
+func FetchFavicon(ctx context.Context, resourceURL string) (*Favicon, error) {
+	return defaultFaviconResolver.Resolve(ctx, resourceURL)
+}
+
+func (fr *FaviconResolver) Resolve(ctx context.Context, pageURL string) (*Favicon, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("invalid page URL %q", pageURL)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+	// The page may fail to load or not be HTML; /favicon.ico is still
+	// worth trying then.
+	candidates, pageErr := fr.pageCandidates(ctx, base)
+	if ico, err := base.Parse("/favicon.ico"); err == nil {
+		candidates = append(candidates, iconCandidate{url: ico.String(), kind: kindFaviconICO})
 	}
+	sortCandidates(candidates)
 
-	doc, err := html.Parse(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	seen := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		if seen[candidate.url] {
+			continue
+		}
+		if len(seen) == maxIconCandidates {
+			break
+		}
+		seen[candidate.url] = true
+		if favicon := fr.download(ctx, candidate.url); favicon != nil {
+			return favicon, nil
+		}
+	}
+
+	if pageErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFaviconNotFound, pageErr)
 	}
+	return nil, ErrFaviconNotFound
+}
 
-	var baseURL *url.URL
-	baseURL, _ = url.Parse(resourceURL)
+// sortCandidates orders candidates from best to worst: declared icons by
+// size, then /favicon.ico, the monochrome mask icon and the og:image.
+func sortCandidates(candidates []iconCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if fallbackA, fallbackB := a.kind >= kindFaviconICO, b.kind >= kindFaviconICO; fallbackA != fallbackB || fallbackA {
+			return a.kind < b.kind
+		}
+		if a.size != b.size {
+			return a.size > b.size
+		}
+		return a.kind < b.kind
+	})
+}
 
-	var faviconURL string
-	var found bool
-	var mu sync.Mutex
-	wg := &sync.WaitGroup{}
+func (fr *FaviconResolver) pageCandidates(ctx context.Context, base *url.URL) ([]iconCandidate, error) {
+	// Redirects change the base relative icon URLs are resolved against.
+	doc, base, err := fetchPage(ctx, fr.Fetcher, base.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []iconCandidate
+	var manifests []string
+	add := func(ref string, kind, size int) {
+		if resolved := resolveURL(base, ref); resolved != "" {
+			candidates = append(candidates, iconCandidate{url: resolved, kind: kind, size: size})
+		}
+	}
 
-	var traverse func(*html.Node)
-	traverse = func(n *html.Node) {
-		defer wg.Done()
-		if n.Type == html.ElementNode && n.Data == "link" {
-			var rel, href string
-			for _, attr := range n.Attr {
-				if attr.Key == "rel" {
-					rel = strings.ToLower(attr.Val)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			attrs := nodeAttrs(n)
+			switch n.Data {
+			case "base":
+				if href, err := base.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+					base = href
 				}
-				if attr.Key == "href" {
-					href = attr.Val
+			case "link":
+				href := attrs["href"]
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					switch rel {
+					case "icon":
+						add(href, kindIcon, parseIconSizes(attrs["sizes"]))
+					case "apple-touch-icon", "apple-touch-icon-precomposed":
+						size := parseIconSizes(attrs["sizes"])
+						if size == 0 {
+							// The size Safari uses when none is given.
+							size = 180
+						}
+						add(href, kindAppleTouchIcon, size)
+					case "mask-icon":
+						add(href, kindMaskIcon, 0)
+					case "manifest":
+						if resolved := resolveURL(base, href); resolved != "" {
+							manifests = append(manifests, resolved)
+						}
+					}
 				}
-			}
-			if (rel == "icon" || rel == "shortcut icon") && href != "" {
-				mu.Lock()
-				if !found {
-					faviconURL = href
-					found = true
+			case "meta":
+				if strings.EqualFold(attrs["property"], "og:image") || strings.EqualFold(attrs["name"], "og:image") {
+					add(attrs["content"], kindOpenGraph, 0)
 				}
-				mu.Unlock()
-				return
 			}
 		}
-
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			if found {
-				return
-			}
-			wg.Add(1)
-			go traverse(c)
+			walk(c)
 		}
 	}
+	walk(doc)
 
-	wg.Add(1)
-	go traverse(doc)
-	wg.Wait()
+	for _, manifestURL := range manifests[:min(len(manifests), maxManifests)] {
+		candidates = append(candidates, fr.manifestCandidates(ctx, manifestURL)...)
+	}
+	return candidates, nil
+}
+
+type webAppManifest struct {
+	Icons []struct {
+		Src     string `json:"src"`
+		Sizes   string `json:"sizes"`
+		Purpose string `json:"purpose"`
+	} `json:"icons"`
+}
+
+// manifestCandidates reads the icons of a Web App Manifest. Icons meant
+// only as monochrome masks are skipped.
+func (fr *FaviconResolver) manifestCandidates(ctx context.Context, manifestURL string) []iconCandidate {
+	resp, err := fr.Fetcher.Get(ctx, manifestURL)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
 
-	if faviconURL == "" {
-		return "", fmt.Errorf("favicon not found")
+	var manifest webAppManifest
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&manifest); err != nil {
+		return nil
 	}
 
-	faviconURLParsed, err := url.Parse(faviconURL)
+	base := resp.Request.URL
+	var candidates []iconCandidate
+	for _, icon := range manifest.Icons {
+		if purpose := strings.Fields(icon.Purpose); len(purpose) == 1 && purpose[0] == "monochrome" {
+			continue
+		}
+		if resolved := resolveURL(base, icon.Src); resolved != "" {
+			candidates = append(candidates, iconCandidate{url: resolved, kind: kindManifestIcon, size: parseIconSizes(icon.Sizes)})
+		}
+	}
+	return candidates
+}
+
+// download fetches iconURL and returns it when it is an image no larger
+// than MaxIconSize that Theca can decode. Only image types are downloaded,
+// and application/octet-stream, which servers commonly send icons as; an
+// HTML error page served with 200 is not worth a decode attempt.
+func (fr *FaviconResolver) download(ctx context.Context, iconURL string) *Favicon {
+	resp, err := fr.Fetcher.Get(ctx, iconURL)
 	if err != nil {
-		return "", fmt.Errorf("invalid favicon URL: %w", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.ContentLength > MaxIconSize {
+		return nil
 	}
-	if !faviconURLParsed.IsAbs() {
-		faviconURL = baseURL.ResolveReference(faviconURLParsed).String()
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !iconContentType(contentType) {
+		return nil
 	}
 
-	return faviconURL, nil
-}
\ No newline at end of file
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxIconSize+1))
+	if err != nil || len(data) == 0 || len(data) > MaxIconSize {
+		return nil
+	}
+	icon, err := imaging.Normalize(data)
+	if err != nil {
+		return nil
+	}
+	return &Favicon{URL: iconURL, Icon: icon}
+}
+
+func iconContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") || contentType == "application/octet-stream"
+}
+
+// parseIconSizes returns the largest edge listed in a sizes attribute such
+// as "16x16 32x32". "any" marks a scalable icon and wins over any bitmap.
+func parseIconSizes(sizes string) int {
+	best := 0
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		if size == "any" {
+			return 1 << 16
+		}
+		width, height, ok := strings.Cut(size, "x")
+		if !ok {
+			continue
+		}
+		w, errW := strconv.Atoi(width)
+		h, errH := strconv.Atoi(height)
+		if errW != nil || errH != nil {
+			continue
+		}
+		best = max(best, w, h)
+	}
+	return best
+}
@@ -0,0 +1,73 @@
+package parsers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIconContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"image/png", true},
+		{"image/x-icon", true},
+		{"image/vnd.microsoft.icon", true},
+		{"image/svg+xml", true},
+		{"application/octet-stream", true},
+		{"", false},
+		{"text/html", false},
+		{"text/plain", false},
+		{"text/xml", false},
+		{"application/xml", false},
+		{"application/json", false},
+	}
+	for _, tt := range tests {
+		if got := iconContentType(tt.contentType); got != tt.want {
+			t.Errorf("iconContentType(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestParseIconSizes(t *testing.T) {
+	tests := []struct {
+		sizes string
+		want  int
+	}{
+		{"", 0},
+		{"16x16", 16},
+		{"16x16 32x32", 32},
+		{"48X24", 48},
+		{"any", 1 << 16},
+		{"16x16 any", 1 << 16},
+		{"big 16x", 0},
+	}
+	for _, tt := range tests {
+		if got := parseIconSizes(tt.sizes); got != tt.want {
+			t.Errorf("parseIconSizes(%q) = %d, want %d", tt.sizes, got, tt.want)
+		}
+	}
+}
+
+func TestSortCandidates(t *testing.T) {
+	candidates := []iconCandidate{
+		{url: "og", kind: kindOpenGraph},
+		{url: "mask", kind: kindMaskIcon},
+		{url: "ico", kind: kindFaviconICO},
+		{url: "icon-16", kind: kindIcon, size: 16},
+		{url: "manifest-192", kind: kindManifestIcon, size: 192},
+		{url: "apple-180", kind: kindAppleTouchIcon, size: 180},
+		{url: "icon-180", kind: kindIcon, size: 180},
+		{url: "icon-unsized", kind: kindIcon},
+	}
+	sortCandidates(candidates)
+
+	var got []string
+	for _, candidate := range candidates {
+		got = append(got, candidate.url)
+	}
+	want := []string{"manifest-192", "icon-180", "apple-180", "icon-16", "icon-unsized", "ico", "mask", "og"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,216 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedURL is wrapped by errors of requests the Fetcher refuses to
+// make: other schemes than http and https, unusual ports, and addresses
+// that are not on the public internet.
+var ErrBlockedURL = errors.New("URL not allowed")
+
+// ErrBodyTooLarge is returned when reading more than Fetcher.MaxBodySize
+// bytes of a response.
+var ErrBodyTooLarge = errors.New("response body too large")
+
+const (
+	// UserAgent identifies Theca to the sites it fetches.
+	UserAgent = "Mozilla/5.0 (compatible; ThecaBot/1.0; +https://theca.oxytocingroup.com)"
+
+	maxRedirects = 5
+	// maxBodySize caps every response; callers reading less can set
+	// tighter limits of their own.
+	maxBodySize = 5 << 20
+)
+
+// allowedPorts are the ports web pages are served on. Other ports are more
+// likely to reach mail, database or admin services than a site.
+var allowedPorts = []int{80, 443, 8080, 8443}
+
+// blockedPrefixes are special-purpose ranges that net/netip has no
+// predicate for. Loopback, private, link-local, multicast and unspecified
+// addresses are checked with the netip methods.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, embeds IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local NAT64
+	netip.MustParsePrefix("100::/64"),        // discard
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments, Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4, embeds IPv4
+	netip.MustParsePrefix("fec0::/10"),       // deprecated site-local
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // IPv4-translated
+}
+
+// Fetcher makes GET requests to URLs supplied by users. Since such URLs
+// may point at the server itself, its cloud metadata endpoint or other
+// internal services, it only connects to public addresses. The check is
+// made on the address actually dialed, after DNS resolution and for every
+// redirect, so neither a hostname resolving to a private address nor a
+// redirect to one gets through.
+type Fetcher struct {
+	Client      *http.Client
+	UserAgent   string
+	MaxBodySize int64
+}
+
+func NewFetcher() *Fetcher {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkDialAddress(address)
+		},
+	}
+	transport := &http.Transport{
+		// A proxy would dial on our behalf, bypassing the address check.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   2,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &Fetcher{
+		Client: &http.Client{
+			Transport:     transport,
+			Timeout:       15 * time.Second,
+			CheckRedirect: checkRedirect,
+		},
+		UserAgent:   UserAgent,
+		MaxBodySize: maxBodySize,
+	}
+}
+
+// DefaultFetcher is used for every request Theca makes to user-supplied
+// URLs.
+var DefaultFetcher = NewFetcher()
+
+// Get requests target. Reading more than MaxBodySize bytes of the
+// response body fails with ErrBodyTooLarge.
+func (f *Fetcher) Get(ctx context.Context, target string) (*http.Response, error) {
+	return f.Fetch(ctx, http.MethodGet, target)
+}
+
+// Fetch makes a request of the given method, without a body, to target.
+func (f *Fetcher) Fetch(ctx context.Context, method, target string) (*http.Response, error) {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkURL(parsed); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, parsed.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: f.MaxBodySize}
+	return resp, nil
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return checkURL(req.URL)
+}
+
+// checkURL rejects URLs the Fetcher will not request, before any
+// connection is made. Addresses are checked when dialing.
+func checkURL(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", ErrBlockedURL, target.Scheme)
+	}
+	if target.Hostname() == "" {
+		return fmt.Errorf("%w: no host", ErrBlockedURL)
+	}
+	if port := target.Port(); port != "" {
+		number, err := strconv.Atoi(port)
+		if err != nil || !slices.Contains(allowedPorts, number) {
+			return fmt.Errorf("%w: port %s", ErrBlockedURL, port)
+		}
+	}
+	return nil
+}
+
+func checkDialAddress(address string) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBlockedURL, err)
+	}
+	if !slices.Contains(allowedPorts, int(addrPort.Port())) {
+		return fmt.Errorf("%w: port %d", ErrBlockedURL, addrPort.Port())
+	}
+	if !publicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: address %s is not public", ErrBlockedURL, addrPort.Addr())
+	}
+	return nil
+}
+
+// publicAddr reports whether addr is a unicast address on the public
+// internet.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// limitedBody fails once more than remaining bytes were read, unlike
+// io.LimitReader which ends silently, so a cut off body is not taken for
+// a whole one.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	if lb.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	// Reading one byte past the limit tells a body of exactly the limit
+	// from a larger one.
+	if int64(len(p)) > lb.remaining+1 {
+		p = p[:lb.remaining+1]
+	}
+	n, err := lb.ReadCloser.Read(p)
+	lb.remaining -= int64(n)
+	if lb.remaining < 0 {
+		return n - 1, ErrBodyTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,187 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/", true},
+		{"http://example.com/page", true},
+		{"http://example.com:8080/", true},
+		{"https://example.com:8443/", true},
+		{"https://93.184.216.34/", true},
+		{"ftp://example.com/", false},
+		{"file:///etc/passwd", false},
+		{"gopher://example.com:70/", false},
+		{"javascript:alert(1)", false},
+		{"https:///path", false},
+		{"http://example.com:22/", false},
+		{"http://example.com:6379/", false},
+		{"http://example.com:0/", false},
+	}
+	for _, tt := range tests {
+		target, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = checkURL(target)
+		if tt.allowed && err != nil {
+			t.Errorf("checkURL(%q) = %v, want allowed", tt.url, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrBlockedURL) {
+			t.Errorf("checkURL(%q) = %v, want ErrBlockedURL", tt.url, err)
+		}
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"127.8.9.10", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"172.31.255.255", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"255.255.255.255", false},
+		{"192.0.2.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"::ffff:93.184.216.34", true},
+		{"64:ff9b::a00:1", false},
+		{"2002:7f00:1::", false},
+		{"2001:db8::1", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("publicAddr(%s) = %v, want %v", tt.addr, got, tt.public)
+		}
+	}
+}
+
+func TestCheckDialAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:80", true},
+		{"93.184.216.34:25", false},
+		{"127.0.0.1:80", false},
+		{"[::ffff:127.0.0.1]:443", false},
+		{"localhost:80", false},
+	}
+	for _, tt := range tests {
+		err := checkDialAddress(tt.address)
+		if tt.allowed && err != nil {
+			t.Errorf("checkDialAddress(%q) = %v, want allowed", tt.address, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrBlockedURL) {
+			t.Errorf("checkDialAddress(%q) = %v, want ErrBlockedURL", tt.address, err)
+		}
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	request := func(target string) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, target, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	via := []*http.Request{request("https://example.com/")}
+
+	if err := checkRedirect(request("https://example.org/"), via); err != nil {
+		t.Errorf("redirect to a public URL refused: %v", err)
+	}
+	if err := checkRedirect(request("http://example.org:6379/"), via); !errors.Is(err, ErrBlockedURL) {
+		t.Errorf("redirect to a blocked port = %v, want ErrBlockedURL", err)
+	}
+	for len(via) < maxRedirects {
+		via = append(via, via[0])
+	}
+	if err := checkRedirect(request("https://example.org/"), via); err == nil {
+		t.Errorf("redirect %d followed", maxRedirects+1)
+	}
+}
+
+func TestFetchRefusesLoopback(t *testing.T) {
+	// The name resolves to a loopback address, which is refused when
+	// dialing, before anything is sent.
+	_, err := NewFetcher().Get(context.Background(), "http://localhost:8080/")
+	if !errors.Is(err, ErrBlockedURL) {
+		t.Fatalf("Get = %v, want ErrBlockedURL", err)
+	}
+}
+
+func TestLimitedBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		limit   int64
+		wantErr error
+	}{
+		{name: "shorter than the limit", body: "hello", limit: 10},
+		{name: "exactly the limit", body: "hello", limit: 5},
+		{name: "over the limit", body: "hello!", limit: 5, wantErr: ErrBodyTooLarge},
+		{name: "empty", body: "", limit: 0},
+		{name: "zero limit", body: "x", limit: 0, wantErr: ErrBodyTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &limitedBody{ReadCloser: io.NopCloser(strings.NewReader(tt.body)), remaining: tt.limit}
+			got, err := io.ReadAll(body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if int64(len(got)) > tt.limit {
+				t.Fatalf("read %d bytes past a limit of %d", len(got), tt.limit)
+			}
+			if err == nil && string(got) != tt.body {
+				t.Fatalf("read %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestLimitedBodySmallReads(t *testing.T) {
+	body := &limitedBody{ReadCloser: io.NopCloser(strings.NewReader("abcdef")), remaining: 5}
+	var got []byte
+	buf := make([]byte, 2)
+	var err error
+	for err == nil {
+		var n int
+		n, err = body.Read(buf)
+		got = append(got, buf[:n]...)
+	}
+	if !errors.Is(err, ErrBodyTooLarge) || string(got) != "abcde" {
+		t.Fatalf("read %q with %v, want \"abcde\" and ErrBodyTooLarge", got, err)
+	}
+}
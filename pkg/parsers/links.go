@@ -0,0 +1,52 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// LinkCheck is the outcome of requesting a bookmarked URL.
+type LinkCheck struct {
+	// StatusCode is the status of the last response, after redirects.
+	StatusCode int
+	// FinalURL is where redirects led, empty when there were none.
+	FinalURL string
+	// PermanentRedirect is set when every redirect on the way was
+	// permanent (301 or 308), meaning the link itself should change.
+	PermanentRedirect bool
+}
+
+// CheckLink requests linkURL with HEAD, or with GET when the HEAD request
+// fails, since some servers refuse or mishandle HEAD. Any response is a
+// successful check; errors are those of the request.
+func CheckLink(ctx context.Context, linkURL string) (LinkCheck, error) {
+	check, err := checkLink(ctx, DefaultFetcher, http.MethodHead, linkURL)
+	if errors.Is(err, ErrBlockedURL) || (err == nil && check.StatusCode < http.StatusBadRequest) {
+		return check, err
+	}
+	return checkLink(ctx, DefaultFetcher, http.MethodGet, linkURL)
+}
+
+func checkLink(ctx context.Context, fetcher *Fetcher, method, linkURL string) (LinkCheck, error) {
+	resp, err := fetcher.Fetch(ctx, method, linkURL)
+	if err != nil {
+		return LinkCheck{}, err
+	}
+	resp.Body.Close()
+
+	check := LinkCheck{StatusCode: resp.StatusCode}
+	// Every request made for a redirect keeps the response that caused it.
+	permanent, redirected := true, false
+	for req := resp.Request; req.Response != nil; req = req.Response.Request {
+		redirected = true
+		if code := req.Response.StatusCode; code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			permanent = false
+		}
+	}
+	if redirected {
+		check.FinalURL = resp.Request.URL.String()
+		check.PermanentRedirect = permanent
+	}
+	return check, nil
+}
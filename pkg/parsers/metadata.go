@@ -0,0 +1,154 @@
+package parsers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// PageMetadata describes a web page as it presents itself to link
+// previews. Fields the page does not provide are empty.
+type PageMetadata struct {
+	// URL is where the page was found, after redirects.
+	URL          string `json:"url"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	SiteName     string `json:"site_name"`
+	CanonicalURL string `json:"canonical_url"`
+	ImageURL     string `json:"image_url"`
+}
+
+// Meta tags each field is read from, best first. OpenGraph and Twitter
+// cards are written for previews, so they win over the plain tags.
+var (
+	titleMeta       = []string{"og:title", "twitter:title"}
+	descriptionMeta = []string{"og:description", "twitter:description", "description"}
+	siteNameMeta    = []string{"og:site_name", "application-name"}
+	imageMeta       = []string{"og:image", "og:image:url", "og:image:secure_url", "twitter:image", "twitter:image:src"}
+)
+
+// FetchMetadata downloads the page at pageURL and extracts its metadata.
+func FetchMetadata(ctx context.Context, pageURL string) (PageMetadata, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return PageMetadata{}, fmt.Errorf("%w: invalid page URL %q", ErrBlockedURL, pageURL)
+	}
+	doc, base, err := fetchPage(ctx, DefaultFetcher, base.String())
+	if err != nil {
+		return PageMetadata{}, err
+	}
+	return extractMetadata(doc, base), nil
+}
+
+// extractMetadata reads the metadata of a parsed page found at base.
+// Relative URLs are resolved against the page's <base> when it has one.
+func extractMetadata(doc *html.Node, base *url.URL) PageMetadata {
+	pageURL := base.String()
+
+	// The first non-empty tag of each name counts, as in browsers and link
+	// preview crawlers.
+	meta := make(map[string]string)
+	var title, canonical string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		// Elements of inline SVGs are skipped: their <title> names the
+		// drawing, not the page.
+		if n.Type == html.ElementNode && n.Namespace == "" {
+			attrs := nodeAttrs(n)
+			switch n.DataAtom {
+			case atom.Base:
+				if href, err := base.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+					base = href
+				}
+			case atom.Title:
+				if title == "" {
+					title = textContent(n)
+				}
+			case atom.Link:
+				if canonical == "" && hasRel(attrs["rel"], "canonical") {
+					canonical = attrs["href"]
+				}
+			case atom.Meta:
+				content := cleanText(attrs["content"])
+				if content == "" {
+					break
+				}
+				// OpenGraph uses property, the others use name.
+				for _, key := range []string{attrs["property"], attrs["name"]} {
+					key = strings.ToLower(strings.TrimSpace(key))
+					if _, seen := meta[key]; key != "" && !seen {
+						meta[key] = content
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	metadata := PageMetadata{
+		URL:         pageURL,
+		Title:       firstMeta(meta, titleMeta),
+		Description: firstMeta(meta, descriptionMeta),
+		SiteName:    firstMeta(meta, siteNameMeta),
+	}
+	if metadata.Title == "" {
+		metadata.Title = title
+	}
+	metadata.CanonicalURL = resolveURL(base, canonical)
+	if metadata.CanonicalURL == "" {
+		metadata.CanonicalURL = resolveURL(base, meta["og:url"])
+	}
+	for _, key := range imageMeta {
+		if metadata.ImageURL = resolveURL(base, meta[key]); metadata.ImageURL != "" {
+			break
+		}
+	}
+	return metadata
+}
+
+func firstMeta(meta map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value := meta[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func hasRel(rel, value string) bool {
+	for _, field := range strings.Fields(strings.ToLower(rel)) {
+		if field == value {
+			return true
+		}
+	}
+	return false
+}
+
+func textContent(n *html.Node) string {
+	var text strings.Builder
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return cleanText(text.String())
+}
+
+// cleanText collapses runs of whitespace, including line breaks, into
+// single spaces.
+func cleanText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,161 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// NetscapeBookmark is a link read from a Netscape bookmark file, the format
+// browsers use for bookmark export.
+type NetscapeBookmark struct {
+	Title      string
+	URL        string
+	IconURL    string
+	AddDate    time.Time
+	FolderPath []string
+	Tags       []string
+	ShowText   bool
+}
+
+// ParseNetscapeBookmarks reads a Netscape bookmark file. Folders are <H3>
+// headings followed by a <DL> list with their contents; each entry keeps
+// the path of folder names it was found in.
+func ParseNetscapeBookmarks(r io.Reader) ([]NetscapeBookmark, error) {
+	tokenizer := html.NewTokenizer(r)
+
+	var (
+		bookmarks     []NetscapeBookmark
+		path          []string
+		listIsFolder  []bool
+		pendingFolder *string
+		current       *NetscapeBookmark
+		inHeading     bool
+		text          strings.Builder
+		sawList       bool
+	)
+
+	finishLink := func() {
+		if current == nil {
+			return
+		}
+		current.Title = strings.TrimSpace(text.String())
+		if current.URL != "" {
+			bookmarks = append(bookmarks, *current)
+		}
+		current = nil
+	}
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				finishLink()
+				if !sawList {
+					return nil, fmt.Errorf("not a bookmark file: no bookmark list found")
+				}
+				return bookmarks, nil
+			}
+			return nil, fmt.Errorf("failed to read bookmark file: %w", tokenizer.Err())
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch string(name) {
+			case "h3":
+				finishLink()
+				inHeading = true
+				text.Reset()
+			case "dl":
+				finishLink()
+				sawList = true
+				listIsFolder = append(listIsFolder, pendingFolder != nil)
+				if pendingFolder != nil {
+					path = append(path, *pendingFolder)
+					pendingFolder = nil
+				}
+			case "a":
+				finishLink()
+				attrs := readAttrs(tokenizer, hasAttr)
+				current = &NetscapeBookmark{
+					URL:        strings.TrimSpace(attrs["href"]),
+					IconURL:    strings.TrimSpace(attrs["icon_uri"]),
+					AddDate:    parseUnixDate(attrs["add_date"]),
+					FolderPath: append([]string(nil), path...),
+					Tags:       splitTags(attrs["tags"]),
+					ShowText:   strings.EqualFold(attrs["show_text"], "true"),
+				}
+				text.Reset()
+			case "dt":
+				finishLink()
+			}
+
+		case html.TextToken:
+			if current != nil || inHeading {
+				text.Write(tokenizer.Text())
+			}
+
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "h3":
+				folder := strings.TrimSpace(text.String())
+				pendingFolder = &folder
+				inHeading = false
+			case "a":
+				finishLink()
+			case "dl":
+				finishLink()
+				if n := len(listIsFolder); n > 0 {
+					if listIsFolder[n-1] && len(path) > 0 {
+						path = path[:len(path)-1]
+					}
+					listIsFolder = listIsFolder[:n-1]
+				}
+			}
+		}
+	}
+}
+
+func readAttrs(tokenizer *html.Tokenizer, hasAttr bool) map[string]string {
+	attrs := make(map[string]string)
+	for hasAttr {
+		var key, val []byte
+		key, val, hasAttr = tokenizer.TagAttr()
+		attrs[strings.ToLower(string(key))] = string(val)
+	}
+	return attrs
+}
+
+// parseUnixDate reads ADD_DATE values. Browsers write seconds, but some
+// tools export milliseconds or microseconds.
+func parseUnixDate(value string) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	switch {
+	case n > 1e15:
+		return time.UnixMicro(n)
+	case n > 1e12:
+		return time.UnixMilli(n)
+	default:
+		return time.Unix(n, 0)
+	}
+}
+
+// splitTags reads the comma separated TAGS attribute written by Theca,
+// Pinboard and Firefox.
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,88 @@
+package parsers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const netscapeFile = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><A HREF="https://go.dev/" ADD_DATE="1700000000" ICON_URI="https://go.dev/favicon.ico" TAGS="go, lang">The Go  Programming Language</A>
+    <DT><H3 ADD_DATE="1700000000">Work</H3>
+    <DL><p>
+        <DT><A HREF=" https://example.com/a " ADD_DATE="1700000000000" SHOW_TEXT="true">A</A>
+        <DT><H3>Docs</H3>
+        <DL><p>
+            <DT><A HREF="https://example.com/b" ADD_DATE="1700000000000000">B</A>
+        </DL><p>
+        <DT><A HREF="https://example.com/c">C</A>
+        <DD>A description that is not part of the title
+    </DL><p>
+    <DT><A HREF="">No URL</A>
+    <DT><A HREF="https://example.com/d" ADD_DATE="soon">D</A>
+</DL><p>
+`
+
+func TestParseNetscapeBookmarks(t *testing.T) {
+	got, err := ParseNetscapeBookmarks(strings.NewReader(netscapeFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	added := time.Unix(1700000000, 0)
+	want := []NetscapeBookmark{
+		{Title: "The Go  Programming Language", URL: "https://go.dev/", IconURL: "https://go.dev/favicon.ico", AddDate: added, FolderPath: []string{}, Tags: []string{"go", "lang"}},
+		{Title: "A", URL: "https://example.com/a", AddDate: added, FolderPath: []string{"Work"}, ShowText: true},
+		{Title: "B", URL: "https://example.com/b", AddDate: added, FolderPath: []string{"Work", "Docs"}},
+		{Title: "C", URL: "https://example.com/c", FolderPath: []string{"Work"}},
+		{Title: "D", URL: "https://example.com/d", FolderPath: []string{}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d bookmarks, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].AddDate.Equal(want[i].AddDate) {
+			t.Errorf("bookmark %d added %v, want %v", i, got[i].AddDate, want[i].AddDate)
+		}
+		got[i].AddDate, want[i].AddDate = time.Time{}, time.Time{}
+		if len(got[i].FolderPath) == 0 {
+			got[i].FolderPath = []string{}
+		}
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("bookmark %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseNetscapeBookmarksRejectsOtherHTML(t *testing.T) {
+	if _, err := ParseNetscapeBookmarks(strings.NewReader(`<html><body><a href="https://example.com">x</a></body></html>`)); err == nil {
+		t.Fatal("a page without a bookmark list was accepted")
+	}
+}
+
+func TestParseUnixDate(t *testing.T) {
+	want := time.Unix(1700000000, 0)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"1700000000", want},
+		{"1700000000000", want},
+		{"1700000000000000", want},
+		{" 1700000000 ", want},
+		{"0", time.Time{}},
+		{"-5", time.Time{}},
+		{"", time.Time{}},
+		{"yesterday", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseUnixDate(tt.value); !got.Equal(tt.want) {
+			t.Errorf("parseUnixDate(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// ErrPageUnavailable is wrapped by errors of pages that could not be
+// loaded for reasons that may go away, such as timeouts or server errors.
+var ErrPageUnavailable = errors.New("page unavailable")
+
+// maxPageSize is how much of a page is parsed. Everything Theca reads from
+// pages is in the head, well within it.
+const maxPageSize = 2 << 20
+
+// fetchPage downloads and parses the HTML page at pageURL. It returns the
+// URL of the page after redirects, which relative links are resolved
+// against.
+func fetchPage(ctx context.Context, fetcher *Fetcher, pageURL string) (*html.Node, *url.URL, error) {
+	resp, err := fetcher.Get(ctx, pageURL)
+	if errors.Is(err, ErrBlockedURL) {
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to fetch URL: %w", ErrPageUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return nil, nil, fmt.Errorf("%w: received response code %d", ErrPageUnavailable, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+	}
+
+	// Pages not in UTF-8 are converted from the charset of the header or
+	// of the page's <meta charset>.
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxPageSize), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode page: %w", err)
+	}
+	doc, err := html.Parse(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	return doc, resp.Request.URL, nil
+}
+
+// resolveURL makes ref absolute. Only http and https URLs are usable,
+// data: URIs and the like are dropped.
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	resolved, err := base.Parse(ref)
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+		return ""
+	}
+	resolved.Fragment = ""
+	return resolved.String()
+}
+
+func nodeAttrs(n *html.Node) map[string]string {
+	attrs := make(map[string]string, len(n.Attr))
+	for _, attr := range n.Attr {
+		attrs[strings.ToLower(attr.Key)] = attr.Val
+	}
+	return attrs
+}
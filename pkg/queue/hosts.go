@@ -0,0 +1,54 @@
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+// hostLimiter spaces out the jobs of this process that go to the same
+// host, so importing many bookmarks of one site does not flood it.
+type hostLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func newHostLimiter(interval time.Duration) *hostLimiter {
+	return &hostLimiter{interval: interval, next: make(map[string]time.Time)}
+}
+
+// reserve takes the next slot of host when it is free and returns 0, or
+// returns how long until it is. Jobs without a host are never limited.
+func (hl *hostLimiter) reserve(host string) time.Duration {
+	if host == "" {
+		return 0
+	}
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	now := time.Now()
+	if next, ok := hl.next[host]; ok && now.Before(next) {
+		return next.Sub(now)
+	}
+	hl.next[host] = now.Add(hl.interval)
+	return 0
+}
+
+// busy returns the hosts whose next slot is not free yet, and forgets
+// those whose slot is.
+func (hl *hostLimiter) busy() []string {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	now := time.Now()
+	var hosts []string
+	for host, next := range hl.next {
+		if now.Before(next) {
+			hosts = append(hosts, host)
+		} else {
+			delete(hl.next, host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,300 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+	"github.com/OxytocinGroup/theca-backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Handler runs a job. Returning an error retries the job later, unless it
+// was the last attempt or the error is Permanent. Handlers must stop when
+// ctx is cancelled; the job is then run again from the start.
+type Handler func(ctx context.Context, job domain.Job) error
+
+type permanentError struct {
+	err error
+}
+
+func (pe *permanentError) Error() string { return pe.err.Error() }
+func (pe *permanentError) Unwrap() error { return pe.err }
+
+// Permanent marks err as one retrying will not fix, such as a malformed
+// payload, so the job fails at once.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type Options struct {
+	// Workers is how many jobs run at the same time.
+	Workers int
+	// MaxAttempts is how many times a job runs before it is dead.
+	MaxAttempts int
+	// HostInterval is the least time between two jobs for the same host.
+	HostInterval time.Duration
+	// Timeout bounds a single attempt.
+	Timeout time.Duration
+	// PollInterval is how often idle workers look for due jobs.
+	PollInterval time.Duration
+	// MinBackoff is the delay before the first retry; it doubles with
+	// every further attempt up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+var DefaultOptions = Options{
+	Workers:      4,
+	MaxAttempts:  5,
+	HostInterval: time.Second,
+	Timeout:      2 * time.Minute,
+	PollInterval: time.Second,
+	MinBackoff:   30 * time.Second,
+	MaxBackoff:   6 * time.Hour,
+}
+
+// staleAfter is how long a job may stay running before it is taken for
+// abandoned by a crashed worker and queued again. It must exceed Timeout.
+const staleAfter = 15 * time.Minute
+
+// Queue runs jobs stored in Postgres with a fixed number of workers. Any
+// number of processes may share the table.
+type Queue struct {
+	repo     repository.JobRepository
+	log      logger.Logger
+	opts     Options
+	handlers map[string]Handler
+	kinds    []string
+	hosts    *hostLimiter
+
+	// wake lets a worker pick up a new job without waiting for its poll.
+	wake chan struct{}
+	stop chan struct{}
+	// ctx is the parent of jobs' contexts. It is cancelled when the
+	// shutdown deadline passes with jobs still running.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	workers  sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// New creates a queue; options left zero take their DefaultOptions value.
+func New(repo repository.JobRepository, log logger.Logger, opts Options) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultOptions.Workers
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultOptions.MaxAttempts
+	}
+	if opts.HostInterval <= 0 {
+		opts.HostInterval = DefaultOptions.HostInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultOptions.Timeout
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultOptions.PollInterval
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = DefaultOptions.MinBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultOptions.MaxBackoff
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		repo:     repo,
+		log:      log,
+		opts:     opts,
+		handlers: make(map[string]Handler),
+		hosts:    newHostLimiter(opts.HostInterval),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Handle sets the handler of a job kind. Jobs of kinds without a handler
+// are left in the table, for other processes to run. Handle must be
+// called before Start.
+func (q *Queue) Handle(kind string, handler Handler) {
+	if _, ok := q.handlers[kind]; !ok {
+		q.kinds = append(q.kinds, kind)
+	}
+	q.handlers[kind] = handler
+}
+
+// Enqueue stores a job that runs as soon as a worker is free. host is the
+// site the job sends requests to, if any.
+func (q *Queue) Enqueue(kind, host string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s payload: %w", kind, err)
+	}
+	job := domain.Job{
+		Kind:        kind,
+		Payload:     string(data),
+		Host:        host,
+		Status:      domain.JobQueued,
+		RunAt:       time.Now(),
+		MaxAttempts: q.opts.MaxAttempts,
+	}
+	if err := q.repo.EnqueueJob(&job); err != nil {
+		return fmt.Errorf("enqueue %s job: %w", kind, err)
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start launches the workers.
+func (q *Queue) Start() {
+	if requeued, err := q.repo.RequeueStaleJobs(time.Now().Add(-staleAfter)); err != nil {
+		q.log.Error(context.Background(), "Job queue: failed to requeue stale jobs", map[string]any{"error": err})
+	} else if requeued > 0 {
+		q.log.Info(context.Background(), "Job queue: requeued stale jobs", map[string]any{"jobs": requeued})
+	}
+
+	for range q.opts.Workers {
+		q.workers.Add(1)
+		go q.work()
+	}
+	q.log.Info(context.Background(), "Job queue: started", map[string]any{"workers": q.opts.Workers, "kinds": q.kinds})
+}
+
+// Shutdown stops taking jobs and waits for the running ones to finish.
+// Jobs still running when ctx is done are cancelled and queued again.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.stopOnce.Do(func() { close(q.stop) })
+
+	drained := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		q.cancel()
+		q.log.Info(context.Background(), "Job queue: drained", nil)
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-drained
+		q.log.Info(context.Background(), "Job queue: interrupted running jobs", nil)
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.workers.Done()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		job, err := q.repo.ClaimJob(q.kinds, q.hosts.busy())
+		if err == nil {
+			q.run(job)
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			q.log.Error(context.Background(), "Job queue: failed to claim job", map[string]any{"error": err})
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-time.After(q.opts.PollInterval):
+		}
+	}
+}
+
+func (q *Queue) run(job domain.Job) {
+	// Another worker may have taken the host since the job was claimed.
+	if wait := q.hosts.reserve(job.Host); wait > 0 {
+		q.release(job, time.Now().Add(wait))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(q.ctx, q.opts.Timeout)
+	err := q.call(ctx, job)
+	cancel()
+
+	switch {
+	case err == nil:
+		if err := q.repo.CompleteJob(job.ID); err != nil {
+			q.log.Error(context.Background(), "Job queue: failed to complete job", map[string]any{"error": err, "job_id": job.ID})
+		}
+	case q.ctx.Err() != nil:
+		// Shutting down: the attempt was cut short, not failed.
+		q.release(job, time.Now())
+	case job.LastAttempt() || errors.As(err, new(*permanentError)):
+		q.log.Error(context.Background(), "Job queue: job failed for good", map[string]any{
+			"error":    err,
+			"job_id":   job.ID,
+			"kind":     job.Kind,
+			"attempts": job.Attempts,
+		})
+		if err := q.repo.KillJob(job.ID, err.Error()); err != nil {
+			q.log.Error(context.Background(), "Job queue: failed to mark job dead", map[string]any{"error": err, "job_id": job.ID})
+		}
+	default:
+		delay := q.backoff(job.Attempts)
+		q.log.Info(context.Background(), "Job queue: job failed, retrying", map[string]any{
+			"error":    err,
+			"job_id":   job.ID,
+			"kind":     job.Kind,
+			"attempts": job.Attempts,
+			"retry_in": delay.String(),
+		})
+		if err := q.repo.RetryJob(job.ID, time.Now().Add(delay), err.Error()); err != nil {
+			q.log.Error(context.Background(), "Job queue: failed to retry job", map[string]any{"error": err, "job_id": job.ID})
+		}
+	}
+}
+
+// call runs the job's handler; a panic fails the attempt instead of
+// killing the process.
+func (q *Queue) call(ctx context.Context, job domain.Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return q.handlers[job.Kind](ctx, job)
+}
+
+func (q *Queue) release(job domain.Job, runAt time.Time) {
+	if err := q.repo.ReleaseJob(job.ID, runAt); err != nil {
+		q.log.Error(context.Background(), "Job queue: failed to release job", map[string]any{"error": err, "job_id": job.ID})
+	}
+}
+
+// backoff is the delay before the next attempt, doubled after every
+// failure and spread by up to a fifth so failed batches do not retry in
+// lockstep.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.opts.MinBackoff
+	for i := 1; i < attempts && delay < q.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, q.opts.MaxBackoff)
+	return delay + rand.N(delay/5+1)
+}
@@ -0,0 +1,141 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/internal/repository"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(context.Context, string, map[string]any) {}
+func (nopLogger) Info(context.Context, string, map[string]any)  {}
+func (nopLogger) Warn(context.Context, string, map[string]any)  {}
+func (nopLogger) Error(context.Context, string, map[string]any) {}
+
+// recordingJobRepository remembers what became of the last job.
+type recordingJobRepository struct {
+	repository.JobRepository
+	outcome string
+	runAt   time.Time
+}
+
+func (r *recordingJobRepository) CompleteJob(uint) error {
+	r.outcome = "completed"
+	return nil
+}
+
+func (r *recordingJobRepository) RetryJob(_ uint, runAt time.Time, _ string) error {
+	r.outcome, r.runAt = "retried", runAt
+	return nil
+}
+
+func (r *recordingJobRepository) ReleaseJob(_ uint, runAt time.Time) error {
+	r.outcome, r.runAt = "released", runAt
+	return nil
+}
+
+func (r *recordingJobRepository) KillJob(uint, string) error {
+	r.outcome = "killed"
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  Handler
+		attempts int
+		want     string
+	}{
+		{name: "success", handler: func(context.Context, domain.Job) error { return nil }, attempts: 1, want: "completed"},
+		{name: "failure", handler: func(context.Context, domain.Job) error { return errors.New("timeout") }, attempts: 1, want: "retried"},
+		{name: "failure on the last attempt", handler: func(context.Context, domain.Job) error { return errors.New("timeout") }, attempts: 3, want: "killed"},
+		{name: "permanent failure", handler: func(context.Context, domain.Job) error { return Permanent(errors.New("bad payload")) }, attempts: 1, want: "killed"},
+		{name: "panic", handler: func(context.Context, domain.Job) error { panic("boom") }, attempts: 1, want: "retried"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &recordingJobRepository{}
+			q := New(repo, nopLogger{}, Options{MinBackoff: time.Minute})
+			q.Handle("test", tt.handler)
+
+			before := time.Now()
+			q.run(domain.Job{ID: 1, Kind: "test", Host: "example.com", Attempts: tt.attempts, MaxAttempts: 3})
+			if repo.outcome != tt.want {
+				t.Fatalf("job %s, want %s", repo.outcome, tt.want)
+			}
+			if repo.outcome == "retried" && repo.runAt.Before(before.Add(time.Minute)) {
+				t.Fatalf("retried at %v, before the backoff of a minute", repo.runAt)
+			}
+		})
+	}
+}
+
+func TestRunPutsOffBusyHost(t *testing.T) {
+	repo := &recordingJobRepository{}
+	q := New(repo, nopLogger{}, Options{HostInterval: time.Hour})
+	q.Handle("test", func(context.Context, domain.Job) error { return nil })
+
+	q.run(domain.Job{ID: 1, Kind: "test", Host: "example.com", Attempts: 1, MaxAttempts: 3})
+	if repo.outcome != "completed" {
+		t.Fatalf("first job %s, want completed", repo.outcome)
+	}
+	q.run(domain.Job{ID: 2, Kind: "test", Host: "example.com", Attempts: 1, MaxAttempts: 3})
+	if repo.outcome != "released" || time.Until(repo.runAt) < 59*time.Minute {
+		t.Fatalf("second job %s until %v, want released for the host interval", repo.outcome, repo.runAt)
+	}
+	q.run(domain.Job{ID: 3, Kind: "test", Host: "example.org", Attempts: 1, MaxAttempts: 3})
+	if repo.outcome != "completed" {
+		t.Fatalf("job of another host %s, want completed", repo.outcome)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	q := New(nil, nopLogger{}, Options{MinBackoff: time.Minute, MaxBackoff: time.Hour})
+	tests := []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{attempts: 1, base: time.Minute},
+		{attempts: 2, base: 2 * time.Minute},
+		{attempts: 3, base: 4 * time.Minute},
+		{attempts: 7, base: time.Hour},
+		{attempts: 50, base: time.Hour},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := q.backoff(tt.attempts); got < tt.base || got > tt.base+tt.base/5 {
+				t.Fatalf("backoff(%d) = %v, want between %v and a fifth more", tt.attempts, got, tt.base)
+			}
+		}
+	}
+}
+
+func TestHostLimiter(t *testing.T) {
+	hl := newHostLimiter(50 * time.Millisecond)
+
+	if wait := hl.reserve("example.com"); wait != 0 {
+		t.Fatalf("first reserve waits %v", wait)
+	}
+	if wait := hl.reserve("example.com"); wait <= 0 || wait > 50*time.Millisecond {
+		t.Fatalf("second reserve waits %v, want up to the interval", wait)
+	}
+	if wait := hl.reserve(""); wait != 0 {
+		t.Fatalf("jobs without a host wait %v", wait)
+	}
+	if busy := hl.busy(); len(busy) != 1 || busy[0] != "example.com" {
+		t.Fatalf("busy = %v, want [example.com]", busy)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if busy := hl.busy(); len(busy) != 0 {
+		t.Fatalf("busy after the interval = %v", busy)
+	}
+	if wait := hl.reserve("example.com"); wait != 0 {
+		t.Fatalf("reserve after the interval waits %v", wait)
+	}
+}
@@ -0,0 +1,121 @@
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+// digits are ordered the same way in byte order and in the "C" collation,
+// so keys compare correctly both in Go and in Postgres.
+const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var (
+	ErrInvalidKey   = errors.New("rank: key contains invalid characters or a trailing zero")
+	ErrInvalidRange = errors.New("rank: lower key must sort before upper key")
+)
+
+// Between returns a key that sorts strictly between lower and upper.
+// An empty lower means "before everything" and an empty upper means
+// "after everything", so Between("", "") returns a key for an empty list.
+func Between(lower, upper string) (string, error) {
+	if (lower != "" && !Valid(lower)) || (upper != "" && !Valid(upper)) {
+		return "", ErrInvalidKey
+	}
+	if upper != "" && lower >= upper {
+		return "", ErrInvalidRange
+	}
+	return midpoint(lower, upper), nil
+}
+
+// After returns a key that sorts after key. Unlike Between(key, "") it bumps
+// the first digit that can still grow, so appending to a list keeps keys short.
+func After(key string) (string, error) {
+	if key != "" && !Valid(key) {
+		return "", ErrInvalidKey
+	}
+	for i := 0; i < len(key); i++ {
+		if d := strings.IndexByte(digits, key[i]); d < len(digits)-1 {
+			return key[:i] + string(digits[d+1]), nil
+		}
+	}
+	return key + midpoint("", ""), nil
+}
+
+// Sequence returns n increasing keys, used to (re)number a whole list.
+func Sequence(n int) []string {
+	keys := make([]string, 0, n)
+	width := 1
+	for capacity := len(digits) - 1; capacity < n; capacity *= len(digits) {
+		width++
+	}
+
+	for i := 1; i <= n; i++ {
+		key := make([]byte, width)
+		for pos, rest := width-1, i; pos >= 0; pos-- {
+			key[pos] = digits[rest%len(digits)]
+			rest /= len(digits)
+		}
+		keys = append(keys, strings.TrimRight(string(key), "0"))
+	}
+	return keys
+}
+
+// midpoint assumes lower < upper (with an empty upper meaning infinity) and
+// that neither key ends with the zero digit.
+func midpoint(lower, upper string) string {
+	if upper != "" {
+		n := 0
+		for n < len(upper) && digitAt(lower, n) == upper[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(lower) {
+				rest = lower[n:]
+			}
+			return upper[:n] + midpoint(rest, upper[n:])
+		}
+	}
+
+	lowDigit := 0
+	if lower != "" {
+		lowDigit = strings.IndexByte(digits, lower[0])
+	}
+	highDigit := len(digits)
+	if upper != "" {
+		highDigit = strings.IndexByte(digits, upper[0])
+	}
+
+	if highDigit-lowDigit > 1 {
+		return string(digits[(lowDigit+highDigit+1)/2])
+	}
+	if len(upper) > 1 {
+		return upper[:1]
+	}
+
+	rest := ""
+	if len(lower) > 1 {
+		rest = lower[1:]
+	}
+	return string(digits[lowDigit]) + midpoint(rest, "")
+}
+
+func digitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return digits[0]
+}
+
+// Valid reports whether key is a non-empty key produced by this package.
+func Valid(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	return !strings.HasSuffix(key, digits[:1])
+}
@@ -0,0 +1,151 @@
+package rank
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name         string
+		lower, upper string
+		want         string
+		wantErr      error
+	}{
+		{name: "empty list", want: "V"},
+		{name: "before first", upper: "V", want: "G"},
+		{name: "after last", lower: "V", want: "l"},
+		{name: "gap of one digit", lower: "1", upper: "3", want: "2"},
+		{name: "adjacent digits", lower: "1", upper: "2", want: "1V"},
+		{name: "common prefix", lower: "A1", upper: "A2", want: "A1V"},
+		{name: "lower is prefix of upper", lower: "A", upper: "A1", want: "A0V"},
+		{name: "upper longer", lower: "1", upper: "2V", want: "2"},
+		{name: "last digit", lower: "z", want: "zV"},
+		{name: "equal keys", lower: "V", upper: "V", wantErr: ErrInvalidRange},
+		{name: "reversed keys", lower: "W", upper: "V", wantErr: ErrInvalidRange},
+		{name: "trailing zero", lower: "V0", wantErr: ErrInvalidKey},
+		{name: "invalid character", upper: "a-b", wantErr: ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Between(tt.lower, tt.upper)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Between(%q, %q) error = %v, want %v", tt.lower, tt.upper, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Between(%q, %q) = %q, want %q", tt.lower, tt.upper, got, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			if !Valid(got) || got <= tt.lower || (tt.upper != "" && got >= tt.upper) {
+				t.Fatalf("Between(%q, %q) = %q, not a key strictly between them", tt.lower, tt.upper, got)
+			}
+		})
+	}
+}
+
+func TestBetweenRepeatedly(t *testing.T) {
+	// Inserting again and again at the same place must keep finding keys.
+	lower, upper := "1", "2"
+	for i := 0; i < 200; i++ {
+		key, err := Between(lower, upper)
+		if err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+		if key <= lower || key >= upper {
+			t.Fatalf("insert %d: %q is not between %q and %q", i, key, lower, upper)
+		}
+		if i%2 == 0 {
+			upper = key
+		} else {
+			lower = key
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		want    string
+		wantErr error
+	}{
+		{name: "empty list", want: "V"},
+		{name: "single digit", key: "1", want: "2"},
+		{name: "bumps first digit", key: "1V", want: "2"},
+		{name: "skips last digit", key: "zA", want: "zB"},
+		{name: "all last digits", key: "zz", want: "zzV"},
+		{name: "trailing zero", key: "10", wantErr: ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := After(tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("After(%q) error = %v, want %v", tt.key, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("After(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+			if err == nil && got <= tt.key {
+				t.Fatalf("After(%q) = %q, does not sort after it", tt.key, got)
+			}
+		})
+	}
+}
+
+func TestSequence(t *testing.T) {
+	tests := []struct {
+		n         int
+		first     string
+		last      string
+		maxLength int
+	}{
+		{n: 0},
+		{n: 1, first: "1", last: "1", maxLength: 1},
+		{n: 61, first: "1", last: "z", maxLength: 1},
+		{n: 62, first: "01", last: "1", maxLength: 2},
+		{n: 1000, first: "01", last: "G8", maxLength: 2},
+		{n: 4000, first: "001", last: "12W", maxLength: 3},
+	}
+	for _, tt := range tests {
+		keys := Sequence(tt.n)
+		if len(keys) != tt.n {
+			t.Fatalf("Sequence(%d) returned %d keys", tt.n, len(keys))
+		}
+		if tt.n == 0 {
+			continue
+		}
+		if keys[0] != tt.first || keys[tt.n-1] != tt.last {
+			t.Errorf("Sequence(%d) runs from %q to %q, want %q to %q", tt.n, keys[0], keys[tt.n-1], tt.first, tt.last)
+		}
+		for i, key := range keys {
+			if !Valid(key) || len(key) > tt.maxLength {
+				t.Fatalf("Sequence(%d)[%d] = %q, want a valid key of at most %d digits", tt.n, i, key, tt.maxLength)
+			}
+			if i > 0 && keys[i-1] >= key {
+				t.Fatalf("Sequence(%d) is not increasing at %d: %q >= %q", tt.n, i, keys[i-1], key)
+			}
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"", false},
+		{"V", true},
+		{"0V", true},
+		{"V0", false},
+		{"0", false},
+		{"a b", false},
+		{"é", false},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.key); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
@@ -6,3 +6,28 @@ type BookmarkRequest struct {
 	URL      string `json:"url" binding:"required"`
 	ShowText bool   `json:"show_text" binding:"required"`
 }
+
+type ReorderBookmarkRequest struct {
+	ID        uint   `json:"id" binding:"required"`
+	TargetID  uint   `json:"target_id" binding:"required"`
+	Placement string `json:"placement" binding:"required,oneof=before after"`
+	// MoveToFolder also moves the bookmark into the folder of the target.
+	MoveToFolder bool `json:"move_to_folder"`
+}
+
+type RestoreBookmarkRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+type MergeDuplicatesRequest struct {
+	KeepID   uint   `json:"keep_id" binding:"required"`
+	MergeIDs []uint `json:"merge_ids" binding:"required,min=1"`
+}
+
+type ApplyRedirectsRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1"`
+}
+
+type PreviewBookmarkRequest struct {
+	URL string `json:"url" binding:"required"`
+}
@@ -0,0 +1,6 @@
+package requests
+
+type DeleteFolderRequest struct {
+	ID      uint `json:"id" binding:"required"`
+	Cascade bool `json:"cascade"`
+}
@@ -0,0 +1,15 @@
+package requests
+
+type TagRequest struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+type DeleteTagRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+type MergeTagsRequest struct {
+	SourceIDs []uint `json:"source_ids" binding:"required,min=1"`
+	TargetID  uint   `json:"target_id" binding:"required"`
+}
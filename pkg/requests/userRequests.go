@@ -1,5 +1,7 @@
 package requests
 
+import "encoding/json"
+
 type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Username string `json:"username" binding:"required,min=3"`
@@ -11,12 +13,14 @@ type EmailVerifyRequest struct {
 }
 
 type LoginRequest struct {
-	Username string `json:"username" binding:"required,min=3"`
-	Password string `json:"password" binding:"required,min=6"`
+	Username   string `json:"username" binding:"required,min=3"`
+	Password   string `json:"password" binding:"required,min=6"`
+	RememberMe bool   `json:"remember_me"`
 }
 
 type ChangePasswordRequest struct {
-	Password string `json:"password" binding:"required"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Password        string `json:"password" binding:"required,min=6"`
 }
 
 type RequestPasswordReset struct {
@@ -28,6 +32,46 @@ type ResetPassword struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// TwoFactorLoginRequest answers a login challenge with either Code, a
+// code of the authenticator app or a recovery code, or Credential, the
+// passkey's answer to the options of the challenge.
+type TwoFactorLoginRequest struct {
+	Challenge  string          `json:"challenge" binding:"required"`
+	Code       string          `json:"code"`
+	Credential json.RawMessage `json:"credential" swaggertype:"object"`
+}
+
+type TwoFactorPasskeyRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+}
+
+type BeginPasskeyRegistrationRequest struct {
+	Name string `json:"name" binding:"max=64"`
+}
+
+type BeginPasskeyLoginRequest struct {
+	RememberMe bool `json:"remember_me"`
+}
+
+// FinishPasskeyRequest completes a passkey ceremony with the
+// PublicKeyCredential returned by the browser, as JSON.
+type FinishPasskeyRequest struct {
+	Ceremony   string          `json:"ceremony" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required" swaggertype:"object"`
+}
+
+type RenamePasskeyRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
 type RequestVerificationToken struct {
 	Username string `json:"username" binding:"required,min=3"`
 }
@@ -1,15 +1,25 @@
 package pkg
 
+import (
+	"github.com/OxytocinGroup/theca-backend/internal/domain"
+	"github.com/OxytocinGroup/theca-backend/pkg/parsers"
+)
+
 type Response struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Error   string `json:"error"`
 }
 
+// LoginResponse carries a Challenge instead of setting a session when the
+// user has two-factor authentication; the login is completed with it and
+// one of Methods: "totp", "recovery_code" or "passkey".
 type LoginResponse struct {
-	Code     int    `json:"code"`
-	Message  string `json:"message"`
-	Username string `json:"username"`
+	Code      int      `json:"code"`
+	Message   string   `json:"message"`
+	Username  string   `json:"username"`
+	Challenge string   `json:"challenge,omitempty"`
+	Methods   []string `json:"methods,omitempty"`
 }
 
 type UserInfoResponse struct {
@@ -17,4 +27,86 @@ type UserInfoResponse struct {
 	Message string `json:"message"`
 	Email string `json:"email"`
 	Username string `json:"username"`
-}
\ No newline at end of file
+	Usage *domain.QuotaUsage `json:"usage,omitempty"`
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+}
+
+// TOTPEnrollmentResponse holds the secret to add to an authenticator app,
+// as an otpauth:// URI and as a QR code PNG, base64 encoded.
+type TOTPEnrollmentResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Error   string `json:"error"`
+	Secret  string `json:"secret"`
+	URI     string `json:"uri"`
+	QRCode  []byte `json:"qr_code"`
+}
+
+// PasskeyOptionsResponse starts a passkey ceremony. Options are passed to
+// navigator.credentials.create() or get() and the resulting credential is
+// sent back with Ceremony.
+type PasskeyOptionsResponse struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	Error    string `json:"error"`
+	Ceremony string `json:"ceremony,omitempty"`
+	Options  any    `json:"options,omitempty"`
+}
+
+// RecoveryCodesResponse shows recovery codes the only time they are
+// available.
+type RecoveryCodesResponse struct {
+	Code          int      `json:"code"`
+	Message       string   `json:"message"`
+	Error         string   `json:"error"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type BookmarkSearchResponse struct {
+	Code     int                           `json:"code"`
+	Message  string                        `json:"message"`
+	Error    string                        `json:"error"`
+	Results  []domain.BookmarkSearchResult `json:"results"`
+	Total    int64                         `json:"total"`
+	Page     int                           `json:"page"`
+	PageSize int                           `json:"page_size"`
+}
+
+type BookmarkPreviewResponse struct {
+	Code    int                  `json:"code"`
+	Message string               `json:"message"`
+	Error   string               `json:"error"`
+	Preview parsers.PageMetadata `json:"preview"`
+}
+
+type BookmarkHealthResponse struct {
+	Code      int                      `json:"code"`
+	Message   string                   `json:"message"`
+	Error     string                   `json:"error"`
+	Summary   domain.LinkHealthSummary `json:"summary"`
+	Bookmarks []domain.Bookmark        `json:"bookmarks"`
+}
+
+const (
+	ImportStatusImported = "imported"
+	ImportStatusSkipped  = "skipped"
+	ImportStatusFailed   = "failed"
+)
+
+type ImportItem struct {
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Folder string `json:"folder"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type ImportResponse struct {
+	Code     int          `json:"code"`
+	Message  string       `json:"message"`
+	Error    string       `json:"error"`
+	Imported int          `json:"imported"`
+	Skipped  int          `json:"skipped"`
+	Failed   int          `json:"failed"`
+	Items    []ImportItem `json:"items"`
+}
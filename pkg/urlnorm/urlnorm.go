@@ -0,0 +1,145 @@
+package urlnorm
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// DefaultTrackingParams are query parameters added by analytics and ad
+// platforms to follow clicks. They do not change the page. A trailing *
+// matches any suffix.
+var DefaultTrackingParams = []string{
+	"utm_*", "gclid", "gclsrc", "dclid", "gbraid", "wbraid", "fbclid", "msclkid", "yclid", "twclid", "ttclid",
+	"igshid", "mc_cid", "mc_eid", "_hsenc", "_hsmi", "mkt_tok", "oly_anon_id", "oly_enc_id", "vero_id", "_ga", "_gl",
+}
+
+// Normalizer rewrites URLs into canonical forms.
+//
+// Canonical is the URL as Theca stores it: the scheme and host are lower
+// case, default ports and tracking parameters are removed and an empty
+// path becomes "/". It leads to the same page as the original.
+//
+// Key goes further and is only used to find duplicates: it also drops the
+// scheme, a leading "www.", trailing slashes and fragments, and sorts the
+// query, so https://example.com/ and http://www.example.com share a key.
+//
+// URLs of other schemes than http and https, and unparsable ones, are
+// only trimmed.
+type Normalizer struct {
+	exact    []string
+	prefixes []string
+}
+
+// New creates a Normalizer removing trackingParams, compared without
+// regard to case.
+func New(trackingParams []string) *Normalizer {
+	n := &Normalizer{}
+	for _, param := range trackingParams {
+		param = strings.ToLower(strings.TrimSpace(param))
+		if prefix, ok := strings.CutSuffix(param, "*"); ok && prefix != "" {
+			n.prefixes = append(n.prefixes, prefix)
+		} else if param != "" {
+			n.exact = append(n.exact, param)
+		}
+	}
+	return n
+}
+
+func (n *Normalizer) Canonical(rawURL string) string {
+	parsed, ok := n.parse(rawURL)
+	if !ok {
+		return strings.TrimSpace(rawURL)
+	}
+	return parsed.String()
+}
+
+func (n *Normalizer) Key(rawURL string) string {
+	parsed, ok := n.parse(rawURL)
+	if !ok {
+		return strings.TrimSpace(rawURL)
+	}
+
+	key := strings.TrimPrefix(parsed.Host, "www.")
+	key += strings.TrimRight(parsed.EscapedPath(), "/")
+	if parsed.RawQuery != "" {
+		pairs := strings.Split(parsed.RawQuery, "&")
+		slices.Sort(pairs)
+		key += "?" + strings.Join(pairs, "&")
+	}
+	// Fragments are anchors within the page, except in single page apps
+	// routing with "#/" or "#!".
+	if fragment := parsed.EscapedFragment(); strings.HasPrefix(fragment, "/") || strings.HasPrefix(fragment, "!") {
+		key += "#" + fragment
+	}
+	return key
+}
+
+// parse returns the canonical form of rawURL as a URL, or false when it
+// is not an http or https URL.
+func (n *Normalizer) parse(rawURL string) (*url.URL, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, false
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return nil, false
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		host = ascii
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := parsed.Port(); port != "" && !(parsed.Scheme == "http" && port == "80") && !(parsed.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	parsed.Host = host
+
+	if parsed.Path == "" {
+		parsed.Path, parsed.RawPath = "/", ""
+	}
+	parsed.RawQuery = n.stripTracking(parsed.RawQuery)
+	parsed.ForceQuery = false
+	parsed.RawFragment = ""
+	return parsed, true
+}
+
+// stripTracking removes tracking parameters from a raw query, keeping the
+// others as they were written and in their order.
+func (n *Normalizer) stripTracking(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	var kept []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !n.tracking(strings.ToLower(name)) {
+			kept = append(kept, pair)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+func (n *Normalizer) tracking(name string) bool {
+	if slices.Contains(n.exact, name) {
+		return true
+	}
+	for _, prefix := range n.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
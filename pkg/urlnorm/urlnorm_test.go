@@ -0,0 +1,97 @@
+package urlnorm
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+	n := New(DefaultTrackingParams)
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "already canonical", url: "https://example.com/a?b=1", want: "https://example.com/a?b=1"},
+		{name: "case of scheme and host", url: "HTTPS://Example.COM/Path", want: "https://example.com/Path"},
+		{name: "empty path", url: "https://example.com", want: "https://example.com/"},
+		{name: "keeps www and trailing slash", url: "https://www.example.com/docs/", want: "https://www.example.com/docs/"},
+		{name: "default http port", url: "http://example.com:80/", want: "http://example.com/"},
+		{name: "default https port", url: "https://example.com:443/", want: "https://example.com/"},
+		{name: "other port", url: "https://example.com:8443/", want: "https://example.com:8443/"},
+		{name: "https port over http", url: "http://example.com:443/", want: "http://example.com:443/"},
+		{name: "utm parameters", url: "https://example.com/?utm_source=x&id=7&UTM_Medium=y", want: "https://example.com/?id=7"},
+		{name: "click ids", url: "https://example.com/?fbclid=1&gclid=2", want: "https://example.com/"},
+		{name: "keeps query order", url: "https://example.com/?b=2&a=1", want: "https://example.com/?b=2&a=1"},
+		{name: "empty query", url: "https://example.com/?", want: "https://example.com/"},
+		{name: "keeps fragment", url: "https://example.com/page#intro", want: "https://example.com/page#intro"},
+		{name: "trailing dot of host", url: "https://example.com./", want: "https://example.com/"},
+		{name: "internationalized host", url: "https://Bücher.example/", want: "https://xn--bcher-kva.example/"},
+		{name: "ipv6 host", url: "http://[::1]:80/", want: "http://[::1]/"},
+		{name: "surrounding spaces", url: "  https://example.com/a  ", want: "https://example.com/a"},
+		{name: "other scheme", url: " mailto:someone@example.com ", want: "mailto:someone@example.com"},
+		{name: "no host", url: "https:///path", want: "https:///path"},
+		{name: "unparsable", url: "http://exa mple.com/%zz", want: "http://exa mple.com/%zz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.Canonical(tt.url); got != tt.want {
+				t.Fatalf("Canonical(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	n := New(DefaultTrackingParams)
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{name: "scheme", a: "http://example.com/", b: "https://example.com/", same: true},
+		{name: "www", a: "https://www.example.com/a", b: "https://example.com/a", same: true},
+		{name: "trailing slash", a: "https://example.com/docs/", b: "https://example.com/docs", same: true},
+		{name: "empty path", a: "https://example.com", b: "https://example.com/", same: true},
+		{name: "default port", a: "https://example.com:443/a", b: "https://example.com/a", same: true},
+		{name: "other port", a: "https://example.com:8443/a", b: "https://example.com/a"},
+		{name: "utm parameters", a: "https://example.com/a?utm_campaign=x", b: "https://example.com/a", same: true},
+		{name: "query order", a: "https://example.com/?a=1&b=2", b: "https://example.com/?b=2&a=1", same: true},
+		{name: "query values", a: "https://example.com/?a=1", b: "https://example.com/?a=2"},
+		{name: "anchor", a: "https://example.com/page#intro", b: "https://example.com/page", same: true},
+		{name: "single page app route", a: "https://example.com/#/inbox", b: "https://example.com/#/settings"},
+		{name: "hashbang route", a: "https://example.com/#!/a", b: "https://example.com/"},
+		{name: "path case", a: "https://example.com/Docs", b: "https://example.com/docs"},
+		{name: "other host", a: "https://example.org/", b: "https://example.com/"},
+		{name: "subdomain", a: "https://blog.example.com/", b: "https://example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyA, keyB := n.Key(tt.a), n.Key(tt.b)
+			if (keyA == keyB) != tt.same {
+				t.Fatalf("Key(%q) = %q, Key(%q) = %q, want same = %v", tt.a, keyA, tt.b, keyB, tt.same)
+			}
+		})
+	}
+}
+
+func TestKeyValue(t *testing.T) {
+	n := New(DefaultTrackingParams)
+	if got, want := n.Key("HTTPS://www.Example.com/Docs/?utm_source=x&b=2&a=1#top"), "example.com/Docs?a=1&b=2"; got != want {
+		t.Fatalf("Key = %q, want %q", got, want)
+	}
+}
+
+func TestNewTrackingParams(t *testing.T) {
+	n := New([]string{" Ref ", "ab_*", "*", ""})
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/?ref=x&REF=y&id=1", "https://example.com/?id=1"},
+		{"https://example.com/?ab_test=1&abc=2", "https://example.com/?abc=2"},
+		{"https://example.com/?utm_source=x", "https://example.com/?utm_source=x"},
+	}
+	for _, tt := range tests {
+		if got := n.Canonical(tt.url); got != tt.want {
+			t.Errorf("Canonical(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}